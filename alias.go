@@ -19,6 +19,7 @@ import (
 	"github.com/jaypipes/ghw/pkg/pci"
 	pciaddress "github.com/jaypipes/ghw/pkg/pci/address"
 	"github.com/jaypipes/ghw/pkg/product"
+	"github.com/jaypipes/ghw/pkg/smbios"
 	"github.com/jaypipes/ghw/pkg/topology"
 )
 
@@ -33,10 +34,17 @@ var (
 	WithDisableWarnings = option.WithNullAlerter
 	WithDisableTools    = option.WithDisableTools
 	WithPathOverrides   = option.WithPathOverrides
+	WithBlockOptions    = option.WithBlockOptions
+	WithNetOptions      = option.WithNetOptions
+	WithPCIOptions      = option.WithPCIOptions
 )
 
 type SnapshotOptions = option.SnapshotOptions
 
+type BlockOptions = option.BlockOptions
+type NetOptions = option.NetOptions
+type PCIOptions = option.PCIOptions
+
 type PathOverrides = option.PathOverrides
 
 type CPUInfo = cpu.Info
@@ -62,6 +70,34 @@ var (
 type BlockInfo = block.Info
 type Disk = block.Disk
 type Partition = block.Partition
+type NVMeController = block.NVMeController
+type NVMeNamespace = block.NVMeNamespace
+type VirtualDevice = block.VirtualDevice
+type DeviceMapper = block.DeviceMapper
+type RAID = block.RAID
+type RAIDMember = block.RAIDMember
+type VirtualDeviceType = block.VirtualDeviceType
+
+type DiskHealth = block.Health
+type DiskQueue = block.Queue
+type Mount = block.Mount
+type FilesystemUsage = block.FilesystemUsage
+type BlockDevice = block.BlockDevice
+type LoopDevice = block.LoopDevice
+type ZRAMDevice = block.ZRAMDevice
+type DiskHealthStatus = block.HealthStatus
+
+const (
+	HEALTH_STATUS_UNKNOWN = block.HEALTH_STATUS_UNKNOWN
+	HEALTH_STATUS_PASSED  = block.HEALTH_STATUS_PASSED
+	HEALTH_STATUS_FAILED  = block.HEALTH_STATUS_FAILED
+)
+
+const (
+	VIRTUAL_DEVICE_TYPE_UNKNOWN       = block.VIRTUAL_DEVICE_TYPE_UNKNOWN
+	VIRTUAL_DEVICE_TYPE_DEVICE_MAPPER = block.VIRTUAL_DEVICE_TYPE_DEVICE_MAPPER
+	VIRTUAL_DEVICE_TYPE_MD            = block.VIRTUAL_DEVICE_TYPE_MD
+)
 
 var (
 	Block = block.New
@@ -75,6 +111,9 @@ const (
 	DRIVE_TYPE_FDD     = block.DRIVE_TYPE_FDD
 	DRIVE_TYPE_ODD     = block.DRIVE_TYPE_ODD
 	DRIVE_TYPE_SSD     = block.DRIVE_TYPE_SSD
+	DRIVE_TYPE_LOOP    = block.DRIVE_TYPE_LOOP
+	DRIVE_TYPE_RAM     = block.DRIVE_TYPE_RAM
+	DRIVE_TYPE_ZRAM    = block.DRIVE_TYPE_ZRAM
 )
 
 type StorageController = block.StorageController
@@ -86,16 +125,50 @@ const (
 	STORAGE_CONTROLLER_NVME    = block.STORAGE_CONTROLLER_NVME
 	STORAGE_CONTROLLER_VIRTIO  = block.STORAGE_CONTROLLER_VIRTIO
 	STORAGE_CONTROLLER_MMC     = block.STORAGE_CONTROLLER_MMC
+	STORAGE_CONTROLLER_SATA    = block.STORAGE_CONTROLLER_SATA
+	STORAGE_CONTROLLER_SAS     = block.STORAGE_CONTROLLER_SAS
+	STORAGE_CONTROLLER_USB     = block.STORAGE_CONTROLLER_USB
+	STORAGE_CONTROLLER_FC      = block.STORAGE_CONTROLLER_FC
+	STORAGE_CONTROLLER_ISCSI   = block.STORAGE_CONTROLLER_ISCSI
+	STORAGE_CONTROLLER_XEN     = block.STORAGE_CONTROLLER_XEN
 )
 
 type NetworkInfo = net.Info
 type NIC = net.NIC
 type NICCapability = net.NICCapability
+type NICLink = net.NICLink
+type NICQueues = net.NICQueues
+type NICBond = net.NICBond
+type NICVLAN = net.NICVLAN
+type NICRepresentor = net.NICRepresentor
+type NICAddress = net.NICAddress
+type NICRoute = net.NICRoute
+type NICDriverInfo = net.NICDriverInfo
+type NICRingParams = net.NICRingParams
+type NICChannels = net.NICChannels
+type NICCoalesce = net.NICCoalesce
+type NICLinkModes = net.NICLinkModes
 
 var (
 	Network = net.New
 )
 
+type NICKind = net.NICKind
+
+const (
+	NIC_KIND_UNKNOWN     = net.NIC_KIND_UNKNOWN
+	NIC_KIND_PHYSICAL    = net.NIC_KIND_PHYSICAL
+	NIC_KIND_BOND        = net.NIC_KIND_BOND
+	NIC_KIND_BRIDGE      = net.NIC_KIND_BRIDGE
+	NIC_KIND_VLAN        = net.NIC_KIND_VLAN
+	NIC_KIND_MACVLAN     = net.NIC_KIND_MACVLAN
+	NIC_KIND_IPVLAN      = net.NIC_KIND_IPVLAN
+	NIC_KIND_VETH        = net.NIC_KIND_VETH
+	NIC_KIND_TEAM        = net.NIC_KIND_TEAM
+	NIC_KIND_REPRESENTOR = net.NIC_KIND_REPRESENTOR
+	NIC_KIND_VIRTUAL     = net.NIC_KIND_VIRTUAL
+)
+
 type BIOSInfo = bios.Info
 
 var (
@@ -131,10 +204,33 @@ const (
 type PCIInfo = pci.Info
 type PCIAddress = pciaddress.Address
 type PCIDevice = pci.Device
+type PCILink = pci.Link
+type PCIBAR = pci.BAR
+type PCICapability = pci.Capability
+type PCISRIOV = pci.SRIOV
+type PCIIOMMUGroup = pci.IOMMUGroup
+type PCIRootComplex = pci.RootComplex
+type PCIDriver = pci.Driver
+type PCIPredicate = pci.Predicate
+type VFIOReadiness = pci.VFIOReadiness
+
+const (
+	VFIO_READINESS_READY           = pci.VFIO_READINESS_READY
+	VFIO_READINESS_IOMMU_DISABLED  = pci.VFIO_READINESS_IOMMU_DISABLED
+	VFIO_READINESS_NON_VFIO_DRIVER = pci.VFIO_READINESS_NON_VFIO_DRIVER
+	VFIO_READINESS_SHARED_GROUP    = pci.VFIO_READINESS_SHARED_GROUP
+)
 
 var (
 	PCI                  = pci.New
 	PCIAddressFromString = pciaddress.FromString
+	PCIByClass           = pci.ByClass
+	PCIBySubclass        = pci.BySubclass
+	PCIByVendor          = pci.ByVendor
+	PCIByProduct         = pci.ByProduct
+	PCIByDriver          = pci.ByDriver
+	PCIByNUMANode        = pci.ByNUMANode
+	PCIByAddressPrefix   = pci.ByAddressPrefix
 )
 
 type ProductInfo = product.Info
@@ -149,3 +245,9 @@ type GraphicsCard = gpu.GraphicsCard
 var (
 	GPU = gpu.New
 )
+
+type SMBIOSInfo = smbios.Info
+
+var (
+	SMBIOS = smbios.New
+)
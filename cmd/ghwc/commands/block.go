@@ -14,6 +14,13 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	blockPseudoDevices   bool
+	blockPartitionTables bool
+	blockSMARTData       bool
+	blockUsage           bool
+)
+
 // blockCmd represents the install command
 var blockCmd = &cobra.Command{
 	Use:   "block",
@@ -23,7 +30,12 @@ var blockCmd = &cobra.Command{
 
 // showBlock show block storage information for the host system.
 func showBlock(cmd *cobra.Command, args []string) error {
-	block, err := ghw.Block()
+	block, err := ghw.Block(ghw.WithBlockOptions(ghw.BlockOptions{
+		IncludePseudoDevices:   blockPseudoDevices,
+		ReadPartitionTables:    blockPartitionTables,
+		ReadSMARTData:          blockSMARTData,
+		IncludeFilesystemUsage: blockUsage,
+	}))
 	if err != nil {
 		return errors.Wrap(err, "error getting block device info")
 	}
@@ -47,5 +59,21 @@ func showBlock(cmd *cobra.Command, args []string) error {
 }
 
 func init() {
+	blockCmd.Flags().BoolVar(
+		&blockPseudoDevices, "pseudo-devices", false,
+		"Include the loop, RAM and zram devices",
+	)
+	blockCmd.Flags().BoolVar(
+		&blockPartitionTables, "partition-tables", false,
+		"Read the partition identifiers udev doesn't know from the disks",
+	)
+	blockCmd.Flags().BoolVar(
+		&blockSMARTData, "smart", false,
+		"Read the SMART data of the disks with smartctl",
+	)
+	blockCmd.Flags().BoolVar(
+		&blockUsage, "usage", false,
+		"Include the usage of the filesystems mounted from partitions",
+	)
 	rootCmd.AddCommand(blockCmd)
 }
@@ -14,6 +14,11 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	netTopology  bool
+	netAddresses bool
+)
+
 // netCmd represents the install command
 var netCmd = &cobra.Command{
 	Use:   "net",
@@ -23,7 +28,9 @@ var netCmd = &cobra.Command{
 
 // showNetwork show network information for the host system.
 func showNetwork(cmd *cobra.Command, args []string) error {
-	net, err := ghw.Network()
+	net, err := ghw.Network(ghw.WithNetOptions(ghw.NetOptions{
+		IncludeAddresses: netAddresses,
+	}))
 	if err != nil {
 		return errors.Wrap(err, "error getting network info")
 	}
@@ -32,6 +39,11 @@ func showNetwork(cmd *cobra.Command, args []string) error {
 	case outputFormatHuman:
 		fmt.Printf("%v\n", net)
 
+		if netTopology {
+			fmt.Print(net.TopologyString())
+			return nil
+		}
+
 		for _, nic := range net.NICs {
 			fmt.Printf(" %v\n", nic)
 
@@ -57,5 +69,13 @@ func showNetwork(cmd *cobra.Command, args []string) error {
 }
 
 func init() {
+	netCmd.Flags().BoolVar(
+		&netTopology, "topology", false,
+		"Show the NICs stacked on each NIC, from the physical ports up",
+	)
+	netCmd.Flags().BoolVar(
+		&netAddresses, "addresses", false,
+		"Include the IP addresses and the routes of the NICs",
+	)
 	rootCmd.AddCommand(netCmd)
 }
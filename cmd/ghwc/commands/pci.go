@@ -7,11 +7,23 @@
 package commands
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/jaypipes/ghw"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+var (
+	pciTree             bool
+	pciClass            string
+	pciVendor           string
+	pciDriver           string
+	pciCandidateDrivers bool
+	pciCapabilities     bool
+)
+
 // pciCmd represents the install command
 var pciCmd = &cobra.Command{
 	Use:   "pci",
@@ -21,15 +33,98 @@ var pciCmd = &cobra.Command{
 
 // showPCI shows information for PCI devices on the host system.
 func showPCI(cmd *cobra.Command, args []string) error {
-	pci, err := ghw.PCI()
+	preds := pciPredicates(cmd)
+	if pciTree && len(preds) > 0 {
+		return errors.New("--tree cannot be combined with --class, --vendor or --driver")
+	}
+
+	pci, err := ghw.PCI(ghw.WithPCIOptions(ghw.PCIOptions{
+		IncludeCandidateDrivers: pciCandidateDrivers,
+		IncludeCapabilities:     pciCapabilities,
+	}))
 	if err != nil {
 		return errors.Wrap(err, "error getting PCI info")
 	}
 
+	if pciTree && outputFormat == outputFormatHuman {
+		fmt.Printf("%v\n", pci)
+		for _, rc := range pci.RootComplexes() {
+			fmt.Printf(" %v\n", rc)
+			for _, dev := range rc.Devices {
+				printPCIDeviceTree(dev, 2)
+			}
+		}
+		return nil
+	}
+
+	if len(preds) > 0 {
+		pci.Devices = pci.Filter(preds...)
+		if outputFormat == outputFormatHuman {
+			fmt.Printf("%v\n", pci)
+			for _, dev := range pci.Devices {
+				fmt.Printf(" %v\n", dev)
+			}
+			return nil
+		}
+	}
+
 	printInfo(pci)
 	return nil
 }
 
+// pciPredicates returns the predicates matching the filtering flags given on
+// the command line. The class and vendor flags accept an optional subclass
+// and product ID after a colon, e.g. "01:08" or "8086:10c9".
+func pciPredicates(cmd *cobra.Command) []ghw.PCIPredicate {
+	preds := make([]ghw.PCIPredicate, 0)
+	if pciClass != "" {
+		parts := strings.SplitN(pciClass, ":", 2)
+		if len(parts) == 2 {
+			preds = append(preds, ghw.PCIBySubclass(parts[0], parts[1]))
+		} else {
+			preds = append(preds, ghw.PCIByClass(parts[0]))
+		}
+	}
+	if pciVendor != "" {
+		parts := strings.SplitN(pciVendor, ":", 2)
+		if len(parts) == 2 {
+			preds = append(preds, ghw.PCIByProduct(parts[0], parts[1]))
+		} else {
+			preds = append(preds, ghw.PCIByVendor(parts[0]))
+		}
+	}
+	// an empty driver is meaningful: it selects the devices bound to no driver
+	if cmd.Flags().Changed("driver") {
+		preds = append(preds, ghw.PCIByDriver(pciDriver))
+	}
+	return preds
+}
+
+func printPCIDeviceTree(dev *ghw.PCIDevice, depth int) {
+	fmt.Printf("%s%v\n", strings.Repeat(" ", depth), dev)
+	for _, child := range dev.Children {
+		printPCIDeviceTree(child, depth+1)
+	}
+}
+
 func init() {
+	pciCmd.Flags().BoolVar(
+		&pciTree, "tree", false, "Show the PCI devices as a hierarchy (human output only)",
+	)
+	pciCmd.Flags().StringVar(
+		&pciClass, "class", "", "Only show the devices of this class ID, optionally followed by ':' and a subclass ID",
+	)
+	pciCmd.Flags().StringVar(
+		&pciVendor, "vendor", "", "Only show the devices of this vendor ID, optionally followed by ':' and a product ID",
+	)
+	pciCmd.Flags().StringVar(
+		&pciDriver, "driver", "", "Only show the devices bound to this driver (empty for no driver)",
+	)
+	pciCmd.Flags().BoolVar(
+		&pciCandidateDrivers, "candidate-drivers", false, "Include the kernel modules able to drive each device",
+	)
+	pciCmd.Flags().BoolVar(
+		&pciCapabilities, "capabilities", false, "Include the capabilities listed in the configuration space of each device",
+	)
 	rootCmd.AddCommand(pciCmd)
 }
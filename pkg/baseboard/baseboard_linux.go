@@ -10,11 +10,12 @@ import (
 )
 
 func (i *Info) load() error {
-	i.AssetTag = linuxdmi.Item(i.ctx, "board_asset_tag")
-	i.SerialNumber = linuxdmi.Item(i.ctx, "board_serial")
-	i.Vendor = linuxdmi.Item(i.ctx, "board_vendor")
-	i.Version = linuxdmi.Item(i.ctx, "board_version")
-	i.Product = linuxdmi.Item(i.ctx, "board_name")
+	dmi := linuxdmi.NewItems(i.ctx)
+	i.AssetTag = dmi.Item("board_asset_tag")
+	i.SerialNumber = dmi.Item("board_serial")
+	i.Vendor = dmi.Item("board_vendor")
+	i.Version = dmi.Item("board_version")
+	i.Product = dmi.Item("board_name")
 
 	return nil
 }
@@ -8,9 +8,10 @@ package bios
 import "github.com/jaypipes/ghw/pkg/linuxdmi"
 
 func (i *Info) load() error {
-	i.Vendor = linuxdmi.Item(i.ctx, "bios_vendor")
-	i.Version = linuxdmi.Item(i.ctx, "bios_version")
-	i.Date = linuxdmi.Item(i.ctx, "bios_date")
+	dmi := linuxdmi.NewItems(i.ctx)
+	i.Vendor = dmi.Item("bios_vendor")
+	i.Version = dmi.Item("bios_version")
+	i.Date = dmi.Item("bios_date")
 
 	return nil
 }
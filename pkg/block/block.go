@@ -14,6 +14,8 @@ import (
 	"github.com/jaypipes/ghw/pkg/context"
 	"github.com/jaypipes/ghw/pkg/marshal"
 	"github.com/jaypipes/ghw/pkg/option"
+	"github.com/jaypipes/ghw/pkg/pci"
+	"github.com/jaypipes/ghw/pkg/topology"
 	"github.com/jaypipes/ghw/pkg/unitutil"
 	"github.com/jaypipes/ghw/pkg/util"
 )
@@ -27,6 +29,9 @@ const (
 	DRIVE_TYPE_FDD               // Floppy disk drive
 	DRIVE_TYPE_ODD               // Optical disk drive
 	DRIVE_TYPE_SSD               // Solid-state drive
+	DRIVE_TYPE_LOOP              // Loop device, backed by a file
+	DRIVE_TYPE_RAM               // RAM disk
+	DRIVE_TYPE_ZRAM              // Compressed RAM disk
 )
 
 var (
@@ -36,6 +41,9 @@ var (
 		DRIVE_TYPE_FDD:     "FDD",
 		DRIVE_TYPE_ODD:     "ODD",
 		DRIVE_TYPE_SSD:     "SSD",
+		DRIVE_TYPE_LOOP:    "Loop",
+		DRIVE_TYPE_RAM:     "RAM",
+		DRIVE_TYPE_ZRAM:    "zram",
 	}
 )
 
@@ -64,6 +72,12 @@ const (
 	STORAGE_CONTROLLER_NVME                      // Non-volatile Memory Express
 	STORAGE_CONTROLLER_VIRTIO                    // Virtualized storage controller/driver
 	STORAGE_CONTROLLER_MMC                       // Multi-media controller (used for mobile phone storage devices)
+	STORAGE_CONTROLLER_SATA                      // Serial ATA, e.g. AHCI
+	STORAGE_CONTROLLER_SAS                       // Serial attached SCSI
+	STORAGE_CONTROLLER_USB                       // USB mass storage
+	STORAGE_CONTROLLER_FC                        // Fibre Channel
+	STORAGE_CONTROLLER_ISCSI                     // SCSI over TCP/IP
+	STORAGE_CONTROLLER_XEN                       // Xen virtual block device
 )
 
 var (
@@ -74,6 +88,12 @@ var (
 		STORAGE_CONTROLLER_NVME:    "NVMe",
 		STORAGE_CONTROLLER_VIRTIO:  "virtio",
 		STORAGE_CONTROLLER_MMC:     "MMC",
+		STORAGE_CONTROLLER_SATA:    "SATA",
+		STORAGE_CONTROLLER_SAS:     "SAS",
+		STORAGE_CONTROLLER_USB:     "USB",
+		STORAGE_CONTROLLER_FC:      "FC",
+		STORAGE_CONTROLLER_ISCSI:   "iSCSI",
+		STORAGE_CONTROLLER_XEN:     "Xen",
 	}
 )
 
@@ -98,16 +118,70 @@ type Disk struct {
 	IsRemovable            bool              `json:"removable"`
 	StorageController      StorageController `json:"storage_controller"`
 	BusPath                string            `json:"bus_path"`
-	// TODO(jaypipes): Convert this to a TopologyNode struct pointer and then
-	// add to serialized output as "numa_node,omitempty"
+	// NUMA node the disk is affined to, or -1. Serialized through Node.
 	NUMANodeID   int          `json:"-"`
 	Vendor       string       `json:"vendor"`
 	Model        string       `json:"model"`
 	SerialNumber string       `json:"serial_number"`
 	WWN          string       `json:"wwn"`
 	Partitions   []*Partition `json:"partitions"`
-	// TODO(jaypipes): Add PCI field for accessing PCI device information
-	// PCI *PCIDevice `json:"pci"`
+	// NVMe namespace exposed by the disk. Will be nil if the disk is not a
+	// NVMe namespace.
+	NVMeNamespace *NVMeNamespace `json:"nvme_namespace,omitempty"`
+	// PCI device the disk is attached through. Will be nil if the disk is not
+	// attached through a PCI device, e.g. virtual devices.
+	PCI *pci.Device `json:"pci,omitempty"`
+	// Topology node the disk is affined to. Will be nil if the architecture
+	// is not NUMA.
+	Node *topology.Node `json:"numa_node,omitempty"`
+	// Health reported by the disk. Will be nil if no health information is
+	// available for the disk.
+	Health *Health `json:"health,omitempty"`
+	// Request queue of the disk. Will be nil if the queue characteristics
+	// are not available.
+	Queue *Queue `json:"queue,omitempty"`
+	// Holders are the block devices built on top of the whole disk, e.g. a
+	// device-mapper target or a MD array. Linux only.
+	Holders []*BlockDevice `json:"holders,omitempty"`
+	// Slaves are the block devices the disk is built on, if the disk is a
+	// virtual device. Linux only.
+	Slaves []*BlockDevice `json:"slaves,omitempty"`
+	// Loop device the disk is. Will be nil if the disk is not a loop device,
+	// or if pseudo devices are not requested.
+	Loop *LoopDevice `json:"loop,omitempty"`
+	// Compressed RAM disk the disk is. Will be nil if the disk is not a zram
+	// device, or if pseudo devices are not requested.
+	ZRAM *ZRAMDevice `json:"zram,omitempty"`
+}
+
+// Queue describes the request queue through which the kernel submits I/O to
+// a disk, and the I/O characteristics the disk advertises
+type Queue struct {
+	LogicalBlockSizeBytes uint64 `json:"logical_block_size_bytes"`
+	// MinimumIOSizeBytes is the smallest preferred I/O size, e.g. the stripe
+	// chunk size of RAID arrays
+	MinimumIOSizeBytes uint64 `json:"minimum_io_size_bytes"`
+	// OptimalIOSizeBytes is the preferred I/O size for streaming I/O, or 0
+	// if the disk doesn't report one
+	OptimalIOSizeBytes uint64 `json:"optimal_io_size_bytes"`
+	// Scheduler is the active I/O scheduler, e.g. "mq-deadline" or "none"
+	Scheduler           string   `json:"scheduler"`
+	AvailableSchedulers []string `json:"available_schedulers"`
+	// NumRequests is the number of requests which can be allocated in the
+	// queue
+	NumRequests uint64 `json:"num_requests"`
+	// DiscardGranularityBytes and DiscardMaxBytes are 0 if the disk doesn't
+	// support discard (TRIM/UNMAP)
+	DiscardGranularityBytes uint64 `json:"discard_granularity_bytes"`
+	DiscardMaxBytes         uint64 `json:"discard_max_bytes"`
+	// WriteCache is either "write back" or "write through"
+	WriteCache string `json:"write_cache"`
+	// Zoned is the zone model of the disk: "none", "host-aware" or
+	// "host-managed"
+	Zoned string `json:"zoned"`
+	// IsDAX is true if the disk supports direct access, bypassing the page
+	// cache
+	IsDAX bool `json:"dax"`
 }
 
 // Partition describes a logical division of a Disk.
@@ -120,15 +194,38 @@ type Partition struct {
 	Type       string `json:"type"`
 	IsReadOnly bool   `json:"read_only"`
 	UUID       string `json:"uuid"` // This would be volume UUID on macOS, PartUUID on linux, empty on Windows
+
+	// TypeID is the partition type GUID on GPT disks, and the hexadecimal
+	// partition type (e.g. "0x83") on MBR disks. Linux only.
+	TypeID string `json:"type_id"`
+	// FilesystemUUID and FilesystemLabel identify the filesystem the
+	// partition contains. Linux only.
+	FilesystemUUID  string `json:"filesystem_uuid"`
+	FilesystemLabel string `json:"filesystem_label"`
+	// Mounts of the partition, bind mounts included. Linux only.
+	Mounts []*Mount `json:"mounts,omitempty"`
+	// Holders are the block devices built on top of the partition, e.g. a
+	// LUKS mapping or a LVM logical volume using the partition as physical
+	// volume. Linux only.
+	Holders []*BlockDevice `json:"holders,omitempty"`
+	// Slaves are the block devices the partition is built on. Partitions
+	// are usually built on their Disk only, which is not listed. Linux only.
+	Slaves []*BlockDevice `json:"slaves,omitempty"`
 }
 
 // Info describes all disk drives and partitions in the host system.
 type Info struct {
-	ctx *context.Context
+	ctx    *context.Context
+	mounts []*Mount
 	// TODO(jaypipes): Deprecate this field and replace with TotalSizeBytes
 	TotalPhysicalBytes uint64       `json:"total_size_bytes"`
 	Disks              []*Disk      `json:"disks"`
 	Partitions         []*Partition `json:"-"`
+	// NVMe controllers, sorted by name
+	NVMeControllers []*NVMeController `json:"nvme_controllers,omitempty"`
+	// Device-mapper targets and MD arrays, sorted by name. They are also
+	// listed in Disks.
+	VirtualDevices []*VirtualDevice `json:"virtual_devices,omitempty"`
 }
 
 // New returns a pointer to an Info struct that describes the block storage
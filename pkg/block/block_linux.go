@@ -6,17 +6,18 @@
 package block
 
 import (
-	"bufio"
-	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/jaypipes/ghw/pkg/context"
 	"github.com/jaypipes/ghw/pkg/linuxpath"
+	"github.com/jaypipes/ghw/pkg/pci"
+	pciaddr "github.com/jaypipes/ghw/pkg/pci/address"
+	"github.com/jaypipes/ghw/pkg/topology"
 	"github.com/jaypipes/ghw/pkg/util"
 )
 
@@ -26,9 +27,20 @@ const (
 
 func (i *Info) load() error {
 	paths := linuxpath.New(i.ctx)
-	i.Disks = disks(i.ctx, paths)
+	i.mounts = mounts(i.ctx, paths)
+	i.Disks = disks(i.ctx, paths, newMountIndex(i.ctx, i.mounts))
+	i.NVMeControllers = nvmeControllers(paths, i.Disks)
+	i.VirtualDevices = virtualDevices(paths, i.Disks)
+	linkStackedDevices(paths, i.Disks)
+	diskFillPCIDevices(i.ctx, paths, i.Disks)
+	diskFillNUMANodes(i.ctx, i.Disks)
+	diskFillHealth(i.ctx, paths, i.Disks, newSmartReader(i.ctx))
 	var tpb uint64
 	for _, d := range i.Disks {
+		// pseudo devices don't provide physical storage
+		if isPseudoDriveType(d.DriveType) {
+			continue
+		}
 		tpb += d.SizeBytes
 	}
 	i.TotalPhysicalBytes = tpb
@@ -65,19 +77,151 @@ func diskSizeBytes(paths *linuxpath.Paths, disk string) uint64 {
 	return size * sectorSize
 }
 
+// diskAncestors returns the sysfs directories of the devices the given disk
+// is attached through, starting with the disk itself, e.g.
+// /sys/devices/pci0000:00/0000:00:17.0/ata1/host0/target0:0:0/0:0:0:0/block/sda
+// then /sys/devices/pci0000:00/0000:00:17.0/ata1/host0/target0:0:0/0:0:0:0/block
+// and so on up to /sys/devices/pci0000:00
+func diskAncestors(paths *linuxpath.Paths, disk string) []string {
+	dirs := make([]string, 0)
+	devPath, err := filepath.EvalSymlinks(filepath.Join(paths.SysBlock, disk))
+	if err != nil {
+		return dirs
+	}
+	sysDevices, err := filepath.EvalSymlinks(filepath.Join(filepath.Dir(paths.SysBlock), "devices"))
+	if err != nil {
+		return dirs
+	}
+	for dir := devPath; strings.HasPrefix(dir, sysDevices+string(os.PathSeparator)); dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// diskNUMANodeID returns the NUMA node of the closest device the given disk
+// is attached through which is affined to one, or -1
 func diskNUMANodeID(paths *linuxpath.Paths, disk string) int {
-	link, err := os.Readlink(filepath.Join(paths.SysBlock, disk))
+	for _, dir := range diskAncestors(paths, disk) {
+		contents, err := ioutil.ReadFile(filepath.Join(dir, "numa_node"))
+		if err != nil {
+			continue
+		}
+		nodeID, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+		if err != nil {
+			return -1
+		}
+		return nodeID
+	}
+	return -1
+}
+
+// diskPCIAddress returns the address of the PCI function the given disk is
+// attached through, or an empty string if the disk is not attached through a
+// PCI device
+func diskPCIAddress(paths *linuxpath.Paths, disk *Disk) string {
+	for _, dir := range diskAncestors(paths, disk.Name) {
+		if addr := pciaddr.FromString(filepath.Base(dir)); addr != nil {
+			return addr.String()
+		}
+	}
+	// multipath NVMe namespaces are attached to their subsystem, and reached
+	// through their controllers
+	if disk.NVMeNamespace != nil {
+		for _, ctrl := range disk.NVMeNamespace.Controllers {
+			if addr := pciaddr.FromString(ctrl.Address); ctrl.Transport == "pcie" && addr != nil {
+				return addr.String()
+			}
+		}
+	}
+	return ""
+}
+
+// diskFillPCIDevices sets the PCI device each disk is attached through
+func diskFillPCIDevices(ctx *context.Context, paths *linuxpath.Paths, disks []*Disk) {
+	addrs := make(map[*Disk]string)
+	for _, disk := range disks {
+		if addr := diskPCIAddress(paths, disk); addr != "" {
+			addrs[disk] = addr
+		}
+	}
+	if len(addrs) == 0 {
+		return
+	}
+	pciInfo, err := pci.NewWithContext(ctx)
 	if err != nil {
-		return -1
+		return
 	}
-	for partial := link; strings.HasPrefix(partial, "../devices/"); partial = filepath.Base(partial) {
-		if nodeContents, err := ioutil.ReadFile(filepath.Join(paths.SysBlock, partial, "numa_node")); err != nil {
-			if nodeInt, err := strconv.Atoi(string(nodeContents)); err != nil {
-				return nodeInt
+	for disk, addr := range addrs {
+		disk.PCI = pciInfo.GetDevice(addr)
+	}
+}
+
+// diskFillNUMANodes sets the topology node each disk is affined to. If the
+// host system is not a NUMA system, the Node field will be left nil.
+func diskFillNUMANodes(ctx *context.Context, disks []*Disk) {
+	found := false
+	for _, disk := range disks {
+		found = found || disk.NUMANodeID >= 0
+	}
+	if !found {
+		return
+	}
+	topo, err := topology.NewWithContext(ctx)
+	if err != nil || topo.Architecture != topology.ARCHITECTURE_NUMA {
+		return
+	}
+	for _, disk := range disks {
+		for _, node := range topo.Nodes {
+			if disk.NUMANodeID == node.ID {
+				disk.Node = node
 			}
 		}
 	}
-	return -1
+}
+
+// diskQueue returns the characteristics of the request queue of the given
+// disk, found in the /sys/block/$DEVICE/queue directory, or nil if the disk
+// has no such directory
+func diskQueue(paths *linuxpath.Paths, disk string) *Queue {
+	queuePath := filepath.Join(paths.SysBlock, disk, "queue")
+	if !isDir(queuePath) {
+		return nil
+	}
+	q := &Queue{
+		LogicalBlockSizeBytes:   readUintFile(filepath.Join(queuePath, "logical_block_size")),
+		MinimumIOSizeBytes:      readUintFile(filepath.Join(queuePath, "minimum_io_size")),
+		OptimalIOSizeBytes:      readUintFile(filepath.Join(queuePath, "optimal_io_size")),
+		NumRequests:             readUintFile(filepath.Join(queuePath, "nr_requests")),
+		DiscardGranularityBytes: readUintFile(filepath.Join(queuePath, "discard_granularity")),
+		DiscardMaxBytes:         readUintFile(filepath.Join(queuePath, "discard_max_bytes")),
+		WriteCache:              readStringFile(filepath.Join(queuePath, "write_cache")),
+		Zoned:                   readStringFile(filepath.Join(queuePath, "zoned")),
+		IsDAX:                   readStringFile(filepath.Join(queuePath, "dax")) == "1",
+	}
+	q.Scheduler, q.AvailableSchedulers = parseScheduler(
+		readStringFile(filepath.Join(queuePath, "scheduler")),
+	)
+	return q
+}
+
+// parseScheduler returns the active and the available I/O schedulers listed
+// in the content of a queue/scheduler file, e.g. "mq-deadline kyber [bfq]
+// none", where the active scheduler is enclosed in brackets
+func parseScheduler(contents string) (string, []string) {
+	active := ""
+	available := make([]string, 0)
+	for _, field := range strings.Fields(contents) {
+		if strings.HasPrefix(field, "[") && strings.HasSuffix(field, "]") {
+			field = strings.Trim(field, "[]")
+			active = field
+		}
+		available = append(available, field)
+	}
+	// devices which can't use a scheduler only list "none"
+	if active == "" && len(available) == 1 {
+		active = available[0]
+	}
+	return active, available
 }
 
 func diskVendor(paths *linuxpath.Paths, disk string) string {
@@ -91,6 +235,9 @@ func diskVendor(paths *linuxpath.Paths, disk string) string {
 	return strings.TrimSpace(string(contents))
 }
 
+// udevInfo returns the properties udev recorded for the given block device.
+// The name of a partition must be prefixed by the name of its disk, as in
+// "sda/sda1".
 func udevInfo(paths *linuxpath.Paths, disk string) (map[string]string, error) {
 	// Get device major:minor numbers
 	devNo, err := ioutil.ReadFile(filepath.Join(paths.SysBlock, disk, "dev"))
@@ -180,7 +327,7 @@ func diskWWN(paths *linuxpath.Paths, disk string) string {
 // but just the name. In other words, "sda", not "/dev/sda" and "nvme0n1" not
 // "/dev/nvme0n1") and returns a slice of pointers to Partition structs
 // representing the partitions in that disk
-func diskPartitions(ctx *context.Context, paths *linuxpath.Paths, disk string) []*Partition {
+func diskPartitions(ctx *context.Context, paths *linuxpath.Paths, disk string, idx *mountIndex) []*Partition {
 	out := make([]*Partition, 0)
 	path := filepath.Join(paths.SysBlock, disk)
 	files, err := ioutil.ReadDir(path)
@@ -188,58 +335,109 @@ func diskPartitions(ctx *context.Context, paths *linuxpath.Paths, disk string) [
 		ctx.Warn("failed to read disk partitions: %s\n", err)
 		return out
 	}
+	// the partition table of the disk is only read on request, if udev
+	// doesn't know about some partitions
+	var table *partitionTable
+	tableRead := false
 	for _, file := range files {
 		fname := file.Name()
 		if !strings.HasPrefix(fname, disk) {
 			continue
 		}
 		size := partitionSizeBytes(paths, disk, fname)
-		mp, pt, ro := partitionInfo(paths, fname)
-		du := diskPartUUID(ctx, fname)
+		devNo := readStringFile(filepath.Join(path, fname, "dev"))
+		mounts := idx.partitionMounts(fname, devNo)
 		p := &Partition{
 			Name:       fname,
 			SizeBytes:  size,
-			MountPoint: mp,
-			Type:       pt,
-			IsReadOnly: ro,
-			UUID:       du,
+			IsReadOnly: true,
+			Mounts:     mounts,
+		}
+		// the first mount of the partition is the one reported by MountPoint
+		if len(mounts) > 0 {
+			p.MountPoint = mounts[0].MountPoint
+			p.Type = mounts[0].FilesystemType
+			p.IsReadOnly = mounts[0].IsReadOnly
+		}
+		partitionUdevInfo(paths, disk, p)
+		if ctx.BlockPartitionTables && (p.UUID == "" || p.TypeID == "") {
+			if !tableRead {
+				table = diskPartitionTable(ctx, paths, disk)
+				tableRead = true
+			}
+			if table != nil {
+				number := int(readUintFile(filepath.Join(path, fname, "partition")))
+				if entry, ok := table.Entries[number]; ok {
+					p.UUID = entry.UUID
+					p.TypeID = entry.TypeID
+					p.Label = entry.Label
+				}
+			}
 		}
 		out = append(out, p)
 	}
 	return out
 }
 
-func diskPartUUID(ctx *context.Context, part string) string {
-	if !ctx.EnableTools {
-		ctx.Warn("EnableTools=false disables partition UUID detection.")
-		return ""
-	}
-	if !strings.HasPrefix(part, "/dev") {
-		part = "/dev/" + part
-	}
-	args := []string{
-		"blkid",
-		"-s",
-		"PARTUUID",
-		part,
-	}
-	out, err := exec.Command(args[0], args[1:]...).Output()
+// partitionUdevInfo sets the identifiers of the supplied partition, and of
+// the filesystem it contains, from the udev runtime database
+func partitionUdevInfo(paths *linuxpath.Paths, disk string, p *Partition) {
+	info, err := udevInfo(paths, filepath.Join(disk, p.Name))
 	if err != nil {
-		ctx.Warn("failed to read disk partuuid of %s : %s\n", part, err.Error())
-		return ""
+		return
+	}
+	p.UUID = info["ID_PART_ENTRY_UUID"]
+	p.TypeID = info["ID_PART_ENTRY_TYPE"]
+	p.Label = udevDecode(info["ID_PART_ENTRY_NAME"])
+	p.FilesystemUUID = info["ID_FS_UUID"]
+	// ID_FS_LABEL has unsafe characters replaced by underscores, while
+	// ID_FS_LABEL_ENC has them escaped
+	if label, ok := info["ID_FS_LABEL_ENC"]; ok {
+		p.FilesystemLabel = udevDecode(label)
+	} else {
+		p.FilesystemLabel = info["ID_FS_LABEL"]
 	}
+}
 
-	if out == nil || len(out) == 0 {
-		return ""
+// udevDecode returns the supplied udev property value with its \xNN escaped
+// bytes decoded
+func udevDecode(s string) string {
+	if !strings.Contains(s, "\\x") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) && s[i+1] == 'x' {
+			if c, err := strconv.ParseUint(s[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
 	}
+	return b.String()
+}
 
-	parts := strings.Split(string(out), "PARTUUID=")
-	if len(parts) != 2 {
-		ctx.Warn("failed to parse the partuuid of %s\n", part)
-		return ""
+// diskPartitionTable returns the partition table read from the device file
+// of the given disk, or nil if the device file can't be read, which is the
+// case for unprivileged users and snapshots
+func diskPartitionTable(ctx *context.Context, paths *linuxpath.Paths, disk string) *partitionTable {
+	f, err := os.Open(filepath.Join(paths.Dev, disk))
+	if err != nil {
+		return nil
 	}
-
-	return strings.ReplaceAll(strings.TrimSpace(parts[1]), `"`, "")
+	defer util.SafeClose(f)
+	blockSize := readUintFile(filepath.Join(paths.SysBlock, disk, "queue", "logical_block_size"))
+	if blockSize == 0 {
+		blockSize = sectorSize
+	}
+	table, err := readPartitionTable(f, int64(blockSize))
+	if err != nil {
+		ctx.Warn("failed to read partition table of %s: %s\n", disk, err)
+		return nil
+	}
+	return table
 }
 
 func diskIsRemovable(paths *linuxpath.Paths, disk string) bool {
@@ -255,7 +453,7 @@ func diskIsRemovable(paths *linuxpath.Paths, disk string) bool {
 	return false
 }
 
-func disks(ctx *context.Context, paths *linuxpath.Paths) []*Disk {
+func disks(ctx *context.Context, paths *linuxpath.Paths, idx *mountIndex) []*Disk {
 	// In Linux, we could use the fdisk, lshw or blockdev commands to list disk
 	// information, however all of these utilities require root privileges to
 	// run. We can get all of this information by examining the /sys/block
@@ -268,15 +466,31 @@ func disks(ctx *context.Context, paths *linuxpath.Paths) []*Disk {
 	for _, file := range files {
 		dname := file.Name()
 		if strings.HasPrefix(dname, "loop") {
+			// detached loop devices have no backing file
+			if !ctx.BlockPseudoDevices || !isDir(filepath.Join(paths.SysBlock, dname, "loop")) {
+				continue
+			}
+		}
+		// the paths to multipath NVMe namespaces are reported in the
+		// namespaces of their controllers, not as disks
+		if isNVMePath(dname) {
 			continue
 		}
 
 		driveType, storageController := diskTypes(dname)
+		if sc := diskStorageController(paths, dname); sc != STORAGE_CONTROLLER_UNKNOWN {
+			storageController = sc
+		}
 		// TODO(jaypipes): Move this into diskTypes() once abstracting
 		// diskIsRotational for ease of unit testing
 		if !diskIsRotational(ctx, paths, dname) {
 			driveType = DRIVE_TYPE_SSD
 		}
+		if ctx.BlockPseudoDevices {
+			if pdt, ok := pseudoDriveType(dname); ok {
+				driveType = pdt
+			}
+		}
 		size := diskSizeBytes(paths, dname)
 		pbs := diskPhysicalBlockSizeBytes(paths, dname)
 		busPath := diskBusPath(paths, dname)
@@ -286,6 +500,7 @@ func disks(ctx *context.Context, paths *linuxpath.Paths) []*Disk {
 		serialNo := diskSerialNumber(paths, dname)
 		wwn := diskWWN(paths, dname)
 		removable := diskIsRemovable(paths, dname)
+		queue := diskQueue(paths, dname)
 
 		d := &Disk{
 			Name:                   dname,
@@ -300,9 +515,16 @@ func disks(ctx *context.Context, paths *linuxpath.Paths) []*Disk {
 			Model:                  model,
 			SerialNumber:           serialNo,
 			WWN:                    wwn,
+			Queue:                  queue,
+		}
+		switch driveType {
+		case DRIVE_TYPE_LOOP:
+			d.Loop = loopDevice(paths, dname)
+		case DRIVE_TYPE_ZRAM:
+			d.ZRAM = zramDevice(paths, dname)
 		}
 
-		parts := diskPartitions(ctx, paths, dname)
+		parts := diskPartitions(ctx, paths, dname, idx)
 		// Map this Disk object into the Partition...
 		for _, part := range parts {
 			part.Disk = d
@@ -344,7 +566,7 @@ func diskTypes(dname string) (
 		storageController = STORAGE_CONTROLLER_SCSI
 	} else if strings.HasPrefix(dname, "xvd") {
 		driveType = DRIVE_TYPE_HDD
-		storageController = STORAGE_CONTROLLER_SCSI
+		storageController = STORAGE_CONTROLLER_XEN
 	} else if strings.HasPrefix(dname, "mmc") {
 		driveType = DRIVE_TYPE_SSD
 		storageController = STORAGE_CONTROLLER_MMC
@@ -353,6 +575,75 @@ func diskTypes(dname string) (
 	return driveType, storageController
 }
 
+var (
+	// storageControllerDrivers maps the drivers of the devices a disk may be
+	// attached through to the storage controller of the disk
+	storageControllerDrivers = map[string]StorageController{
+		"ahci":           STORAGE_CONTROLLER_SATA,
+		"ata_piix":       STORAGE_CONTROLLER_SATA,
+		"sata_nv":        STORAGE_CONTROLLER_SATA,
+		"sata_sil":       STORAGE_CONTROLLER_SATA,
+		"sata_sil24":     STORAGE_CONTROLLER_SATA,
+		"sata_via":       STORAGE_CONTROLLER_SATA,
+		"ata_generic":    STORAGE_CONTROLLER_IDE,
+		"pata_acpi":      STORAGE_CONTROLLER_IDE,
+		"mpt2sas":        STORAGE_CONTROLLER_SAS,
+		"mpt3sas":        STORAGE_CONTROLLER_SAS,
+		"megaraid_sas":   STORAGE_CONTROLLER_SAS,
+		"smartpqi":       STORAGE_CONTROLLER_SAS,
+		"hisi_sas_v3_hw": STORAGE_CONTROLLER_SAS,
+		"isci":           STORAGE_CONTROLLER_SAS,
+		"pm80xx":         STORAGE_CONTROLLER_SAS,
+		"usb-storage":    STORAGE_CONTROLLER_USB,
+		"uas":            STORAGE_CONTROLLER_USB,
+		"qla2xxx":        STORAGE_CONTROLLER_FC,
+		"lpfc":           STORAGE_CONTROLLER_FC,
+		"bfa":            STORAGE_CONTROLLER_FC,
+		"qedf":           STORAGE_CONTROLLER_FC,
+		"fnic":           STORAGE_CONTROLLER_FC,
+		"virtio_blk":     STORAGE_CONTROLLER_VIRTIO,
+		"virtio_scsi":    STORAGE_CONTROLLER_VIRTIO,
+		"nvme":           STORAGE_CONTROLLER_NVME,
+		"mmcblk":         STORAGE_CONTROLLER_MMC,
+		// xen-blkfront registers its driver as "vbd"
+		"vbd": STORAGE_CONTROLLER_XEN,
+	}
+
+	// storageControllerDevices maps the names of the transport devices a
+	// disk may be attached through to the storage controller of the disk,
+	// for when no driver identifies it (e.g. in snapshots)
+	storageControllerDevices = []struct {
+		re *regexp.Regexp
+		sc StorageController
+	}{
+		{regexp.MustCompile(`^session\d+$`), STORAGE_CONTROLLER_ISCSI},
+		{regexp.MustCompile(`^rport-\d+:\d+-\d+$`), STORAGE_CONTROLLER_FC},
+		{regexp.MustCompile(`^end_device-\d+:`), STORAGE_CONTROLLER_SAS},
+		{regexp.MustCompile(`^usb\d+$`), STORAGE_CONTROLLER_USB},
+		{regexp.MustCompile(`^vbd-\d+$`), STORAGE_CONTROLLER_XEN},
+	}
+)
+
+// diskStorageController returns the storage controller of the given disk,
+// found by walking up the sysfs devices it is attached through, or
+// STORAGE_CONTROLLER_UNKNOWN if none is recognized
+func diskStorageController(paths *linuxpath.Paths, disk string) StorageController {
+	for _, dir := range diskAncestors(paths, disk) {
+		if driver, err := os.Readlink(filepath.Join(dir, "driver")); err == nil {
+			if sc, ok := storageControllerDrivers[filepath.Base(driver)]; ok {
+				return sc
+			}
+		}
+		name := filepath.Base(dir)
+		for _, dev := range storageControllerDevices {
+			if dev.re.MatchString(name) {
+				return dev.sc
+			}
+		}
+	}
+	return STORAGE_CONTROLLER_UNKNOWN
+}
+
 func diskIsRotational(ctx *context.Context, paths *linuxpath.Paths, devName string) bool {
 	path := filepath.Join(paths.SysBlock, devName, "queue", "rotational")
 	contents := util.SafeIntFromFile(ctx, path)
@@ -376,99 +667,26 @@ func partitionSizeBytes(paths *linuxpath.Paths, disk string, part string) uint64
 	return size * sectorSize
 }
 
-// Given a full or short partition name, returns the mount point, the type of
-// the partition and whether it's readonly
-func partitionInfo(paths *linuxpath.Paths, part string) (string, string, bool) {
-	// Allow calling PartitionInfo with either the full partition name
-	// "/dev/sda1" or just "sda1"
-	if !strings.HasPrefix(part, "/dev") {
-		part = "/dev/" + part
-	}
-
-	// mount entries for mounted partitions look like this:
-	// /dev/sda6 / ext4 rw,relatime,errors=remount-ro,data=ordered 0 0
-	var r io.ReadCloser
-	r, err := os.Open(paths.ProcMounts)
+// readUintFile returns the unsigned integer in the given sysfs file, or 0 if
+// the file can't be read
+func readUintFile(path string) uint64 {
+	contents, err := ioutil.ReadFile(path)
 	if err != nil {
-		return "", "", true
+		return 0
 	}
-	defer util.SafeClose(r)
-
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
-		entry := parseMountEntry(line)
-		if entry == nil || entry.Partition != part {
-			continue
-		}
-		ro := true
-		for _, opt := range entry.Options {
-			if opt == "rw" {
-				ro = false
-				break
-			}
-		}
-
-		return entry.Mountpoint, entry.FilesystemType, ro
+	value, err := strconv.ParseUint(strings.TrimSpace(string(contents)), 10, 64)
+	if err != nil {
+		return 0
 	}
-	return "", "", true
-}
-
-type mountEntry struct {
-	Partition      string
-	Mountpoint     string
-	FilesystemType string
-	Options        []string
+	return value
 }
 
-func parseMountEntry(line string) *mountEntry {
-	// mount entries for mounted partitions look like this:
-	// /dev/sda6 / ext4 rw,relatime,errors=remount-ro,data=ordered 0 0
-	if line[0] != '/' {
-		return nil
-	}
-	fields := strings.Fields(line)
-
-	if len(fields) < 4 {
-		return nil
-	}
-
-	// We do some special parsing of the mountpoint, which may contain space,
-	// tab and newline characters, encoded into the mount entry line using their
-	// octal-to-string representations. From the GNU mtab man pages:
-	//
-	//   "Therefore these characters are encoded in the files and the getmntent
-	//   function takes care of the decoding while reading the entries back in.
-	//   '\040' is used to encode a space character, '\011' to encode a tab
-	//   character, '\012' to encode a newline character, and '\\' to encode a
-	//   backslash."
-	mp := fields[1]
-	r := strings.NewReplacer(
-		"\\011", "\t", "\\012", "\n", "\\040", " ", "\\\\", "\\",
-	)
-	mp = r.Replace(mp)
-
-	res := &mountEntry{
-		Partition:      fields[0],
-		Mountpoint:     mp,
-		FilesystemType: fields[2],
+// readStringFile returns the trimmed contents of the given sysfs file, or an
+// empty string if the file can't be read
+func readStringFile(path string) string {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
 	}
-	opts := strings.Split(fields[3], ",")
-	res.Options = opts
-	return res
-}
-
-func partitionMountPoint(paths *linuxpath.Paths, part string) string {
-	mp, _, _ := partitionInfo(paths, part)
-	return mp
-}
-
-func partitionType(paths *linuxpath.Paths, part string) string {
-	_, pt, _ := partitionInfo(paths, part)
-	return pt
-}
-
-func partitionIsReadOnly(paths *linuxpath.Paths, part string) bool {
-	_, _, ro := partitionInfo(paths, part)
-	return ro
+	return strings.TrimSpace(string(contents))
 }
@@ -9,9 +9,24 @@
 package block
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"hash/crc32"
+	"io/ioutil"
+	"log"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/jaypipes/ghw/pkg/context"
+	"github.com/jaypipes/ghw/pkg/linuxpath"
+	"github.com/jaypipes/ghw/pkg/option"
+	"github.com/jaypipes/ghw/pkg/util"
+	"github.com/jaypipes/ghw/testdata"
 )
 
 func TestParseMountEntry(t *testing.T) {
@@ -21,61 +36,88 @@ func TestParseMountEntry(t *testing.T) {
 
 	tests := []struct {
 		line     string
-		expected *mountEntry
+		expected *Mount
 	}{
 		{
-			line: "/dev/sda6 / ext4 rw,relatime,errors=remount-ro,data=ordered 0 0",
-			expected: &mountEntry{
-				Partition:      "/dev/sda6",
-				Mountpoint:     "/",
+			line: "29 1 8:6 / / rw,relatime shared:1 - ext4 /dev/sda6 rw,errors=remount-ro,data=ordered",
+			expected: &Mount{
+				ID:             29,
+				ParentID:       1,
+				Major:          8,
+				Minor:          6,
+				Root:           "/",
+				MountPoint:     "/",
+				Options:        []string{"rw", "relatime"},
+				IsReadOnly:     false,
+				Propagation:    []string{"shared:1"},
 				FilesystemType: "ext4",
-				Options: []string{
-					"rw",
-					"relatime",
-					"errors=remount-ro",
-					"data=ordered",
-				},
+				Source:         "/dev/sda6",
+				SuperOptions:   []string{"rw", "errors=remount-ro", "data=ordered"},
 			},
 		},
 		{
-			line: "/dev/sda8 /home/Name\\040with\\040spaces ext4 ro 0 0",
-			expected: &mountEntry{
-				Partition:      "/dev/sda8",
-				Mountpoint:     "/home/Name with spaces",
+			line: "41 29 8:8 / /home/Name\\040with\\040spaces ro - ext4 /dev/sda8 ro",
+			expected: &Mount{
+				ID:             41,
+				ParentID:       29,
+				Major:          8,
+				Minor:          8,
+				Root:           "/",
+				MountPoint:     "/home/Name with spaces",
+				Options:        []string{"ro"},
+				IsReadOnly:     true,
+				Propagation:    []string{},
 				FilesystemType: "ext4",
-				Options: []string{
-					"ro",
-				},
+				Source:         "/dev/sda8",
+				SuperOptions:   []string{"ro"},
 			},
 		},
 		{
 			// Whoever might do this in real life should be quarantined and
 			// placed in administrative segregation
-			line: "/dev/sda8 /home/Name\\011with\\012tab&newline ext4 ro 0 0",
-			expected: &mountEntry{
-				Partition:      "/dev/sda8",
-				Mountpoint:     "/home/Name\twith\ntab&newline",
+			line: "42 29 8:8 / /home/Name\\011with\\012tab&newline ro - ext4 /dev/sda8 ro",
+			expected: &Mount{
+				ID:             42,
+				ParentID:       29,
+				Major:          8,
+				Minor:          8,
+				Root:           "/",
+				MountPoint:     "/home/Name\twith\ntab&newline",
+				Options:        []string{"ro"},
+				IsReadOnly:     true,
+				Propagation:    []string{},
 				FilesystemType: "ext4",
-				Options: []string{
-					"ro",
-				},
+				Source:         "/dev/sda8",
+				SuperOptions:   []string{"ro"},
 			},
 		},
 		{
-			line: "/dev/sda1 /home/Name\\\\withslash ext4 ro 0 0",
-			expected: &mountEntry{
-				Partition:      "/dev/sda1",
-				Mountpoint:     "/home/Name\\withslash",
+			// a bind mount of a subdirectory, with several propagation fields
+			line: "43 29 8:1 /data/with\\134slash /srv/data rw shared:5 master:2 - ext4 /dev/sda1 rw",
+			expected: &Mount{
+				ID:             43,
+				ParentID:       29,
+				Major:          8,
+				Minor:          1,
+				Root:           "/data/with\\slash",
+				MountPoint:     "/srv/data",
+				Options:        []string{"rw"},
+				IsReadOnly:     false,
+				Propagation:    []string{"shared:5", "master:2"},
 				FilesystemType: "ext4",
-				Options: []string{
-					"ro",
-				},
+				Source:         "/dev/sda1",
+				SuperOptions:   []string{"rw"},
 			},
 		},
 		{
 			line:     "Indy, bad dates",
 			expected: nil,
 		},
+		{
+			// no separator before the filesystem fields
+			line:     "29 1 8:6 / / rw,relatime ext4 /dev/sda6 rw",
+			expected: nil,
+		},
 	}
 
 	for x, test := range tests {
@@ -85,11 +127,147 @@ func TestParseMountEntry(t *testing.T) {
 				t.Fatalf("Expected nil, but got %v", actual)
 			}
 		} else if !reflect.DeepEqual(test.expected, actual) {
-			t.Fatalf("In test %d, expected %v == %v", x, test.expected, actual)
+			t.Fatalf("In test %d, expected %+v == %+v", x, test.expected, actual)
 		}
 	}
 }
 
+func TestPartitionMounts(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_BLOCK"); ok {
+		t.Skip("Skipping block tests.")
+	}
+
+	root, err := ioutil.TempDir("", "ghw-block-mounts-*")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	// sda1 is mounted on /srv and bind mounted on /var/lib/data, sda2 is a
+	// btrfs filesystem and sda3 is not mounted
+	files := map[string]string{
+		"sys/block/sda/dev":      "8:0",
+		"sys/block/sda/sda1/dev": "8:1",
+		"sys/block/sda/sda2/dev": "8:2",
+		"sys/block/sda/sda3/dev": "8:3",
+		"proc/self/mountinfo": `22 1 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw
+29 1 0:31 /@ / rw,relatime shared:1 - btrfs /dev/sda2 rw,ssd,space_cache,subvolid=256,subvol=/@
+30 29 8:1 / /srv rw,relatime shared:2 - ext4 /dev/sda1 rw
+31 29 8:1 /data /var/lib/data ro,relatime shared:2 - ext4 /dev/sda1 rw`,
+	}
+	createTestTree(t, root, files, nil)
+
+	info, err := New(option.WithChroot(root), option.WithDisableTools(), option.WithNullAlerter())
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if len(info.Mounts()) != 4 {
+		t.Fatalf("Expected 4 mounts, got %d", len(info.Mounts()))
+	}
+	if len(info.Disks) != 1 || len(info.Disks[0].Partitions) != 3 {
+		t.Fatalf("Expected 1 disk with 3 partitions, got %v", info.Disks)
+	}
+
+	expected := map[string]struct {
+		mountPoints []string
+		fsType      string
+		readOnly    bool
+	}{
+		"sda1": {[]string{"/srv", "/var/lib/data"}, "ext4", false},
+		"sda2": {[]string{"/"}, "btrfs", false},
+		"sda3": {[]string{}, "", true},
+	}
+	for _, part := range info.Disks[0].Partitions {
+		exp := expected[part.Name]
+		mountPoints := make([]string, 0)
+		for _, m := range part.Mounts {
+			mountPoints = append(mountPoints, m.MountPoint)
+			// the usage is only read on request, on the running system
+			if m.Usage != nil {
+				t.Errorf("Expected no usage for %s, got %+v", m.MountPoint, m.Usage)
+			}
+		}
+		if !reflect.DeepEqual(mountPoints, exp.mountPoints) {
+			t.Errorf("Expected %s to be mounted on %v, got %v", part.Name, exp.mountPoints, mountPoints)
+		}
+		if len(exp.mountPoints) > 0 && part.MountPoint != exp.mountPoints[0] {
+			t.Errorf("Expected %s to have mount point %q, got %q", part.Name, exp.mountPoints[0], part.MountPoint)
+		}
+		if part.Type != exp.fsType || part.IsReadOnly != exp.readOnly {
+			t.Errorf(
+				"Expected %s to have type %q and read-only %v, got %q and %v",
+				part.Name, exp.fsType, exp.readOnly, part.Type, part.IsReadOnly,
+			)
+		}
+	}
+
+	rootMount := []*Mount{{Major: 8, Minor: 1, MountPoint: "/", Source: "/dev/sda1"}}
+	ctx := context.New(option.WithNullAlerter())
+	if m := newMountIndex(ctx, rootMount).partitionMounts("sda1", "8:1"); m[0].Usage != nil {
+		t.Errorf("Expected no usage by default, got %+v", m[0].Usage)
+	}
+	ctx = context.New(
+		option.WithNullAlerter(),
+		option.WithBlockOptions(option.BlockOptions{IncludeFilesystemUsage: true}),
+	)
+	m := newMountIndex(ctx, rootMount).partitionMounts("sda1", "8:1")
+	if m[0].Usage == nil || m[0].Usage.CapacityBytes == 0 {
+		t.Errorf("Expected the usage of the root filesystem, got %+v", m[0].Usage)
+	}
+}
+
+func TestSnapshotMounts(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_BLOCK"); ok {
+		t.Skip("Skipping block tests.")
+	}
+
+	testdataPath, err := testdata.SnapshotsDirectory()
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	// this snapshot predates the mountinfo support and only has
+	// /proc/self/mounts
+	var warnings bytes.Buffer
+	info, err := New(
+		option.WithSnapshot(option.SnapshotOptions{
+			Path: filepath.Join(testdataPath, "linux-amd64-8581cf3a529e5d8b97ea876eade2f60d.tar.gz"),
+		}),
+		option.WithAlerter(log.New(&warnings, "", 0)),
+	)
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if strings.Contains(warnings.String(), "failed to read mounts") {
+		t.Errorf("Unexpected warnings: %s", warnings.String())
+	}
+
+	expected := map[string]struct {
+		mountPoint string
+		fsType     string
+	}{
+		"sda1": {"/boot/efi", "vfat"},
+		"sda2": {"/boot", "ext4"},
+	}
+	for _, disk := range info.Disks {
+		for _, part := range disk.Partitions {
+			exp, ok := expected[part.Name]
+			if !ok {
+				continue
+			}
+			delete(expected, part.Name)
+			if part.MountPoint != exp.mountPoint || part.Type != exp.fsType || part.IsReadOnly {
+				t.Errorf(
+					"Expected %q to be mounted read-write on %q as %q, got %q as %q (read-only: %v)",
+					part.Name, exp.mountPoint, exp.fsType, part.MountPoint, part.Type, part.IsReadOnly,
+				)
+			}
+		}
+	}
+	if len(expected) != 0 {
+		t.Errorf("Expected to find the partitions %v", expected)
+	}
+}
+
 func TestDiskTypes(t *testing.T) {
 	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_BLOCK"); ok {
 		t.Skip("Skipping block tests.")
@@ -129,7 +307,7 @@ func TestDiskTypes(t *testing.T) {
 			line: "xvda1",
 			expected: entry{
 				driveType:         DRIVE_TYPE_HDD,
-				storageController: STORAGE_CONTROLLER_SCSI,
+				storageController: STORAGE_CONTROLLER_XEN,
 			},
 		},
 		{
@@ -178,3 +356,967 @@ func TestDiskTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestDiskStorageController(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_BLOCK"); ok {
+		t.Skip("Skipping block tests.")
+	}
+
+	root, err := ioutil.TempDir("", "ghw-block-controller-*")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	ahciDisk := "sys/devices/pci0000:00/0000:00:17.0/ata1/host0/target0:0:0/0:0:0:0"
+	usbDisk := "sys/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/host6/target6:0:0/6:0:0:0"
+	iscsiDisk := "sys/devices/platform/host7/session1/target7:0:0/7:0:0:1"
+	xenDisk := "sys/devices/vbd-51712"
+	files := map[string]string{
+		ahciDisk + "/block/sda/size":  "1024",
+		usbDisk + "/block/sdb/size":   "1024",
+		iscsiDisk + "/block/sdc/size": "1024",
+		xenDisk + "/block/xvda/size":  "1024",
+		// not attached through any device
+		"sys/block/sdd/size": "1024",
+	}
+	links := map[string]string{
+		"sys/block/sda":  "../devices/pci0000:00/0000:00:17.0/ata1/host0/target0:0:0/0:0:0:0/block/sda",
+		"sys/block/sdb":  "../devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/host6/target6:0:0/6:0:0:0/block/sdb",
+		"sys/block/sdc":  "../devices/platform/host7/session1/target7:0:0/7:0:0:1/block/sdc",
+		"sys/block/xvda": "../devices/vbd-51712/block/xvda",
+		// the drivers of the disks themselves don't identify the controller
+		ahciDisk + "/driver":                                          "../../../../../../../bus/scsi/drivers/sd",
+		"sys/devices/pci0000:00/0000:00:17.0/driver":                  "../../../bus/pci/drivers/ahci",
+		usbDisk + "/driver":                                           "../../../../../../../../../bus/scsi/drivers/sd",
+		"sys/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/driver": "../../../../../../bus/usb/drivers/usb-storage",
+		"sys/devices/pci0000:00/0000:00:14.0/driver":                  "../../../bus/pci/drivers/xhci_hcd",
+		xenDisk + "/driver":                                           "../../bus/xen/drivers/vbd",
+	}
+	createTestTree(t, root, files, links)
+
+	info, err := New(option.WithChroot(root), option.WithDisableTools(), option.WithNullAlerter())
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	expected := map[string]StorageController{
+		"sda":  STORAGE_CONTROLLER_SATA,
+		"sdb":  STORAGE_CONTROLLER_USB,
+		"sdc":  STORAGE_CONTROLLER_ISCSI,
+		"sdd":  STORAGE_CONTROLLER_SCSI,
+		"xvda": STORAGE_CONTROLLER_XEN,
+	}
+	if len(info.Disks) != len(expected) {
+		t.Fatalf("Expected %d disks, got %d", len(expected), len(info.Disks))
+	}
+	for _, disk := range info.Disks {
+		if disk.StorageController != expected[disk.Name] {
+			t.Errorf(
+				"For %s, expected storage controller %s, but got %s",
+				disk.Name, expected[disk.Name], disk.StorageController,
+			)
+		}
+	}
+}
+
+func TestDiskPCIDeviceAndNode(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_BLOCK"); ok {
+		t.Skip("Skipping block tests.")
+	}
+
+	root, err := ioutil.TempDir("", "ghw-block-pci-*")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	// sda is attached through the AHCI controller 0000:00:17.0, which is
+	// affined to the second node of a NUMA system
+	ahci := "sys/devices/pci0000:00/0000:00:17.0"
+	files := map[string]string{
+		"usr/share/hwdata/pci.ids": "8086  Intel Corporation\n\t2826  SATA Controller [RAID mode]\n" +
+			"C 01  Mass storage controller\n\t04  RAID bus controller",
+		ahci + "/modalias":  "pci:v00008086d00002826sv00008086sd00007270bc01sc04i00",
+		ahci + "/numa_node": "1",
+		ahci + "/ata1/host0/target0:0:0/0:0:0:0/block/sda/size": "1024",
+		"sys/devices/system/node/node0/distance":                "10 20",
+		"sys/devices/system/node/node1/distance":                "20 10",
+		// not attached through any device
+		"sys/block/sdd/size": "1024",
+	}
+	links := map[string]string{
+		"sys/block/sda":                    "../devices/pci0000:00/0000:00:17.0/ata1/host0/target0:0:0/0:0:0:0/block/sda",
+		"sys/bus/pci/devices/0000:00:17.0": "../../../devices/pci0000:00/0000:00:17.0",
+	}
+	createTestTree(t, root, files, links)
+
+	info, err := New(option.WithChroot(root), option.WithDisableTools(), option.WithNullAlerter())
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if len(info.Disks) != 2 {
+		t.Fatalf("Expected 2 disks, got %d", len(info.Disks))
+	}
+
+	sda := info.Disks[0]
+	if sda.PCI == nil || sda.PCI.Address != "0000:00:17.0" {
+		t.Errorf("Expected sda to be attached through 0000:00:17.0, got %v", sda.PCI)
+	}
+	if sda.NUMANodeID != 1 {
+		t.Errorf("Expected sda to be affined to NUMA node 1, got %d", sda.NUMANodeID)
+	}
+	if sda.Node == nil || sda.Node.ID != 1 {
+		t.Errorf("Expected sda to be affined to topology node 1, got %v", sda.Node)
+	}
+
+	sdd := info.Disks[1]
+	if sdd.PCI != nil || sdd.Node != nil || sdd.NUMANodeID != -1 {
+		t.Errorf("Expected sdd to have no PCI device and node, got %v, %v and %d", sdd.PCI, sdd.Node, sdd.NUMANodeID)
+	}
+}
+
+func TestDiskQueue(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_BLOCK"); ok {
+		t.Skip("Skipping block tests.")
+	}
+
+	root, err := ioutil.TempDir("", "ghw-block-queue-*")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"sys/block/sda/queue/logical_block_size":  "512",
+		"sys/block/sda/queue/minimum_io_size":     "4096",
+		"sys/block/sda/queue/optimal_io_size":     "0",
+		"sys/block/sda/queue/scheduler":           "mq-deadline kyber [bfq] none",
+		"sys/block/sda/queue/nr_requests":         "64",
+		"sys/block/sda/queue/discard_granularity": "512",
+		"sys/block/sda/queue/discard_max_bytes":   "2147450880",
+		"sys/block/sda/queue/write_cache":         "write back",
+		"sys/block/sda/queue/zoned":               "none",
+		"sys/block/sda/queue/dax":                 "0",
+		"sys/block/sr0/size":                      "0",
+	}
+	createTestTree(t, root, files, nil)
+
+	info, err := New(option.WithChroot(root), option.WithDisableTools(), option.WithNullAlerter())
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if len(info.Disks) != 2 {
+		t.Fatalf("Expected 2 disks, got %d", len(info.Disks))
+	}
+	expected := &Queue{
+		LogicalBlockSizeBytes:   512,
+		MinimumIOSizeBytes:      4096,
+		OptimalIOSizeBytes:      0,
+		Scheduler:               "bfq",
+		AvailableSchedulers:     []string{"mq-deadline", "kyber", "bfq", "none"},
+		NumRequests:             64,
+		DiscardGranularityBytes: 512,
+		DiscardMaxBytes:         2147450880,
+		WriteCache:              "write back",
+		Zoned:                   "none",
+		IsDAX:                   false,
+	}
+	if !reflect.DeepEqual(info.Disks[0].Queue, expected) {
+		t.Errorf("Expected queue %+v, got %+v", expected, info.Disks[0].Queue)
+	}
+	if info.Disks[1].Queue != nil {
+		t.Errorf("Expected sr0 to have no queue, got %+v", info.Disks[1].Queue)
+	}
+
+	tests := []struct {
+		contents  string
+		active    string
+		available []string
+	}{
+		{"[none] mq-deadline", "none", []string{"none", "mq-deadline"}},
+		{"none", "none", []string{"none"}},
+		{"", "", []string{}},
+	}
+	for _, test := range tests {
+		active, available := parseScheduler(test.contents)
+		if active != test.active || !reflect.DeepEqual(available, test.available) {
+			t.Errorf(
+				"For %q, expected %q and %v, got %q and %v",
+				test.contents, test.active, test.available, active, available,
+			)
+		}
+	}
+}
+
+// recordedSmartReader returns the recorded smartctl outputs of the disks
+type recordedSmartReader struct {
+	dir     string
+	outputs map[string]string
+	read    []string
+}
+
+func (r *recordedSmartReader) ReadSMART(disk string) ([]byte, error) {
+	r.read = append(r.read, disk)
+	return ioutil.ReadFile(filepath.Join(r.dir, r.outputs[disk]))
+}
+
+func TestDiskHealth(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_BLOCK"); ok {
+		t.Skip("Skipping block tests.")
+	}
+
+	smartctlDir, err := testdata.SmartctlDirectory()
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	root, err := ioutil.TempDir("", "ghw-block-health-*")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"sys/block/sda/device/hwmon/hwmon3/temp1_input": "36000",
+		"sys/block/sdd/device/hwmon/hwmon4/temp1_input": "29000",
+		"sys/class/nvme/nvme0/hwmon1/temp1_input":       "40850",
+	}
+	createTestTree(t, root, files, nil)
+
+	nvme0 := &NVMeController{Name: "nvme0", State: "live"}
+	disks := []*Disk{
+		{Name: "sda", StorageController: STORAGE_CONTROLLER_SATA, DriveType: DRIVE_TYPE_SSD},
+		{Name: "sdb", StorageController: STORAGE_CONTROLLER_SAS, DriveType: DRIVE_TYPE_HDD},
+		{Name: "sdc", StorageController: STORAGE_CONTROLLER_SATA, DriveType: DRIVE_TYPE_HDD},
+		{Name: "sdd", StorageController: STORAGE_CONTROLLER_USB, DriveType: DRIVE_TYPE_HDD},
+		{
+			Name:              "nvme0n1",
+			StorageController: STORAGE_CONTROLLER_NVME,
+			DriveType:         DRIVE_TYPE_SSD,
+			NVMeNamespace:     &NVMeNamespace{Name: "nvme0n1", Controllers: []*NVMeController{nvme0}},
+		},
+		// no SMART data is read for optical drives, virtual devices and
+		// paravirtualized disks
+		{Name: "sr0", StorageController: STORAGE_CONTROLLER_SCSI, DriveType: DRIVE_TYPE_ODD},
+		{Name: "dm-0", StorageController: STORAGE_CONTROLLER_UNKNOWN, DriveType: DRIVE_TYPE_SSD},
+		{Name: "vda", StorageController: STORAGE_CONTROLLER_VIRTIO, DriveType: DRIVE_TYPE_HDD},
+	}
+	sr := &recordedSmartReader{
+		dir: smartctlDir,
+		outputs: map[string]string{
+			"sda":     "ata-ssd.json",
+			"sdb":     "scsi-failing.json",
+			"sdc":     "permission-denied.json",
+			"sdd":     "missing.json",
+			"nvme0n1": "nvme.json",
+		},
+	}
+
+	ctx := context.New(option.WithChroot(root), option.WithNullAlerter())
+	// smartctl is run only on request
+	if newSmartReader(context.New(option.WithNullAlerter())) != nil {
+		t.Errorf("Expected no SMART data to be read by default")
+	}
+	diskFillHealth(ctx, linuxpath.New(ctx), disks, sr)
+
+	expectedRead := []string{"sda", "sdb", "sdc", "sdd", "nvme0n1"}
+	if !reflect.DeepEqual(sr.read, expectedRead) {
+		t.Errorf("Expected SMART data of %v to be read, got %v", expectedRead, sr.read)
+	}
+	expected := map[string]*Health{
+		"sda": {
+			Status:             HEALTH_STATUS_PASSED,
+			TemperatureCelsius: 34,
+			PowerOnHours:       21837,
+			ReallocatedSectors: 8,
+			MediaErrors:        -1,
+			PercentageUsed:     -1,
+		},
+		"sdb": {
+			Status:             HEALTH_STATUS_FAILED,
+			TemperatureCelsius: 38,
+			PowerOnHours:       43811,
+			ReallocatedSectors: 117,
+			MediaErrors:        -1,
+			PercentageUsed:     -1,
+		},
+		// smartctl failed, and there is no temperature sensor
+		"sdc": nil,
+		// smartctl failed, but the temperature sensor is read
+		"sdd": {
+			Status:             HEALTH_STATUS_UNKNOWN,
+			TemperatureCelsius: 29,
+			PowerOnHours:       -1,
+			ReallocatedSectors: -1,
+			MediaErrors:        -1,
+			PercentageUsed:     -1,
+		},
+		"nvme0n1": {
+			Status:             HEALTH_STATUS_PASSED,
+			TemperatureCelsius: 41,
+			PowerOnHours:       9312,
+			ReallocatedSectors: -1,
+			MediaErrors:        0,
+			PercentageUsed:     3,
+		},
+		"sr0":  nil,
+		"dm-0": nil,
+		"vda":  nil,
+	}
+	for _, disk := range disks {
+		if !reflect.DeepEqual(disk.Health, expected[disk.Name]) {
+			t.Errorf("For %s, expected health %+v, got %+v", disk.Name, expected[disk.Name], disk.Health)
+		}
+	}
+
+	// without SMART data, the health comes from the temperature sensors and
+	// the NVMe controllers
+	nvme0.State = "dead"
+	for _, disk := range disks {
+		disk.Health = nil
+	}
+	diskFillHealth(ctx, linuxpath.New(ctx), disks, nil)
+	if disks[0].Health == nil || disks[0].Health.TemperatureCelsius != 36 {
+		t.Errorf("Expected sda to have a temperature of 36C, got %+v", disks[0].Health)
+	}
+	nvmeHealth := disks[4].Health
+	if nvmeHealth == nil || nvmeHealth.Status != HEALTH_STATUS_FAILED || nvmeHealth.TemperatureCelsius != 40 {
+		t.Errorf("Expected nvme0n1 to be failed with a temperature of 40C, got %+v", nvmeHealth)
+	}
+}
+
+func TestNVMeControllers(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_BLOCK"); ok {
+		t.Skip("Skipping block tests.")
+	}
+
+	root, err := ioutil.TempDir("", "ghw-block-nvme-*")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	// nvme0 is a plain controller with one namespace. nvme1 and nvme2 are
+	// two paths to the same multipath namespace, in subsystem 1.
+	pciDevs := "sys/devices/pci0000:00"
+	subsys := "sys/devices/virtual/nvme-subsystem/nvme-subsys1"
+	files := map[string]string{
+		pciDevs + "/0000:00:04.0/nvme/nvme0/model":                            "Fast NVMe disk",
+		pciDevs + "/0000:00:04.0/nvme/nvme0/serial":                           "S123",
+		pciDevs + "/0000:00:04.0/nvme/nvme0/firmware_rev":                     "1.0",
+		pciDevs + "/0000:00:04.0/nvme/nvme0/transport":                        "pcie",
+		pciDevs + "/0000:00:04.0/nvme/nvme0/address":                          "0000:00:04.0",
+		pciDevs + "/0000:00:04.0/nvme/nvme0/state":                            "live",
+		pciDevs + "/0000:00:04.0/nvme/nvme0/nvme0n1/size":                     "2048",
+		pciDevs + "/0000:00:04.0/nvme/nvme0/nvme0n1/nsid":                     "1",
+		pciDevs + "/0000:00:04.0/nvme/nvme0/nvme0n1/metadata_bytes":           "8",
+		pciDevs + "/0000:00:04.0/nvme/nvme0/nvme0n1/queue/logical_block_size": "512",
+		pciDevs + "/0000:00:05.0/nvme/nvme1/transport":                        "pcie",
+		pciDevs + "/0000:00:05.0/nvme/nvme1/nvme1c1n1/size":                   "8192",
+		pciDevs + "/0000:00:06.0/nvme/nvme2/transport":                        "pcie",
+		pciDevs + "/0000:00:06.0/nvme/nvme2/nvme1c2n1/size":                   "8192",
+		subsys + "/nvme1n1/size":                                              "8192",
+		subsys + "/nvme1n1/nsid":                                              "3",
+		subsys + "/nvme1n1/queue/logical_block_size":                          "4096",
+	}
+	links := map[string]string{
+		"sys/block/nvme0n1":    "../devices/pci0000:00/0000:00:04.0/nvme/nvme0/nvme0n1",
+		"sys/block/nvme1c1n1":  "../devices/pci0000:00/0000:00:05.0/nvme/nvme1/nvme1c1n1",
+		"sys/block/nvme1c2n1":  "../devices/pci0000:00/0000:00:06.0/nvme/nvme2/nvme1c2n1",
+		"sys/block/nvme1n1":    "../devices/virtual/nvme-subsystem/nvme-subsys1/nvme1n1",
+		"sys/class/nvme/nvme0": "../../devices/pci0000:00/0000:00:04.0/nvme/nvme0",
+		"sys/class/nvme/nvme1": "../../devices/pci0000:00/0000:00:05.0/nvme/nvme1",
+		"sys/class/nvme/nvme2": "../../devices/pci0000:00/0000:00:06.0/nvme/nvme2",
+	}
+	createTestTree(t, root, files, links)
+
+	info, err := New(option.WithChroot(root), option.WithDisableTools(), option.WithNullAlerter())
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if len(info.Disks) != 2 || info.Disks[0].Name != "nvme0n1" || info.Disks[1].Name != "nvme1n1" {
+		t.Fatalf("Expected the nvme0n1 and nvme1n1 disks only, got %v", info.Disks)
+	}
+	if len(info.NVMeControllers) != 3 {
+		t.Fatalf("Expected 3 NVMe controllers, got %d", len(info.NVMeControllers))
+	}
+
+	ctrl := info.NVMeControllers[0]
+	expectedCtrl := NVMeController{
+		Name:             "nvme0",
+		Model:            "Fast NVMe disk",
+		SerialNumber:     "S123",
+		FirmwareRevision: "1.0",
+		Transport:        "pcie",
+		Address:          "0000:00:04.0",
+		State:            "live",
+	}
+	if ctrl.Name != expectedCtrl.Name || ctrl.Model != expectedCtrl.Model ||
+		ctrl.SerialNumber != expectedCtrl.SerialNumber ||
+		ctrl.FirmwareRevision != expectedCtrl.FirmwareRevision ||
+		ctrl.Transport != expectedCtrl.Transport ||
+		ctrl.Address != expectedCtrl.Address || ctrl.State != expectedCtrl.State {
+		t.Errorf("Expected controller %v, got %v", expectedCtrl, ctrl)
+	}
+	if len(ctrl.Namespaces) != 1 {
+		t.Fatalf("Expected 1 namespace on %q, got %d", ctrl.Name, len(ctrl.Namespaces))
+	}
+	ns := ctrl.Namespaces[0]
+	if ns.Name != "nvme0n1" || ns.ID != 1 || ns.SizeBytes != 2048*sectorSize ||
+		ns.LogicalBlockSizeBytes != 512 || ns.MetadataSizeBytes != 8 {
+		t.Errorf("Unexpected namespace %v", ns)
+	}
+	if ns.Disk != info.Disks[0] || info.Disks[0].NVMeNamespace != ns {
+		t.Errorf("Expected namespace %v linked to disk nvme0n1", ns)
+	}
+
+	mpNS := info.Disks[1].NVMeNamespace
+	if mpNS == nil {
+		t.Fatalf("Expected the nvme1n1 disk linked to its namespace")
+	}
+	if mpNS.ID != 3 || mpNS.LogicalBlockSizeBytes != 4096 {
+		t.Errorf("Unexpected namespace %v", mpNS)
+	}
+	if len(mpNS.Controllers) != 2 || mpNS.Controllers[0] != info.NVMeControllers[1] ||
+		mpNS.Controllers[1] != info.NVMeControllers[2] {
+		t.Errorf("Expected namespace %v reachable through nvme1 and nvme2", mpNS)
+	}
+	if info.NVMeControllers[2].Model != util.UNKNOWN {
+		t.Errorf("Expected unknown model, got %q", info.NVMeControllers[2].Model)
+	}
+}
+
+func TestVirtualDevices(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_BLOCK"); ok {
+		t.Skip("Skipping block tests.")
+	}
+
+	root, err := ioutil.TempDir("", "ghw-block-virtual-*")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	// md0 is a degraded RAID1 array on sda1 and sdb1, and dm-0 is a LVM
+	// logical volume on md0 and sda2
+	files := map[string]string{
+		"sys/block/sda/size":              "4096",
+		"sys/block/sda/sda1/size":         "1024",
+		"sys/block/sda/sda2/size":         "1024",
+		"sys/block/sdb/size":              "4096",
+		"sys/block/sdb/sdb1/size":         "1024",
+		"sys/block/md0/size":              "1000",
+		"sys/block/md0/md/level":          "raid1",
+		"sys/block/md0/md/array_state":    "clean",
+		"sys/block/md0/md/raid_disks":     "2",
+		"sys/block/md0/md/degraded":       "1",
+		"sys/block/md0/md/dev-sda1/slot":  "0",
+		"sys/block/md0/md/dev-sda1/state": "in_sync",
+		"sys/block/md0/md/dev-sdb1/slot":  "none",
+		"sys/block/md0/md/dev-sdb1/state": "faulty",
+		"sys/block/dm-0/size":             "2000",
+		"sys/block/dm-0/dm/name":          "vg0-root",
+		"sys/block/dm-0/dm/uuid":          "LVM-abcdef",
+		"sys/block/dm-0/dm/suspended":     "0",
+	}
+	links := map[string]string{
+		"sys/block/md0/slaves/sda1":  "../../sda/sda1",
+		"sys/block/md0/slaves/sdb1":  "../../sdb/sdb1",
+		"sys/block/dm-0/slaves/md0":  "../../md0",
+		"sys/block/dm-0/slaves/sda2": "../../sda/sda2",
+	}
+	createTestTree(t, root, files, links)
+
+	info, err := New(option.WithChroot(root), option.WithDisableTools(), option.WithNullAlerter())
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if len(info.VirtualDevices) != 2 {
+		t.Fatalf("Expected 2 virtual devices, got %d", len(info.VirtualDevices))
+	}
+
+	dm := info.VirtualDevices[0]
+	if dm.Name != "dm-0" || dm.Type != VIRTUAL_DEVICE_TYPE_DEVICE_MAPPER || dm.SizeBytes != 2000*sectorSize {
+		t.Errorf("Unexpected virtual device %v", dm)
+	}
+	expectedDM := &DeviceMapper{
+		Name:      "vg0-root",
+		UUID:      "LVM-abcdef",
+		Subsystem: "LVM",
+	}
+	if !reflect.DeepEqual(dm.DeviceMapper, expectedDM) {
+		t.Errorf("Expected %+v, got %+v", expectedDM, dm.DeviceMapper)
+	}
+	if !reflect.DeepEqual(dm.Slaves, []string{"md0", "sda2"}) {
+		t.Errorf("Unexpected slaves %v", dm.Slaves)
+	}
+	if len(dm.Disks) != 2 || dm.Disks[0].Name != "sda" || dm.Disks[1].Name != "sdb" {
+		t.Errorf("Expected %v built on sda and sdb, got %v", dm, dm.Disks)
+	}
+
+	md := info.VirtualDevices[1]
+	if md.Name != "md0" || md.Type != VIRTUAL_DEVICE_TYPE_MD || md.DeviceMapper != nil {
+		t.Errorf("Unexpected virtual device %v", md)
+	}
+	expectedRAID := &RAID{
+		Level:       "raid1",
+		State:       "clean",
+		NumDevices:  2,
+		NumDegraded: 1,
+		IsDegraded:  true,
+		Members: []*RAIDMember{
+			{Name: "sda1", Slot: 0, State: "in_sync"},
+			{Name: "sdb1", Slot: -1, State: "faulty"},
+		},
+	}
+	if !reflect.DeepEqual(md.RAID, expectedRAID) {
+		t.Errorf("Expected %+v, got %+v", expectedRAID, md.RAID)
+	}
+	if len(md.Disks) != 2 || md.Disks[0].Name != "sda" || md.Disks[1].Name != "sdb" {
+		t.Errorf("Expected %v built on sda and sdb, got %v", md, md.Disks)
+	}
+}
+
+func TestPseudoDevices(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_BLOCK"); ok {
+		t.Skip("Skipping block tests.")
+	}
+
+	root, err := ioutil.TempDir("", "ghw-block-pseudo-*")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	// loop0 is attached to an image file, loop1 is detached
+	files := map[string]string{
+		"sys/block/sda/size":                "4096",
+		"sys/block/loop0/size":              "2048",
+		"sys/block/loop0/loop/backing_file": "/var/lib/images/disk.img",
+		"sys/block/loop0/loop/offset":       "1048576",
+		"sys/block/loop0/loop/autoclear":    "1",
+		"sys/block/loop1/size":              "0",
+		"sys/block/ram0/size":               "1024",
+		"sys/block/zram0/size":              "8192",
+		"sys/block/zram0/comp_algorithm":    "lzo lzo-rle lz4 [zstd]",
+		"sys/block/zram0/disksize":          "4194304",
+		"sys/block/zram0/mm_stat":           "1048576 262144 327680 0 327680 12 0 0 0",
+		"sys/block/zram0/queue/rotational":  "0",
+		"sys/block/sda/queue/rotational":    "1",
+		"sys/block/ram0/queue/rotational":   "0",
+		"sys/block/loop0/queue/rotational":  "0",
+	}
+	createTestTree(t, root, files, nil)
+
+	driveTypes := func(info *Info) map[string]DriveType {
+		types := make(map[string]DriveType)
+		for _, disk := range info.Disks {
+			types[disk.Name] = disk.DriveType
+		}
+		return types
+	}
+
+	// the default is unchanged: no loop devices, and the RAM disks are
+	// reported as regular disks
+	info, err := New(option.WithChroot(root), option.WithDisableTools(), option.WithNullAlerter())
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	expected := map[string]DriveType{
+		"sda":   DRIVE_TYPE_HDD,
+		"ram0":  DRIVE_TYPE_SSD,
+		"zram0": DRIVE_TYPE_SSD,
+	}
+	if types := driveTypes(info); !reflect.DeepEqual(types, expected) {
+		t.Errorf("Expected disks %v, got %v", expected, types)
+	}
+	for _, disk := range info.Disks {
+		if disk.Loop != nil || disk.ZRAM != nil {
+			t.Errorf("Expected no pseudo device details for %s", disk.Name)
+		}
+	}
+
+	info, err = New(
+		option.WithChroot(root),
+		option.WithDisableTools(),
+		option.WithNullAlerter(),
+		option.WithBlockOptions(option.BlockOptions{IncludePseudoDevices: true}),
+	)
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	expected = map[string]DriveType{
+		"sda":   DRIVE_TYPE_HDD,
+		"loop0": DRIVE_TYPE_LOOP,
+		"ram0":  DRIVE_TYPE_RAM,
+		"zram0": DRIVE_TYPE_ZRAM,
+	}
+	if types := driveTypes(info); !reflect.DeepEqual(types, expected) {
+		t.Errorf("Expected disks %v, got %v", expected, types)
+	}
+	if info.TotalPhysicalBytes != 4096*sectorSize {
+		t.Errorf("Expected pseudo devices excluded from the total size, got %d", info.TotalPhysicalBytes)
+	}
+	for _, disk := range info.Disks {
+		switch disk.Name {
+		case "loop0":
+			expectedLoop := &LoopDevice{
+				BackingFile: "/var/lib/images/disk.img",
+				OffsetBytes: 1048576,
+				IsAutoclear: true,
+			}
+			if !reflect.DeepEqual(disk.Loop, expectedLoop) {
+				t.Errorf("Expected %+v, got %+v", expectedLoop, disk.Loop)
+			}
+		case "zram0":
+			expectedZRAM := &ZRAMDevice{
+				CompressionAlgorithm: "zstd",
+				DiskSizeBytes:        4194304,
+				MemoryUsedBytes:      327680,
+			}
+			if !reflect.DeepEqual(disk.ZRAM, expectedZRAM) {
+				t.Errorf("Expected %+v, got %+v", expectedZRAM, disk.ZRAM)
+			}
+		default:
+			if disk.Loop != nil || disk.ZRAM != nil {
+				t.Errorf("Expected no pseudo device details for %s", disk.Name)
+			}
+		}
+	}
+}
+
+func TestStackedDevices(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_BLOCK"); ok {
+		t.Skip("Skipping block tests.")
+	}
+
+	root, err := ioutil.TempDir("", "ghw-block-stacked-*")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	// dm-0 is a LUKS mapping of sda1 and dm-1 a LVM logical volume on top
+	// of it, md0 is a RAID array on the whole sdb disk and dm-2 is built on
+	// the loop0 device, which is not reported as a disk
+	files := map[string]string{
+		"sys/block/sda/size":      "4096",
+		"sys/block/sda/sda1/size": "1024",
+		"sys/block/sdb/size":      "4096",
+		"sys/block/md0/size":      "4000",
+		"sys/block/md0/md/level":  "raid1",
+		"sys/block/dm-0/size":     "1000",
+		"sys/block/dm-0/dm/name":  "luks-root",
+		"sys/block/dm-1/size":     "1000",
+		"sys/block/dm-1/dm/name":  "vg0-root",
+		"sys/block/dm-2/size":     "100",
+		"sys/block/dm-2/dm/name":  "snapshot",
+		"sys/block/loop0/size":    "100",
+	}
+	links := map[string]string{
+		"sys/block/sda/sda1/holders/dm-0": "../../../dm-0",
+		"sys/block/dm-0/slaves/sda1":      "../../sda/sda1",
+		"sys/block/dm-0/holders/dm-1":     "../../dm-1",
+		"sys/block/dm-1/slaves/dm-0":      "../../dm-0",
+		"sys/block/sdb/holders/md0":       "../../md0",
+		"sys/block/md0/slaves/sdb":        "../../sdb",
+		"sys/block/dm-2/slaves/loop0":     "../../loop0",
+	}
+	createTestTree(t, root, files, links)
+
+	info, err := New(option.WithChroot(root), option.WithDisableTools(), option.WithNullAlerter())
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	disks := make(map[string]*Disk)
+	for _, disk := range info.Disks {
+		disks[disk.Name] = disk
+	}
+
+	sda1 := disks["sda"].Partitions[0]
+	if len(sda1.Holders) != 1 || sda1.Holders[0].Disk != disks["dm-0"] {
+		t.Fatalf("Expected sda1 held by dm-0, got %v", sda1.Holders)
+	}
+	if len(sda1.Slaves) != 0 || len(disks["sda"].Holders) != 0 {
+		t.Errorf("Unexpected stacked devices of sda and sda1")
+	}
+	dm0 := disks["dm-0"]
+	if len(dm0.Slaves) != 1 || dm0.Slaves[0].Partition != sda1 || dm0.Slaves[0].Disk != nil {
+		t.Errorf("Expected dm-0 built on sda1, got %v", dm0.Slaves)
+	}
+	if len(dm0.Holders) != 1 || dm0.Holders[0].Disk != disks["dm-1"] {
+		t.Errorf("Expected dm-0 held by dm-1, got %v", dm0.Holders)
+	}
+	dm1 := disks["dm-1"]
+	if len(dm1.Slaves) != 1 || dm1.Slaves[0].Disk != dm0 || len(dm1.Holders) != 0 {
+		t.Errorf("Expected dm-1 built on dm-0 only, got %v", dm1.Slaves)
+	}
+
+	sdb := disks["sdb"]
+	if len(sdb.Holders) != 1 || sdb.Holders[0].Disk != disks["md0"] {
+		t.Errorf("Expected sdb held by md0, got %v", sdb.Holders)
+	}
+	md0 := disks["md0"]
+	if len(md0.Slaves) != 1 || md0.Slaves[0].Disk != sdb {
+		t.Errorf("Expected md0 built on sdb, got %v", md0.Slaves)
+	}
+
+	dm2 := disks["dm-2"]
+	if len(dm2.Slaves) != 1 || dm2.Slaves[0].Name != "loop0" ||
+		dm2.Slaves[0].Disk != nil || dm2.Slaves[0].Partition != nil {
+		t.Errorf("Expected dm-2 built on the unresolved loop0, got %v", dm2.Slaves)
+	}
+
+	// the stacked devices are serialized by name
+	b, err := json.Marshal(dm0)
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if !strings.Contains(string(b), `"holders":["dm-1"],"slaves":["sda1"]`) {
+		t.Errorf("Unexpected serialization %s", b)
+	}
+}
+
+func TestPartitionIdentifiers(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_BLOCK"); ok {
+		t.Skip("Skipping block tests.")
+	}
+
+	root, err := ioutil.TempDir("", "ghw-block-partitions-*")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	// udev knows about the partitions of sda, while the identifiers of the
+	// partitions of sdb (GPT) and sdc (MBR) are read from their device files
+	files := map[string]string{
+		"sys/block/sda/dev":                      "8:0",
+		"sys/block/sda/sda1/dev":                 "8:1",
+		"sys/block/sda/sda1/partition":           "1",
+		"sys/block/sdb/dev":                      "8:16",
+		"sys/block/sdb/queue/logical_block_size": "512",
+		"sys/block/sdb/sdb1/dev":                 "8:17",
+		"sys/block/sdb/sdb1/partition":           "1",
+		"sys/block/sdb/sdb2/dev":                 "8:18",
+		"sys/block/sdb/sdb2/partition":           "2",
+		"sys/block/sdc/dev":                      "8:32",
+		"sys/block/sdc/sdc1/dev":                 "8:33",
+		"sys/block/sdc/sdc1/partition":           "1",
+		"sys/block/sdc/sdc5/dev":                 "8:37",
+		"sys/block/sdc/sdc5/partition":           "5",
+		"run/udev/data/b8:1": `E:ID_PART_ENTRY_UUID=3f8a1e2c-0d6b-4a57-9e21-6c1f0b2d7a10
+E:ID_PART_ENTRY_TYPE=c12a7328-f81f-11d2-ba4b-00a0c93ec93b
+E:ID_PART_ENTRY_NAME=EFI\x20System
+E:ID_FS_UUID=1A2B-3C4D
+E:ID_FS_LABEL=boot_fs
+E:ID_FS_LABEL_ENC=boot\x20fs`,
+	}
+	createTestTree(t, root, files, nil)
+	images := map[string][]byte{
+		"dev/sdb": testGPTImage(),
+		"dev/sdc": testMBRImage(),
+	}
+	for path, contents := range images {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("Unable to create %q: %v", filepath.Dir(path), err)
+		}
+		if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+			t.Fatalf("Unable to write %q: %v", path, err)
+		}
+	}
+
+	// the partition tables are read only on request
+	info, err := New(option.WithChroot(root), option.WithDisableTools(), option.WithNullAlerter())
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	for _, disk := range info.Disks {
+		for _, part := range disk.Partitions {
+			if part.Name != "sda1" && (part.UUID != "" || part.TypeID != "") {
+				t.Errorf("Expected no identifiers for %q, got %q and %q", part.Name, part.UUID, part.TypeID)
+			}
+		}
+	}
+
+	info, err = New(
+		option.WithChroot(root),
+		option.WithDisableTools(),
+		option.WithNullAlerter(),
+		option.WithBlockOptions(option.BlockOptions{ReadPartitionTables: true}),
+	)
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	expected := map[string]*Partition{
+		"sda1": {
+			UUID:            "3f8a1e2c-0d6b-4a57-9e21-6c1f0b2d7a10",
+			TypeID:          "c12a7328-f81f-11d2-ba4b-00a0c93ec93b",
+			Label:           "EFI System",
+			FilesystemUUID:  "1A2B-3C4D",
+			FilesystemLabel: "boot fs",
+		},
+		"sdb1": {
+			UUID:   "01234567-89ab-cdef-0123-456789abcdef",
+			TypeID: "0fc63daf-8483-4772-8e79-3d69d8477de4",
+			Label:  "root",
+		},
+		"sdb2": {
+			UUID:   "fedcba98-7654-3210-fedc-ba9876543210",
+			TypeID: "0657fd6d-a4ab-43c4-84e5-0933c84b4f4f",
+			Label:  "swap",
+		},
+		"sdc1": {
+			UUID:   "0a1b2c3d-01",
+			TypeID: "0x83",
+		},
+		"sdc5": {
+			UUID:   "0a1b2c3d-05",
+			TypeID: "0x82",
+		},
+	}
+	found := 0
+	for _, disk := range info.Disks {
+		for _, part := range disk.Partitions {
+			exp, ok := expected[part.Name]
+			if !ok {
+				t.Fatalf("Unexpected partition %q", part.Name)
+			}
+			found++
+			if part.UUID != exp.UUID || part.TypeID != exp.TypeID || part.Label != exp.Label {
+				t.Errorf(
+					"Expected %q to have UUID %q, type %q and label %q, got %q, %q and %q",
+					part.Name, exp.UUID, exp.TypeID, exp.Label, part.UUID, part.TypeID, part.Label,
+				)
+			}
+			if part.FilesystemUUID != exp.FilesystemUUID || part.FilesystemLabel != exp.FilesystemLabel {
+				t.Errorf(
+					"Expected %q to have filesystem UUID %q and label %q, got %q and %q",
+					part.Name, exp.FilesystemUUID, exp.FilesystemLabel, part.FilesystemUUID, part.FilesystemLabel,
+				)
+			}
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("Expected %d partitions, got %d", len(expected), found)
+	}
+}
+
+func TestReadPartitionTableMalformed(t *testing.T) {
+	tCases := []struct {
+		name       string
+		numEntries uint32
+		entrySize  uint32
+	}{
+		// the size of the entries overflows 32 bits, to 0 bytes whose CRC
+		// is 0
+		{"entries size overflow", 1024, 1 << 22},
+		{"oversized entries", 4, 8192},
+		{"undersized entries", 4, 64},
+		{"too many entries", 4096, 128},
+	}
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			img := testGPTImage()
+			header := img[512:1024]
+			binary.LittleEndian.PutUint32(header[80:84], tCase.numEntries)
+			binary.LittleEndian.PutUint32(header[84:88], tCase.entrySize)
+			binary.LittleEndian.PutUint32(header[88:92], 0)
+			binary.LittleEndian.PutUint32(header[16:20], 0)
+			binary.LittleEndian.PutUint32(header[16:20], crc32.ChecksumIEEE(header[:92]))
+			if _, err := readPartitionTable(bytes.NewReader(img), 512); err == nil {
+				t.Errorf("Expected error reading the partition table")
+			}
+		})
+	}
+}
+
+// testGPTImage returns the first blocks of a GPT disk with a Linux
+// filesystem partition named "root" and a Linux swap partition named "swap"
+func testGPTImage() []byte {
+	const blockSize = 512
+	img := make([]byte, 3*blockSize)
+	// protective MBR
+	img[446+4] = 0xee
+	img[510], img[511] = 0x55, 0xaa
+
+	guid := func(b []byte, s string) {
+		raw, _ := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+		// the first three fields are stored in little-endian order
+		raw[0], raw[1], raw[2], raw[3] = raw[3], raw[2], raw[1], raw[0]
+		raw[4], raw[5] = raw[5], raw[4]
+		raw[6], raw[7] = raw[7], raw[6]
+		copy(b, raw)
+	}
+	entries := img[2*blockSize:]
+	parts := []struct {
+		typeID string
+		uuid   string
+		label  string
+	}{
+		{"0fc63daf-8483-4772-8e79-3d69d8477de4", "01234567-89ab-cdef-0123-456789abcdef", "root"},
+		{"0657fd6d-a4ab-43c4-84e5-0933c84b4f4f", "fedcba98-7654-3210-fedc-ba9876543210", "swap"},
+	}
+	for i, part := range parts {
+		entry := entries[i*128 : (i+1)*128]
+		guid(entry[0:16], part.typeID)
+		guid(entry[16:32], part.uuid)
+		for j, c := range part.label {
+			binary.LittleEndian.PutUint16(entry[56+2*j:], uint16(c))
+		}
+	}
+
+	header := img[blockSize : 2*blockSize]
+	copy(header, "EFI PART")
+	binary.LittleEndian.PutUint32(header[12:16], 92)
+	binary.LittleEndian.PutUint64(header[72:80], 2)
+	binary.LittleEndian.PutUint32(header[80:84], 4)
+	binary.LittleEndian.PutUint32(header[84:88], 128)
+	binary.LittleEndian.PutUint32(header[88:92], crc32.ChecksumIEEE(entries))
+	binary.LittleEndian.PutUint32(header[16:20], crc32.ChecksumIEEE(header[:92]))
+	return img
+}
+
+// testMBRImage returns the first blocks of a MBR disk with a Linux primary
+// partition and a Linux swap logical partition in an extended partition
+func testMBRImage() []byte {
+	const blockSize = 512
+	img := make([]byte, 5*blockSize)
+	binary.LittleEndian.PutUint32(img[440:444], 0x0a1b2c3d)
+	img[446+4] = 0x83
+	binary.LittleEndian.PutUint32(img[446+8:], 2048)
+	img[462+4] = 0x05
+	binary.LittleEndian.PutUint32(img[462+8:], 4)
+	img[510], img[511] = 0x55, 0xaa
+
+	// EBR of the only logical partition, at the start of the extended
+	// partition
+	ebr := img[4*blockSize:]
+	ebr[446+4] = 0x82
+	binary.LittleEndian.PutUint32(ebr[446+8:], 2048)
+	ebr[510], ebr[511] = 0x55, 0xaa
+	return img
+}
+
+// createTestTree creates a fake sysfs tree in root, with the given files
+// (path -> contents) and symlinks (path -> destination)
+func createTestTree(t *testing.T, root string, files map[string]string, links map[string]string) {
+	for path, contents := range files {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("Unable to create %q: %v", filepath.Dir(path), err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents+"\n"), 0644); err != nil {
+			t.Fatalf("Unable to write %q: %v", path, err)
+		}
+	}
+	for path, dest := range links {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("Unable to create %q: %v", filepath.Dir(path), err)
+		}
+		if err := os.Symlink(dest, path); err != nil {
+			t.Fatalf("Unable to link %q: %v", path, err)
+		}
+	}
+}
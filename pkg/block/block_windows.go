@@ -207,6 +207,8 @@ func toStorageController(interfaceType string) StorageController {
 		storageController = STORAGE_CONTROLLER_SCSI
 	case "IDE":
 		storageController = STORAGE_CONTROLLER_IDE
+	case "USB":
+		storageController = STORAGE_CONTROLLER_USB
 	default:
 		storageController = STORAGE_CONTROLLER_UNKNOWN
 	}
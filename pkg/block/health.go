@@ -0,0 +1,166 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package block
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// HealthStatus describes the overall health assessment of a disk, as
+// reported by the disk itself
+type HealthStatus int
+
+const (
+	HEALTH_STATUS_UNKNOWN HealthStatus = iota
+	HEALTH_STATUS_PASSED               // The disk reports no problem
+	HEALTH_STATUS_FAILED               // The disk reports it is failing, or is unreachable
+)
+
+var (
+	healthStatusString = map[HealthStatus]string{
+		HEALTH_STATUS_UNKNOWN: "Unknown",
+		HEALTH_STATUS_PASSED:  "Passed",
+		HEALTH_STATUS_FAILED:  "Failed",
+	}
+)
+
+func (hs HealthStatus) String() string {
+	return healthStatusString[hs]
+}
+
+// NOTE(jaypipes): since serialized output is as "official" as we're going to
+// get, let's lowercase the string output when serializing, in order to
+// "normalize" the expected serialized output
+func (hs HealthStatus) MarshalJSON() ([]byte, error) {
+	return []byte("\"" + strings.ToLower(hs.String()) + "\""), nil
+}
+
+// Health describes the health of a disk. The counters which could not be
+// determined are -1.
+type Health struct {
+	Status             HealthStatus `json:"status"`
+	TemperatureCelsius int          `json:"temperature_celsius"`
+	PowerOnHours       int64        `json:"power_on_hours"`
+	// ReallocatedSectors is the number of sectors remapped to spare sectors
+	// on ATA disks, or the number of grown defects on SCSI disks
+	ReallocatedSectors int64 `json:"reallocated_sectors"`
+	// MediaErrors is the number of unrecovered data integrity errors of NVMe
+	// disks
+	MediaErrors int64 `json:"media_errors"`
+	// PercentageUsed is the vendor estimate of the life of NVMe disks which
+	// has been used. It may exceed 100.
+	PercentageUsed int `json:"percentage_used"`
+}
+
+func newHealth() *Health {
+	return &Health{
+		TemperatureCelsius: -1,
+		PowerOnHours:       -1,
+		ReallocatedSectors: -1,
+		MediaErrors:        -1,
+		PercentageUsed:     -1,
+	}
+}
+
+func (h *Health) String() string {
+	tempStr := ""
+	if h.TemperatureCelsius != -1 {
+		tempStr = fmt.Sprintf(" temperature=%dC", h.TemperatureCelsius)
+	}
+	hoursStr := ""
+	if h.PowerOnHours != -1 {
+		hoursStr = fmt.Sprintf(" power_on_hours=%d", h.PowerOnHours)
+	}
+	return fmt.Sprintf("health %s%s%s", h.Status.String(), tempStr, hoursStr)
+}
+
+// smartctlOutput contains the parts of the `smartctl --json` output we care
+// about. The sections are pointers since smartctl only reports those which
+// apply to the device type.
+type smartctlOutput struct {
+	Smartctl struct {
+		ExitStatus int `json:"exit_status"`
+		Messages   []struct {
+			String string `json:"string"`
+		} `json:"messages"`
+	} `json:"smartctl"`
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	PowerOnTime *struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+	Temperature *struct {
+		Current int `json:"current"`
+	} `json:"temperature"`
+	ATASmartAttributes *struct {
+		Table []struct {
+			ID  int `json:"id"`
+			Raw struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+	NVMeSmartHealthInformationLog *struct {
+		PercentageUsed int   `json:"percentage_used"`
+		MediaErrors    int64 `json:"media_errors"`
+	} `json:"nvme_smart_health_information_log"`
+	SCSIGrownDefectList *int64 `json:"scsi_grown_defect_list"`
+}
+
+const (
+	// smartctl exit status bits telling the command line could not be
+	// parsed, and the device could not be opened
+	smartctlExitStatusFatal = 0x3
+	// ATA SMART attribute counting the reallocated sectors
+	ataAttributeReallocatedSectors = 5
+)
+
+// parseSmartctlOutput updates the supplied health with the values found in
+// the output of `smartctl --json`
+func parseSmartctlOutput(out []byte, health *Health) error {
+	var so smartctlOutput
+	if err := json.Unmarshal(out, &so); err != nil {
+		return fmt.Errorf("failed to parse smartctl output: %s", err)
+	}
+	if so.Smartctl.ExitStatus&smartctlExitStatusFatal != 0 {
+		msg := fmt.Sprintf("exit status %d", so.Smartctl.ExitStatus)
+		if len(so.Smartctl.Messages) > 0 {
+			msg = so.Smartctl.Messages[0].String
+		}
+		return fmt.Errorf("smartctl failed: %s", msg)
+	}
+	if so.SmartStatus != nil {
+		health.Status = HEALTH_STATUS_FAILED
+		if so.SmartStatus.Passed {
+			health.Status = HEALTH_STATUS_PASSED
+		}
+	}
+	if so.PowerOnTime != nil {
+		health.PowerOnHours = so.PowerOnTime.Hours
+	}
+	if so.Temperature != nil {
+		health.TemperatureCelsius = so.Temperature.Current
+	}
+	if so.ATASmartAttributes != nil {
+		for _, attr := range so.ATASmartAttributes.Table {
+			if attr.ID == ataAttributeReallocatedSectors {
+				health.ReallocatedSectors = attr.Raw.Value
+			}
+		}
+	}
+	if so.NVMeSmartHealthInformationLog != nil {
+		health.PercentageUsed = so.NVMeSmartHealthInformationLog.PercentageUsed
+		health.MediaErrors = so.NVMeSmartHealthInformationLog.MediaErrors
+	}
+	if so.SCSIGrownDefectList != nil {
+		health.ReallocatedSectors = *so.SCSIGrownDefectList
+	}
+	return nil
+}
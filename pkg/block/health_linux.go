@@ -0,0 +1,136 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package block
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/jaypipes/ghw/pkg/context"
+	"github.com/jaypipes/ghw/pkg/linuxpath"
+)
+
+// smartReader returns the `smartctl --json` output for a disk. The output is
+// parsed separately so that recorded outputs can be used in tests.
+type smartReader interface {
+	ReadSMART(disk string) ([]byte, error)
+}
+
+type smartctl struct{}
+
+func (s *smartctl) ReadSMART(disk string) ([]byte, error) {
+	// don't spin up disks in standby to read their SMART data
+	out, err := exec.Command("smartctl", "--json", "--all", "--nocheck=standby", "/dev/"+disk).Output()
+	// smartctl sets bits of its exit status for disk problems too, in which
+	// case the SMART data is still reported
+	if len(out) > 0 {
+		return out, nil
+	}
+	return nil, err
+}
+
+// newSmartReader returns the smartReader to use with the given context, or
+// nil if SMART data is not requested or can't be read. SMART data is
+// optional, so there is no warning when smartctl is not installed or can't
+// open the devices.
+func newSmartReader(ctx *context.Context) smartReader {
+	// smartctl reads the devices of the running system, not the ones of the
+	// chroot or snapshot
+	if !ctx.BlockSMARTData || !ctx.EnableTools || ctx.Chroot != "/" {
+		return nil
+	}
+	if _, err := exec.LookPath("smartctl"); err != nil {
+		return nil
+	}
+	// smartctl requires root privileges to open the devices
+	if os.Geteuid() != 0 {
+		return nil
+	}
+	return &smartctl{}
+}
+
+// diskFillHealth sets the health of the disks, from their hwmon temperature
+// sensors and NVMe controllers, and from their SMART data if the supplied
+// smartReader is not nil. Disks with no health information are left with a
+// nil Health.
+func diskFillHealth(ctx *context.Context, paths *linuxpath.Paths, disks []*Disk, sr smartReader) {
+	for _, disk := range disks {
+		// virtual devices, and removable media drives
+		if disk.StorageController == STORAGE_CONTROLLER_UNKNOWN ||
+			disk.DriveType == DRIVE_TYPE_ODD || disk.DriveType == DRIVE_TYPE_FDD {
+			continue
+		}
+		health := newHealth()
+		found := false
+		if temp, ok := diskTemperature(paths, disk); ok {
+			health.TemperatureCelsius = temp
+			found = true
+		}
+		if disk.NVMeNamespace != nil {
+			for _, ctrl := range disk.NVMeNamespace.Controllers {
+				// the controller has been removed after a failure
+				if ctrl.State == "dead" {
+					health.Status = HEALTH_STATUS_FAILED
+					found = true
+				}
+			}
+		}
+		if sr != nil && diskHasSMART(disk) {
+			if out, err := sr.ReadSMART(disk.Name); err != nil {
+				ctx.Warn("failed to read SMART data of %s: %s\n", disk.Name, err)
+			} else if err := parseSmartctlOutput(out, health); err != nil {
+				ctx.Warn("failed to read SMART data of %s: %s\n", disk.Name, err)
+			} else {
+				found = true
+			}
+		}
+		if found {
+			disk.Health = health
+		}
+	}
+}
+
+// diskHasSMART returns whether the given disk may report SMART data.
+// Paravirtualized disks and MMC cards don't.
+func diskHasSMART(disk *Disk) bool {
+	switch disk.StorageController {
+	case STORAGE_CONTROLLER_VIRTIO, STORAGE_CONTROLLER_XEN, STORAGE_CONTROLLER_MMC:
+		return false
+	}
+	return true
+}
+
+// diskTemperature returns the temperature reported by the hwmon sensor of the
+// given disk, provided by the drivetemp driver for ATA disks and by the nvme
+// driver for NVMe controllers
+func diskTemperature(paths *linuxpath.Paths, disk *Disk) (int, bool) {
+	patterns := []string{
+		filepath.Join(paths.SysBlock, disk.Name, "device", "hwmon", "hwmon*", "temp1_input"),
+		filepath.Join(paths.SysBlock, disk.Name, "device", "hwmon*", "temp1_input"),
+	}
+	if disk.NVMeNamespace != nil {
+		for _, ctrl := range disk.NVMeNamespace.Controllers {
+			patterns = append(
+				patterns,
+				filepath.Join(paths.SysClassNVMe, ctrl.Name, "hwmon*", "temp1_input"),
+			)
+		}
+	}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil || len(matches) == 0 {
+			continue
+		}
+		milliCelsius, err := strconv.Atoi(readStringFile(matches[0]))
+		if err != nil {
+			continue
+		}
+		return milliCelsius / 1000, true
+	}
+	return 0, false
+}
@@ -0,0 +1,66 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package block
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FilesystemUsage describes the space used on a mounted filesystem
+type FilesystemUsage struct {
+	CapacityBytes uint64 `json:"capacity_bytes"`
+	UsedBytes     uint64 `json:"used_bytes"`
+	// FreeBytes is the space available to unprivileged users, which
+	// excludes the space reserved to root
+	FreeBytes uint64 `json:"free_bytes"`
+}
+
+// Mount describes a filesystem mounted on the host system
+type Mount struct {
+	ID       int `json:"id"`
+	ParentID int `json:"parent_id"`
+	// Major and Minor are the device number of the filesystem. Filesystems
+	// not backed by a block device (and btrfs) have a major number of 0.
+	Major uint32 `json:"major"`
+	Minor uint32 `json:"minor"`
+	// Root is the directory of the filesystem which is mounted, which is
+	// "/" unless the mount is a bind mount of a subdirectory
+	Root       string `json:"root"`
+	MountPoint string `json:"mount_point"`
+	// Options are the per-mount options, e.g. "rw" or "noatime"
+	Options    []string `json:"options"`
+	IsReadOnly bool     `json:"read_only"`
+	// Propagation lists the peer groups of the mount, e.g. "shared:1" or
+	// "master:2". Empty for private mounts.
+	Propagation    []string `json:"propagation"`
+	FilesystemType string   `json:"filesystem_type"`
+	// Source is the filesystem-specific source of the mount, e.g. "/dev/sda1"
+	Source string `json:"source"`
+	// SuperOptions are the per-filesystem options
+	SuperOptions []string `json:"super_options"`
+	// Usage of the filesystem. Only set on request for the mounts of
+	// partitions, when not reading from a chroot or snapshot.
+	Usage *FilesystemUsage `json:"usage,omitempty"`
+}
+
+func (m *Mount) String() string {
+	return fmt.Sprintf(
+		"%s on %s type %s (%s)",
+		m.Source,
+		m.MountPoint,
+		m.FilesystemType,
+		strings.Join(m.Options, ","),
+	)
+}
+
+// Mounts returns the filesystems mounted on the host system, in the order
+// they were mounted. The Mounts field of each Partition lists the mounts of
+// that partition.
+func (i *Info) Mounts() []*Mount {
+	return i.mounts
+}
@@ -0,0 +1,233 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package block
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/jaypipes/ghw/pkg/context"
+	"github.com/jaypipes/ghw/pkg/linuxpath"
+	"github.com/jaypipes/ghw/pkg/util"
+)
+
+// mounts returns the filesystems listed in /proc/self/mountinfo, or in
+// /proc/self/mounts for the snapshots which don't include mountinfo
+func mounts(ctx *context.Context, paths *linuxpath.Paths) []*Mount {
+	out, err := readMounts(paths.ProcMountInfo, parseMountEntry)
+	if os.IsNotExist(err) {
+		out, err = readMounts(paths.ProcMounts, parseMountsEntry)
+	}
+	if err != nil {
+		ctx.Warn("failed to read mounts: %s\n", err)
+		return make([]*Mount, 0)
+	}
+	return out
+}
+
+// readMounts returns the mounts parsed from each line of the given file with
+// the supplied function
+func readMounts(path string, parse func(string) *Mount) ([]*Mount, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer util.SafeClose(r)
+
+	out := make([]*Mount, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if m := parse(scanner.Text()); m != nil {
+			out = append(out, m)
+		}
+	}
+	return out, scanner.Err()
+}
+
+// parseMountEntry parses a line of /proc/self/mountinfo, or returns nil if
+// the line is malformed
+func parseMountEntry(line string) *Mount {
+	// mountinfo entries look like this, with a variable number of optional
+	// propagation fields terminated by a "-" field:
+	// 36 35 98:0 /mnt1 /mnt/parent rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+	fields := strings.Fields(line)
+	sep := -1
+	for x, field := range fields {
+		if field == "-" {
+			sep = x
+			break
+		}
+	}
+	if sep < 6 || len(fields) < sep+4 {
+		return nil
+	}
+	id, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return nil
+	}
+	parentID, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil
+	}
+	devNo := strings.SplitN(fields[2], ":", 2)
+	if len(devNo) != 2 {
+		return nil
+	}
+	major, err := strconv.ParseUint(devNo[0], 10, 32)
+	if err != nil {
+		return nil
+	}
+	minor, err := strconv.ParseUint(devNo[1], 10, 32)
+	if err != nil {
+		return nil
+	}
+
+	m := &Mount{
+		ID:             id,
+		ParentID:       parentID,
+		Major:          uint32(major),
+		Minor:          uint32(minor),
+		Root:           unescapeMountField(fields[3]),
+		MountPoint:     unescapeMountField(fields[4]),
+		Options:        strings.Split(fields[5], ","),
+		IsReadOnly:     true,
+		Propagation:    append([]string{}, fields[6:sep]...),
+		FilesystemType: fields[sep+1],
+		Source:         unescapeMountField(fields[sep+2]),
+		SuperOptions:   strings.Split(fields[sep+3], ","),
+	}
+	for _, opt := range m.Options {
+		if opt == "rw" {
+			m.IsReadOnly = false
+			break
+		}
+	}
+	return m
+}
+
+// parseMountsEntry parses a line of /proc/self/mounts, or returns nil if the
+// line is malformed. The entries of /proc/self/mounts have neither IDs, nor
+// device numbers, roots and propagation, and mix the per-mount and
+// per-filesystem options:
+// /dev/sda6 / ext4 rw,relatime,errors=remount-ro 0 0
+func parseMountsEntry(line string) *Mount {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return nil
+	}
+	m := &Mount{
+		MountPoint:     unescapeMountField(fields[1]),
+		Options:        strings.Split(fields[3], ","),
+		IsReadOnly:     true,
+		Propagation:    []string{},
+		FilesystemType: fields[2],
+		Source:         unescapeMountField(fields[0]),
+		SuperOptions:   []string{},
+	}
+	for _, opt := range m.Options {
+		if opt == "rw" {
+			m.IsReadOnly = false
+			break
+		}
+	}
+	return m
+}
+
+// unescapeMountField decodes the space, tab, newline and backslash
+// characters of a mountinfo field. From the GNU mtab man pages:
+//
+//	"Therefore these characters are encoded in the files and the getmntent
+//	function takes care of the decoding while reading the entries back in.
+//	'\040' is used to encode a space character, '\011' to encode a tab
+//	character, '\012' to encode a newline character, and '\\' to encode a
+//	backslash."
+//
+// The kernel encodes the backslash as '\134', like the other characters.
+func unescapeMountField(field string) string {
+	if !strings.Contains(field, "\\") {
+		return field
+	}
+	var b strings.Builder
+	for x := 0; x < len(field); x++ {
+		if field[x] == '\\' && x+3 < len(field) {
+			if c, err := strconv.ParseUint(field[x+1:x+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				x += 3
+				continue
+			}
+		}
+		if field[x] == '\\' && x+1 < len(field) && field[x+1] == '\\' {
+			b.WriteByte('\\')
+			x++
+			continue
+		}
+		b.WriteByte(field[x])
+	}
+	return b.String()
+}
+
+// mountIndex indexes mounts by the device number and the source of their
+// filesystem
+type mountIndex struct {
+	byDevNo  map[string][]*Mount
+	bySource map[string][]*Mount
+	// whether to read the usage of the mounted filesystems, which is only
+	// done on request, and possible for the running system only
+	withUsage bool
+}
+
+func newMountIndex(ctx *context.Context, mounts []*Mount) *mountIndex {
+	idx := &mountIndex{
+		byDevNo:   make(map[string][]*Mount),
+		bySource:  make(map[string][]*Mount),
+		withUsage: ctx.BlockFilesystemUsage && ctx.Chroot == "/",
+	}
+	for _, m := range mounts {
+		devNo := strconv.FormatUint(uint64(m.Major), 10) + ":" + strconv.FormatUint(uint64(m.Minor), 10)
+		idx.byDevNo[devNo] = append(idx.byDevNo[devNo], m)
+		idx.bySource[m.Source] = append(idx.bySource[m.Source], m)
+	}
+	return idx
+}
+
+// partitionMounts returns the mounts of the partition with the given name
+// and major:minor device number, bind mounts included
+func (idx *mountIndex) partitionMounts(part string, devNo string) []*Mount {
+	mounts, ok := idx.byDevNo[devNo]
+	if !ok {
+		// btrfs reports anonymous device numbers in mountinfo
+		mounts = idx.bySource["/dev/"+part]
+	}
+	if mounts == nil {
+		mounts = make([]*Mount, 0)
+	}
+	if idx.withUsage {
+		for _, m := range mounts {
+			if m.Usage == nil {
+				m.Usage = filesystemUsage(m.MountPoint)
+			}
+		}
+	}
+	return mounts
+}
+
+// filesystemUsage returns the usage of the filesystem mounted at the given
+// mountpoint, or nil if it can't be determined
+func filesystemUsage(mountPoint string) *FilesystemUsage {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(mountPoint, &st); err != nil {
+		return nil
+	}
+	blockSize := uint64(st.Bsize)
+	return &FilesystemUsage{
+		CapacityBytes: st.Blocks * blockSize,
+		UsedBytes:     (st.Blocks - st.Bfree) * blockSize,
+		FreeBytes:     st.Bavail * blockSize,
+	}
+}
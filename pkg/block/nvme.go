@@ -0,0 +1,129 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package block
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// NVMeController describes a NVMe controller. With NVMe multipath, the same
+// namespaces are reachable through more than one controller.
+type NVMeController struct {
+	// Name is the name of the controller character device, e.g. "nvme0"
+	Name             string `json:"name"`
+	Model            string `json:"model"`
+	SerialNumber     string `json:"serial_number"`
+	FirmwareRevision string `json:"firmware_revision"`
+	// Transport is "pcie" for local controllers, or the fabric ("rdma",
+	// "tcp", "fc", "loop") the controller is reached through
+	Transport string `json:"transport"`
+	// Address is the PCI address of local controllers, or the transport
+	// address (e.g. "traddr=192.168.1.1,trsvcid=4420") of fabric controllers
+	Address string `json:"address"`
+	// State is the state of the controller, e.g. "live" or "connecting"
+	State string `json:"state"`
+	// The namespaces attached to the controller, sorted by name
+	Namespaces []*NVMeNamespace `json:"-"`
+}
+
+type nvmeControllerMarshallable struct {
+	Name             string   `json:"name"`
+	Model            string   `json:"model"`
+	SerialNumber     string   `json:"serial_number"`
+	FirmwareRevision string   `json:"firmware_revision"`
+	Transport        string   `json:"transport"`
+	Address          string   `json:"address"`
+	State            string   `json:"state"`
+	Namespaces       []string `json:"namespaces"`
+}
+
+// MarshalJSON serializes the names of the namespaces attached to the
+// controller, instead of the namespaces themselves
+func (c *NVMeController) MarshalJSON() ([]byte, error) {
+	cm := nvmeControllerMarshallable{
+		Name:             c.Name,
+		Model:            c.Model,
+		SerialNumber:     c.SerialNumber,
+		FirmwareRevision: c.FirmwareRevision,
+		Transport:        c.Transport,
+		Address:          c.Address,
+		State:            c.State,
+		Namespaces:       make([]string, 0, len(c.Namespaces)),
+	}
+	for _, ns := range c.Namespaces {
+		cm.Namespaces = append(cm.Namespaces, ns.Name)
+	}
+	return json.Marshal(cm)
+}
+
+func (c *NVMeController) String() string {
+	return fmt.Sprintf(
+		"%s %s model=%s serial=%s firmware=%s (%d namespaces)",
+		c.Name,
+		c.Transport,
+		c.Model,
+		c.SerialNumber,
+		c.FirmwareRevision,
+		len(c.Namespaces),
+	)
+}
+
+// NVMeNamespace describes a NVMe namespace, exposed as a Disk
+type NVMeNamespace struct {
+	// Name is the name of the block device of the namespace, e.g. "nvme0n1"
+	Name string `json:"name"`
+	// ID is the namespace identifier (NSID) reported by the controller
+	ID        int    `json:"id"`
+	SizeBytes uint64 `json:"size_bytes"`
+	// LogicalBlockSizeBytes and MetadataSizeBytes describe the LBA format
+	// the namespace is formatted with
+	LogicalBlockSizeBytes uint64 `json:"logical_block_size_bytes"`
+	MetadataSizeBytes     uint64 `json:"metadata_size_bytes"`
+	// The controllers the namespace is reachable through, sorted by name.
+	// More than one with NVMe multipath.
+	Controllers []*NVMeController `json:"-"`
+	// The disk exposing the namespace
+	Disk *Disk `json:"-"`
+}
+
+type nvmeNamespaceMarshallable struct {
+	Name                  string   `json:"name"`
+	ID                    int      `json:"id"`
+	SizeBytes             uint64   `json:"size_bytes"`
+	LogicalBlockSizeBytes uint64   `json:"logical_block_size_bytes"`
+	MetadataSizeBytes     uint64   `json:"metadata_size_bytes"`
+	Controllers           []string `json:"controllers"`
+}
+
+// MarshalJSON serializes the names of the controllers the namespace is
+// reachable through, instead of the controllers themselves
+func (ns *NVMeNamespace) MarshalJSON() ([]byte, error) {
+	nsm := nvmeNamespaceMarshallable{
+		Name:                  ns.Name,
+		ID:                    ns.ID,
+		SizeBytes:             ns.SizeBytes,
+		LogicalBlockSizeBytes: ns.LogicalBlockSizeBytes,
+		MetadataSizeBytes:     ns.MetadataSizeBytes,
+		Controllers:           make([]string, 0, len(ns.Controllers)),
+	}
+	for _, ctrl := range ns.Controllers {
+		nsm.Controllers = append(nsm.Controllers, ctrl.Name)
+	}
+	return json.Marshal(nsm)
+}
+
+func (ns *NVMeNamespace) String() string {
+	return fmt.Sprintf(
+		"%s nsid=%d lba=%d+%d (%d controllers)",
+		ns.Name,
+		ns.ID,
+		ns.LogicalBlockSizeBytes,
+		ns.MetadataSizeBytes,
+		len(ns.Controllers),
+	)
+}
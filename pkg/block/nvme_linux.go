@@ -0,0 +1,115 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package block
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jaypipes/ghw/pkg/linuxpath"
+	"github.com/jaypipes/ghw/pkg/util"
+)
+
+var (
+	// with NVMe multipath, each path to a namespace is a hidden block device
+	// named nvme<subsystem>c<controller>n<namespace>, and the namespace
+	// itself is the nvme<subsystem>n<namespace> block device
+	nvmePathRegexp      = regexp.MustCompile(`^nvme(\d+)c\d+n(\d+)$`)
+	nvmeNamespaceRegexp = regexp.MustCompile(`^nvme\d+n\d+$`)
+)
+
+// isNVMePath returns true if the given block device is a path to a NVMe
+// namespace, rather than the namespace itself
+func isNVMePath(dname string) bool {
+	return nvmePathRegexp.MatchString(dname)
+}
+
+// nvmeNamespaceName returns the name of the block device of the namespace
+// the given entry of a controller directory refers to, or an empty string if
+// the entry is not a namespace
+func nvmeNamespaceName(entry string) string {
+	if matches := nvmePathRegexp.FindStringSubmatch(entry); matches != nil {
+		return "nvme" + matches[1] + "n" + matches[2]
+	}
+	if nvmeNamespaceRegexp.MatchString(entry) {
+		return entry
+	}
+	return ""
+}
+
+// nvmeControllers returns the NVMe controllers found in /sys/class/nvme, and
+// links the given disks to the namespaces attached to the controllers
+func nvmeControllers(paths *linuxpath.Paths, disks []*Disk) []*NVMeController {
+	ctrls := make([]*NVMeController, 0)
+	entries, err := ioutil.ReadDir(paths.SysClassNVMe)
+	if err != nil {
+		// the nvme driver is not loaded
+		return ctrls
+	}
+	disksByName := make(map[string]*Disk, len(disks))
+	for _, disk := range disks {
+		disksByName[disk.Name] = disk
+	}
+	namespaces := make(map[string]*NVMeNamespace)
+	for _, entry := range entries {
+		ctrlPath := filepath.Join(paths.SysClassNVMe, entry.Name())
+		ctrl := &NVMeController{
+			Name:             entry.Name(),
+			Model:            nvmeControllerAttr(ctrlPath, "model"),
+			SerialNumber:     nvmeControllerAttr(ctrlPath, "serial"),
+			FirmwareRevision: nvmeControllerAttr(ctrlPath, "firmware_rev"),
+			Transport:        nvmeControllerAttr(ctrlPath, "transport"),
+			Address:          nvmeControllerAttr(ctrlPath, "address"),
+			State:            nvmeControllerAttr(ctrlPath, "state"),
+			Namespaces:       make([]*NVMeNamespace, 0),
+		}
+		nsEntries, err := ioutil.ReadDir(ctrlPath)
+		if err != nil {
+			continue
+		}
+		for _, nsEntry := range nsEntries {
+			nsName := nvmeNamespaceName(nsEntry.Name())
+			if nsName == "" {
+				continue
+			}
+			ns, ok := namespaces[nsName]
+			if !ok {
+				ns = nvmeNamespace(paths, nsName)
+				namespaces[nsName] = ns
+				if disk, ok := disksByName[nsName]; ok {
+					ns.Disk = disk
+					disk.NVMeNamespace = ns
+				}
+			}
+			ns.Controllers = append(ns.Controllers, ctrl)
+			ctrl.Namespaces = append(ctrl.Namespaces, ns)
+		}
+		ctrls = append(ctrls, ctrl)
+	}
+	return ctrls
+}
+
+func nvmeControllerAttr(ctrlPath, attr string) string {
+	contents, err := ioutil.ReadFile(filepath.Join(ctrlPath, attr))
+	if err != nil {
+		return util.UNKNOWN
+	}
+	return strings.TrimSpace(string(contents))
+}
+
+func nvmeNamespace(paths *linuxpath.Paths, name string) *NVMeNamespace {
+	nsPath := filepath.Join(paths.SysBlock, name)
+	return &NVMeNamespace{
+		Name:                  name,
+		ID:                    int(readUintFile(filepath.Join(nsPath, "nsid"))),
+		SizeBytes:             diskSizeBytes(paths, name),
+		LogicalBlockSizeBytes: readUintFile(filepath.Join(nsPath, "queue", "logical_block_size")),
+		MetadataSizeBytes:     readUintFile(filepath.Join(nsPath, "metadata_bytes")),
+		Controllers:           make([]*NVMeController, 0),
+	}
+}
@@ -0,0 +1,224 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package block
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"unicode/utf16"
+)
+
+const (
+	partitionTableTypeGPT = "gpt"
+	partitionTableTypeMBR = "dos"
+
+	mbrSize           = 512
+	mbrSignatureOff   = 510
+	mbrDiskIDOff      = 440
+	mbrEntriesOff     = 446
+	mbrEntrySize      = 16
+	mbrNumEntries     = 4
+	mbrTypeProtective = 0xee
+	mbrMaxEBRs        = 128
+
+	gptSignature    = "EFI PART"
+	gptMinEntrySize = 128
+	gptMaxEntrySize = 4096
+	gptMaxEntries   = 1024
+)
+
+// partitionTableEntry describes a partition recorded in a partition table,
+// with the same identifiers udev reports for the partition
+type partitionTableEntry struct {
+	// Number is the number of the partition, as in /dev/sda$NUMBER
+	Number int
+	// UUID is the GUID of the partition on GPT disks, and the disk signature
+	// followed by the partition number on MBR disks, e.g. "0a1b2c3d-01"
+	UUID string
+	// TypeID is the partition type GUID on GPT disks, and the hexadecimal
+	// partition type on MBR disks, e.g. "0x83"
+	TypeID string
+	// Label is the name of the partition. Always empty on MBR disks.
+	Label string
+}
+
+// partitionTable describes the GPT or MBR partition table of a disk
+type partitionTable struct {
+	// Type is either "gpt" or "dos", as reported by udev
+	Type    string
+	Entries map[int]*partitionTableEntry
+}
+
+// readPartitionTable reads the GPT or MBR partition table of the disk whose
+// content is available from the supplied reader. blockSize is the logical
+// block size of the disk, in which the partition tables express addresses.
+func readPartitionTable(r io.ReaderAt, blockSize int64) (*partitionTable, error) {
+	mbr := make([]byte, mbrSize)
+	if _, err := r.ReadAt(mbr, 0); err != nil {
+		return nil, fmt.Errorf("failed to read MBR: %s", err)
+	}
+	if mbr[mbrSignatureOff] != 0x55 || mbr[mbrSignatureOff+1] != 0xaa {
+		return nil, fmt.Errorf("no partition table found")
+	}
+	for i := 0; i < mbrNumEntries; i++ {
+		// GPT disks have a protective MBR with a single partition spanning
+		// the whole disk
+		if mbr[mbrEntriesOff+i*mbrEntrySize+4] == mbrTypeProtective {
+			return readGPT(r, blockSize)
+		}
+	}
+	return readMBR(r, mbr, blockSize)
+}
+
+func readGPT(r io.ReaderAt, blockSize int64) (*partitionTable, error) {
+	header := make([]byte, blockSize)
+	if _, err := r.ReadAt(header, blockSize); err != nil {
+		return nil, fmt.Errorf("failed to read GPT header: %s", err)
+	}
+	if string(header[0:8]) != gptSignature {
+		return nil, fmt.Errorf("protective MBR found but no GPT header")
+	}
+	headerSize := binary.LittleEndian.Uint32(header[12:16])
+	if headerSize < 92 || int64(headerSize) > blockSize {
+		return nil, fmt.Errorf("invalid GPT header size %d", headerSize)
+	}
+	headerCRC := binary.LittleEndian.Uint32(header[16:20])
+	// the CRC of the header is computed with the CRC field zeroed
+	checked := append([]byte{}, header[:headerSize]...)
+	copy(checked[16:20], []byte{0, 0, 0, 0})
+	if crc32.ChecksumIEEE(checked) != headerCRC {
+		return nil, fmt.Errorf("invalid GPT header checksum")
+	}
+
+	entriesLBA := binary.LittleEndian.Uint64(header[72:80])
+	numEntries := binary.LittleEndian.Uint32(header[80:84])
+	entrySize := binary.LittleEndian.Uint32(header[84:88])
+	if numEntries > gptMaxEntries || entrySize < gptMinEntrySize || entrySize > gptMaxEntrySize || entrySize%8 != 0 {
+		return nil, fmt.Errorf(
+			"invalid GPT partition entries (%d entries of %d bytes)",
+			numEntries, entrySize,
+		)
+	}
+	// bounded above to 4MiB
+	entries := make([]byte, int(numEntries)*int(entrySize))
+	if _, err := r.ReadAt(entries, int64(entriesLBA)*blockSize); err != nil {
+		return nil, fmt.Errorf("failed to read GPT partition entries: %s", err)
+	}
+	if crc32.ChecksumIEEE(entries) != binary.LittleEndian.Uint32(header[88:92]) {
+		return nil, fmt.Errorf("invalid GPT partition entries checksum")
+	}
+
+	pt := &partitionTable{
+		Type:    partitionTableTypeGPT,
+		Entries: make(map[int]*partitionTableEntry),
+	}
+	size := int(entrySize)
+	for i := 0; i < int(numEntries); i++ {
+		entry := entries[i*size : (i+1)*size]
+		// unused entries have a zeroed partition type GUID
+		if bytes.Equal(entry[0:16], make([]byte, 16)) {
+			continue
+		}
+		number := i + 1
+		pt.Entries[number] = &partitionTableEntry{
+			Number: number,
+			TypeID: guidString(entry[0:16]),
+			UUID:   guidString(entry[16:32]),
+			Label:  utf16String(entry[56:128]),
+		}
+	}
+	return pt, nil
+}
+
+func readMBR(r io.ReaderAt, mbr []byte, blockSize int64) (*partitionTable, error) {
+	diskID := binary.LittleEndian.Uint32(mbr[mbrDiskIDOff : mbrDiskIDOff+4])
+	pt := &partitionTable{
+		Type:    partitionTableTypeMBR,
+		Entries: make(map[int]*partitionTableEntry),
+	}
+	addEntry := func(number int, partType byte) {
+		pt.Entries[number] = &partitionTableEntry{
+			Number: number,
+			UUID:   fmt.Sprintf("%08x-%02x", diskID, number),
+			TypeID: fmt.Sprintf("0x%x", partType),
+		}
+	}
+	var extStart uint64
+	for i := 0; i < mbrNumEntries; i++ {
+		entry := mbr[mbrEntriesOff+i*mbrEntrySize : mbrEntriesOff+(i+1)*mbrEntrySize]
+		partType := entry[4]
+		if partType == 0 {
+			continue
+		}
+		addEntry(i+1, partType)
+		if isExtendedPartitionType(partType) && extStart == 0 {
+			extStart = uint64(binary.LittleEndian.Uint32(entry[8:12]))
+		}
+	}
+	if extStart == 0 {
+		return pt, nil
+	}
+
+	// logical partitions are numbered from 5 and described by a chain of
+	// extended boot records, whose first entry is the logical partition
+	// (relative to the EBR) and second entry the next EBR (relative to the
+	// extended partition)
+	ebr := make([]byte, mbrSize)
+	number := 5
+	for next, i := uint64(0), 0; i < mbrMaxEBRs; i++ {
+		if _, err := r.ReadAt(ebr, int64(extStart+next)*blockSize); err != nil {
+			return nil, fmt.Errorf("failed to read EBR: %s", err)
+		}
+		if ebr[mbrSignatureOff] != 0x55 || ebr[mbrSignatureOff+1] != 0xaa {
+			break
+		}
+		if partType := ebr[mbrEntriesOff+4]; partType != 0 {
+			addEntry(number, partType)
+			number++
+		}
+		link := ebr[mbrEntriesOff+mbrEntrySize : mbrEntriesOff+2*mbrEntrySize]
+		next = uint64(binary.LittleEndian.Uint32(link[8:12]))
+		if link[4] == 0 || next == 0 {
+			break
+		}
+	}
+	return pt, nil
+}
+
+func isExtendedPartitionType(partType byte) bool {
+	return partType == 0x05 || partType == 0x0f || partType == 0x85
+}
+
+// guidString returns the string representation of a GUID as stored on disk,
+// with its first three fields in little-endian order
+func guidString(b []byte) string {
+	return fmt.Sprintf(
+		"%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(b[0:4]),
+		binary.LittleEndian.Uint16(b[4:6]),
+		binary.LittleEndian.Uint16(b[6:8]),
+		b[8:10],
+		b[10:16],
+	)
+}
+
+// utf16String returns the string encoded in the supplied NUL-padded UTF-16LE
+// bytes
+func utf16String(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		c := binary.LittleEndian.Uint16(b[i : i+2])
+		if c == 0 {
+			break
+		}
+		u = append(u, c)
+	}
+	return string(utf16.Decode(u))
+}
@@ -0,0 +1,37 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package block
+
+// LoopDevice describes the file backing a loop device
+type LoopDevice struct {
+	// BackingFile is the path of the file the loop device is attached to
+	BackingFile string `json:"backing_file"`
+	// OffsetBytes is the offset in the backing file where the loop device
+	// starts
+	OffsetBytes uint64 `json:"offset_bytes"`
+	// IsAutoclear is true if the loop device is detached from its backing
+	// file when it is last closed
+	IsAutoclear bool `json:"autoclear"`
+}
+
+// ZRAMDevice describes a compressed RAM disk
+type ZRAMDevice struct {
+	// CompressionAlgorithm is the algorithm used to compress the pages
+	// stored in the device, e.g. "lzo-rle" or "zstd"
+	CompressionAlgorithm string `json:"compression_algorithm"`
+	// DiskSizeBytes is the uncompressed capacity of the device
+	DiskSizeBytes uint64 `json:"disk_size_bytes"`
+	// MemoryUsedBytes is the amount of memory allocated to store the
+	// compressed pages
+	MemoryUsedBytes uint64 `json:"memory_used_bytes"`
+}
+
+// isPseudoDriveType returns true for the drive types of the block devices
+// not backed by storage hardware
+func isPseudoDriveType(dt DriveType) bool {
+	return dt == DRIVE_TYPE_LOOP || dt == DRIVE_TYPE_RAM || dt == DRIVE_TYPE_ZRAM
+}
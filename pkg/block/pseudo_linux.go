@@ -0,0 +1,73 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package block
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jaypipes/ghw/pkg/linuxpath"
+)
+
+// pseudoDriveType returns the drive type of the pseudo block device with the
+// given name, and false if the device is backed by storage hardware
+func pseudoDriveType(dname string) (DriveType, bool) {
+	switch {
+	case strings.HasPrefix(dname, "loop"):
+		return DRIVE_TYPE_LOOP, true
+	case strings.HasPrefix(dname, "zram"):
+		return DRIVE_TYPE_ZRAM, true
+	case strings.HasPrefix(dname, "ram"):
+		return DRIVE_TYPE_RAM, true
+	}
+	return DRIVE_TYPE_UNKNOWN, false
+}
+
+// loopDevice returns the backing file of the loop device with the given
+// name, or nil if the loop device is not attached to a file
+func loopDevice(paths *linuxpath.Paths, dname string) *LoopDevice {
+	// the loop/ directory only exists while the device is attached
+	loopPath := filepath.Join(paths.SysBlock, dname, "loop")
+	if !isDir(loopPath) {
+		return nil
+	}
+	return &LoopDevice{
+		BackingFile: readStringFile(filepath.Join(loopPath, "backing_file")),
+		OffsetBytes: readUintFile(filepath.Join(loopPath, "offset")),
+		IsAutoclear: readStringFile(filepath.Join(loopPath, "autoclear")) == "1",
+	}
+}
+
+// zramDevice returns the compressed RAM disk with the given name, or nil if
+// the device is not a zram device
+func zramDevice(paths *linuxpath.Paths, dname string) *ZRAMDevice {
+	devPath := filepath.Join(paths.SysBlock, dname)
+	// the comp_algorithm file has the same format as the queue/scheduler
+	// file, e.g. "lzo lzo-rle lz4 [zstd]"
+	algorithm, _ := parseScheduler(readStringFile(filepath.Join(devPath, "comp_algorithm")))
+	if algorithm == "" {
+		return nil
+	}
+	return &ZRAMDevice{
+		CompressionAlgorithm: algorithm,
+		DiskSizeBytes:        readUintFile(filepath.Join(devPath, "disksize")),
+		MemoryUsedBytes:      zramMemoryUsedBytes(devPath),
+	}
+}
+
+// zramMemoryUsedBytes returns the memory used by the zram device, which is
+// the third field of the mm_stat file, or the content of the mem_used_total
+// file on older kernels
+func zramMemoryUsedBytes(devPath string) uint64 {
+	fields := strings.Fields(readStringFile(filepath.Join(devPath, "mm_stat")))
+	if len(fields) >= 3 {
+		if used, err := strconv.ParseUint(fields[2], 10, 64); err == nil {
+			return used
+		}
+	}
+	return readUintFile(filepath.Join(devPath, "mem_used_total"))
+}
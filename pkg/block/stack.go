@@ -0,0 +1,35 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package block
+
+import (
+	"encoding/json"
+)
+
+// BlockDevice refers to a block device which is part of a storage stack, as
+// a holder or a slave of a disk or a partition. The device is resolved to
+// the Disk or the Partition with the same name when there is one, otherwise
+// (e.g. for loop devices) only its name is known.
+type BlockDevice struct {
+	// Name is the name of the block device, e.g. "sda1" or "dm-0"
+	Name string
+	// Disk is the disk with this name, or nil if the device is not a disk
+	Disk *Disk
+	// Partition is the partition with this name, or nil if the device is
+	// not a partition
+	Partition *Partition
+}
+
+// MarshalJSON serializes the name of the block device, instead of the disk
+// or partition it refers to
+func (bd *BlockDevice) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bd.Name)
+}
+
+func (bd *BlockDevice) String() string {
+	return bd.Name
+}
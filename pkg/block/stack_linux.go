@@ -0,0 +1,47 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package block
+
+import (
+	"path/filepath"
+
+	"github.com/jaypipes/ghw/pkg/linuxpath"
+)
+
+// linkStackedDevices sets the holders and the slaves of the disks and their
+// partitions, resolved to the disks and partitions they refer to, so that
+// storage stacks (e.g. LVM on LUKS on a partition) can be walked both ways
+func linkStackedDevices(paths *linuxpath.Paths, disks []*Disk) {
+	devices := make(map[string]*BlockDevice)
+	for _, disk := range disks {
+		devices[disk.Name] = &BlockDevice{Name: disk.Name, Disk: disk}
+		for _, part := range disk.Partitions {
+			devices[part.Name] = &BlockDevice{Name: part.Name, Partition: part}
+		}
+	}
+	resolve := func(names []string) []*BlockDevice {
+		refs := make([]*BlockDevice, 0, len(names))
+		for _, name := range names {
+			ref, ok := devices[name]
+			if !ok {
+				// devices which are not reported as disks, e.g. loop devices
+				ref = &BlockDevice{Name: name}
+			}
+			refs = append(refs, ref)
+		}
+		return refs
+	}
+	for _, disk := range disks {
+		devPath := filepath.Join(paths.SysBlock, disk.Name)
+		disk.Holders = resolve(blockHolders(devPath))
+		disk.Slaves = resolve(blockSlaves(devPath))
+		for _, part := range disk.Partitions {
+			partPath := filepath.Join(devPath, part.Name)
+			part.Holders = resolve(blockHolders(partPath))
+			part.Slaves = resolve(blockSlaves(partPath))
+		}
+	}
+}
@@ -0,0 +1,162 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package block
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jaypipes/ghw/pkg/unitutil"
+	"github.com/jaypipes/ghw/pkg/util"
+)
+
+// VirtualDeviceType describes the kernel subsystem providing a virtual block
+// device
+type VirtualDeviceType int
+
+const (
+	VIRTUAL_DEVICE_TYPE_UNKNOWN       VirtualDeviceType = iota
+	VIRTUAL_DEVICE_TYPE_DEVICE_MAPPER                   // Device-mapper target (LVM, dm-crypt, multipath...)
+	VIRTUAL_DEVICE_TYPE_MD                              // MD (software RAID) array
+)
+
+var (
+	virtualDeviceTypeString = map[VirtualDeviceType]string{
+		VIRTUAL_DEVICE_TYPE_UNKNOWN:       "Unknown",
+		VIRTUAL_DEVICE_TYPE_DEVICE_MAPPER: "DM",
+		VIRTUAL_DEVICE_TYPE_MD:            "MD",
+	}
+)
+
+func (vdt VirtualDeviceType) String() string {
+	return virtualDeviceTypeString[vdt]
+}
+
+// NOTE(jaypipes): since serialized output is as "official" as we're going to
+// get, let's lowercase the string output when serializing, in order to
+// "normalize" the expected serialized output
+func (vdt VirtualDeviceType) MarshalJSON() ([]byte, error) {
+	return []byte("\"" + strings.ToLower(vdt.String()) + "\""), nil
+}
+
+// DeviceMapper describes a device-mapper target
+type DeviceMapper struct {
+	// Name is the name of the target, e.g. "vg0-root" for a LVM logical
+	// volume. The target is available as /dev/mapper/$NAME.
+	Name string `json:"name"`
+	// UUID is the UUID of the target, prefixed by the subsystem which
+	// created it, e.g. "LVM-..." or "CRYPT-LUKS2-..."
+	UUID string `json:"uuid"`
+	// Subsystem is the prefix of the UUID, e.g. "LVM", "CRYPT" or "mpath".
+	// Empty if the target has no UUID.
+	Subsystem   string `json:"subsystem"`
+	IsSuspended bool   `json:"suspended"`
+}
+
+// RAIDMember describes a block device which is part of a MD array
+type RAIDMember struct {
+	// Name is the name of the block device, e.g. "sda1"
+	Name string `json:"name"`
+	// Slot is the position of the device in the array, or -1 if the
+	// device is a spare or is faulty
+	Slot int `json:"slot"`
+	// State is the state of the device, e.g. "in_sync", "spare" or
+	// "faulty"
+	State string `json:"state"`
+}
+
+// RAID describes a MD (software RAID) array
+type RAID struct {
+	// Level is the RAID level, e.g. "raid1"
+	Level string `json:"level"`
+	// State is the state of the array, e.g. "clean" or "active"
+	State string `json:"state"`
+	// NumDevices is the number of devices the array is made of when fully
+	// functional
+	NumDevices int `json:"num_devices"`
+	// NumDegraded is the number of devices missing from the array
+	NumDegraded int           `json:"num_degraded"`
+	IsDegraded  bool          `json:"degraded"`
+	Members     []*RAIDMember `json:"members"`
+}
+
+// VirtualDevice describes a block device provided by the kernel on top of
+// other block devices, like a device-mapper target or a MD array
+type VirtualDevice struct {
+	// Name is the kernel name of the device, e.g. "dm-0" or "md127"
+	Name      string            `json:"name"`
+	Type      VirtualDeviceType `json:"type"`
+	SizeBytes uint64            `json:"size_bytes"`
+	// Slaves are the names of the block devices (disks, partitions or
+	// other virtual devices) the device is built on
+	Slaves []string `json:"slaves"`
+	// Will be nil if the device is not a device-mapper target
+	DeviceMapper *DeviceMapper `json:"device_mapper,omitempty"`
+	// Will be nil if the device is not a MD array
+	RAID *RAID `json:"raid,omitempty"`
+	// Disks are the physical disks the device is ultimately built on,
+	// following the slaves through partitions and stacked virtual devices
+	Disks []*Disk `json:"-"`
+}
+
+type virtualDeviceMarshallable struct {
+	Name         string            `json:"name"`
+	Type         VirtualDeviceType `json:"type"`
+	SizeBytes    uint64            `json:"size_bytes"`
+	Slaves       []string          `json:"slaves"`
+	DeviceMapper *DeviceMapper     `json:"device_mapper,omitempty"`
+	RAID         *RAID             `json:"raid,omitempty"`
+	Disks        []string          `json:"disks"`
+}
+
+// MarshalJSON serializes the names of the disks the device is built on,
+// instead of the disks themselves
+func (vd *VirtualDevice) MarshalJSON() ([]byte, error) {
+	vdm := virtualDeviceMarshallable{
+		Name:         vd.Name,
+		Type:         vd.Type,
+		SizeBytes:    vd.SizeBytes,
+		Slaves:       vd.Slaves,
+		DeviceMapper: vd.DeviceMapper,
+		RAID:         vd.RAID,
+		Disks:        make([]string, 0, len(vd.Disks)),
+	}
+	for _, disk := range vd.Disks {
+		vdm.Disks = append(vdm.Disks, disk.Name)
+	}
+	return json.Marshal(vdm)
+}
+
+func (vd *VirtualDevice) String() string {
+	sizeStr := util.UNKNOWN
+	if vd.SizeBytes > 0 {
+		size := vd.SizeBytes
+		unit, unitStr := unitutil.AmountString(int64(size))
+		size = uint64(math.Ceil(float64(size) / float64(unit)))
+		sizeStr = fmt.Sprintf("%d%s", size, unitStr)
+	}
+	detail := ""
+	if vd.DeviceMapper != nil {
+		detail = fmt.Sprintf(" name=%s", vd.DeviceMapper.Name)
+	}
+	if vd.RAID != nil {
+		detail = fmt.Sprintf(" level=%s state=%s", vd.RAID.Level, vd.RAID.State)
+		if vd.RAID.IsDegraded {
+			detail += " degraded=true"
+		}
+	}
+	return fmt.Sprintf(
+		"%s %s (%s)%s slaves=%s",
+		vd.Name,
+		vd.Type.String(),
+		sizeStr,
+		detail,
+		strings.Join(vd.Slaves, ","),
+	)
+}
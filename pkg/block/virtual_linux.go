@@ -0,0 +1,167 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package block
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jaypipes/ghw/pkg/linuxpath"
+)
+
+// virtualDevices returns the device-mapper targets and the MD arrays found in
+// /sys/block, linked to the given disks they are built on
+func virtualDevices(paths *linuxpath.Paths, disks []*Disk) []*VirtualDevice {
+	vdevs := make([]*VirtualDevice, 0)
+	files, err := ioutil.ReadDir(paths.SysBlock)
+	if err != nil {
+		return vdevs
+	}
+	for _, file := range files {
+		dname := file.Name()
+		devPath := filepath.Join(paths.SysBlock, dname)
+		vd := &VirtualDevice{
+			Name:      dname,
+			SizeBytes: diskSizeBytes(paths, dname),
+			Slaves:    blockSlaves(devPath),
+		}
+		if isDir(filepath.Join(devPath, "dm")) {
+			vd.Type = VIRTUAL_DEVICE_TYPE_DEVICE_MAPPER
+			vd.DeviceMapper = deviceMapper(filepath.Join(devPath, "dm"))
+		} else if isDir(filepath.Join(devPath, "md")) {
+			vd.Type = VIRTUAL_DEVICE_TYPE_MD
+			vd.RAID = raid(filepath.Join(devPath, "md"))
+		} else {
+			continue
+		}
+		vdevs = append(vdevs, vd)
+	}
+	linkVirtualDevices(vdevs, disks)
+	return vdevs
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+// blockSlaves returns the names of the block devices the given block device
+// is built on, listed as symlinks in its slaves/ directory
+func blockSlaves(devPath string) []string {
+	return blockDeviceLinks(filepath.Join(devPath, "slaves"))
+}
+
+// blockHolders returns the names of the block devices built on top of the
+// given block device, listed as symlinks in its holders/ directory
+func blockHolders(devPath string) []string {
+	return blockDeviceLinks(filepath.Join(devPath, "holders"))
+}
+
+func blockDeviceLinks(dir string) []string {
+	names := make([]string, 0)
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return names
+	}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func deviceMapper(dmPath string) *DeviceMapper {
+	dm := &DeviceMapper{
+		Name:        readStringFile(filepath.Join(dmPath, "name")),
+		UUID:        readStringFile(filepath.Join(dmPath, "uuid")),
+		IsSuspended: readStringFile(filepath.Join(dmPath, "suspended")) == "1",
+	}
+	// the subsystems which create targets prefix their UUIDs with their
+	// name, e.g. "LVM-", "CRYPT-LUKS2-" or "mpath-"
+	if idx := strings.Index(dm.UUID, "-"); idx > 0 {
+		dm.Subsystem = dm.UUID[:idx]
+	}
+	return dm
+}
+
+func raid(mdPath string) *RAID {
+	numDevices, _ := strconv.Atoi(readStringFile(filepath.Join(mdPath, "raid_disks")))
+	// RAID levels without redundancy have no degraded file
+	numDegraded, _ := strconv.Atoi(readStringFile(filepath.Join(mdPath, "degraded")))
+	r := &RAID{
+		Level:       readStringFile(filepath.Join(mdPath, "level")),
+		State:       readStringFile(filepath.Join(mdPath, "array_state")),
+		NumDevices:  numDevices,
+		NumDegraded: numDegraded,
+		IsDegraded:  numDegraded > 0,
+		Members:     make([]*RAIDMember, 0),
+	}
+	entries, err := ioutil.ReadDir(mdPath)
+	if err != nil {
+		return r
+	}
+	// each member device has a dev-$NAME directory
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), "dev-") {
+			continue
+		}
+		memberPath := filepath.Join(mdPath, entry.Name())
+		slot, err := strconv.Atoi(readStringFile(filepath.Join(memberPath, "slot")))
+		if err != nil {
+			// "none" for spare and faulty devices
+			slot = -1
+		}
+		r.Members = append(r.Members, &RAIDMember{
+			Name:  strings.TrimPrefix(entry.Name(), "dev-"),
+			Slot:  slot,
+			State: readStringFile(filepath.Join(memberPath, "state")),
+		})
+	}
+	return r
+}
+
+// linkVirtualDevices sets the physical disks each virtual device is built
+// on, following the slaves through partitions and stacked virtual devices
+// (e.g. a LVM logical volume on top of a MD array)
+func linkVirtualDevices(vdevs []*VirtualDevice, disks []*Disk) {
+	disksByName := make(map[string]*Disk)
+	for _, disk := range disks {
+		disksByName[disk.Name] = disk
+		for _, part := range disk.Partitions {
+			disksByName[part.Name] = disk
+		}
+	}
+	vdevsByName := make(map[string]*VirtualDevice, len(vdevs))
+	for _, vd := range vdevs {
+		vdevsByName[vd.Name] = vd
+	}
+	for _, vd := range vdevs {
+		vd.Disks = make([]*Disk, 0)
+		seen := make(map[string]bool)
+		seenDisks := make(map[*Disk]bool)
+		pending := append([]string{}, vd.Slaves...)
+		for len(pending) > 0 {
+			name := pending[0]
+			pending = pending[1:]
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if stacked, ok := vdevsByName[name]; ok {
+				pending = append(pending, stacked.Slaves...)
+				continue
+			}
+			disk, ok := disksByName[name]
+			if !ok || seenDisks[disk] {
+				continue
+			}
+			seenDisks[disk] = true
+			vd.Disks = append(vd.Disks, disk)
+		}
+	}
+}
@@ -11,16 +11,17 @@ import (
 )
 
 func (i *Info) load() error {
-	i.AssetTag = linuxdmi.Item(i.ctx, "chassis_asset_tag")
-	i.SerialNumber = linuxdmi.Item(i.ctx, "chassis_serial")
-	i.Type = linuxdmi.Item(i.ctx, "chassis_type")
+	dmi := linuxdmi.NewItems(i.ctx)
+	i.AssetTag = dmi.Item("chassis_asset_tag")
+	i.SerialNumber = dmi.Item("chassis_serial")
+	i.Type = dmi.Item("chassis_type")
 	typeDesc, found := chassisTypeDescriptions[i.Type]
 	if !found {
 		typeDesc = util.UNKNOWN
 	}
 	i.TypeDescription = typeDesc
-	i.Vendor = linuxdmi.Item(i.ctx, "chassis_vendor")
-	i.Version = linuxdmi.Item(i.ctx, "chassis_version")
+	i.Vendor = dmi.Item("chassis_vendor")
+	i.Version = dmi.Item("chassis_version")
 
 	return nil
 }
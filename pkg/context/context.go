@@ -20,8 +20,18 @@ type Context struct {
 	SnapshotRoot         string
 	SnapshotExclusive    bool
 	PathOverrides        option.PathOverrides
+	BlockPseudoDevices   bool
+	BlockPartitionTables bool
+	BlockSMARTData       bool
+	BlockFilesystemUsage bool
+	NetAddresses         bool
+	PCICandidateDrivers  bool
+	PCICapabilities      bool
 	snapshotUnpackedPath string
 	alert                option.Alerter
+	// doDepth tracks the nesting of Do calls, which happen when a package
+	// consumes another one through its NewWithContext function
+	doDepth int
 }
 
 // New returns a Context struct pointer that has had various options set on it
@@ -53,6 +63,20 @@ func New(opts ...*option.Option) *Context {
 		ctx.PathOverrides = merged.PathOverrides
 	}
 
+	if merged.Block != nil {
+		ctx.BlockPseudoDevices = merged.Block.IncludePseudoDevices
+		ctx.BlockPartitionTables = merged.Block.ReadPartitionTables
+		ctx.BlockSMARTData = merged.Block.ReadSMARTData
+		ctx.BlockFilesystemUsage = merged.Block.IncludeFilesystemUsage
+	}
+	if merged.Net != nil {
+		ctx.NetAddresses = merged.Net.IncludeAddresses
+	}
+	if merged.PCI != nil {
+		ctx.PCICandidateDrivers = merged.PCI.IncludeCandidateDrivers
+		ctx.PCICapabilities = merged.PCI.IncludeCapabilities
+	}
+
 	return ctx
 }
 
@@ -73,13 +97,24 @@ func FromEnv() *Context {
 	}
 }
 
-// Do wraps a Setup/Teardown pair around the given function
+// Do wraps a Setup/Teardown pair around the given function.
+// Nested calls reuse the setup done by the outermost call, so the inner
+// calls don't tear down resources the outer call is still using.
 func (ctx *Context) Do(fn func() error) error {
+	if ctx.doDepth > 0 {
+		ctx.doDepth++
+		defer func() { ctx.doDepth-- }()
+		return fn()
+	}
 	err := ctx.Setup()
 	if err != nil {
 		return err
 	}
-	defer ctx.Teardown()
+	ctx.doDepth++
+	defer func() {
+		ctx.doDepth--
+		ctx.Teardown()
+	}()
 	return fn()
 }
 
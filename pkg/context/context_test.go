@@ -40,3 +40,36 @@ func TestSnapshotContext(t *testing.T) {
 		t.Fatalf("Expected the uncompressed dir to be deleted: %s", uncompressedDir)
 	}
 }
+
+func TestSnapshotContextNested(t *testing.T) {
+	ctx := context.New(option.WithSnapshot(option.SnapshotOptions{
+		Path: testDataSnapshot,
+	}))
+
+	var outerDir, innerDir string
+	var outerSurvived bool
+	err := ctx.Do(func() error {
+		outerDir = ctx.Chroot
+		err := ctx.Do(func() error {
+			innerDir = ctx.Chroot
+			return nil
+		})
+		if _, statErr := os.Stat(outerDir); statErr == nil {
+			outerSurvived = true
+		}
+		return err
+	})
+
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if innerDir != outerDir {
+		t.Fatalf("Expected nested Do to reuse %q, but got %q", outerDir, innerDir)
+	}
+	if !outerSurvived {
+		t.Fatalf("Expected the uncompressed dir to survive the nested Do: %s", outerDir)
+	}
+	if _, err = os.Stat(outerDir); !os.IsNotExist(err) {
+		t.Fatalf("Expected the uncompressed dir to be deleted: %s", outerDir)
+	}
+}
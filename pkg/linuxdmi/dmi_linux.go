@@ -6,24 +6,135 @@
 package linuxdmi
 
 import (
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"strings"
 
 	"github.com/jaypipes/ghw/pkg/context"
 	"github.com/jaypipes/ghw/pkg/linuxpath"
+	"github.com/jaypipes/ghw/pkg/smbios"
 	"github.com/jaypipes/ghw/pkg/util"
 )
 
-func Item(ctx *context.Context, value string) string {
-	paths := linuxpath.New(ctx)
+// smbiosItems maps the names of the files in /sys/class/dmi/id to functions
+// extracting the same value from the decoded SMBIOS structure table. Each
+// function returns false if the value is not available.
+var smbiosItems = map[string]func(*smbios.Info) (string, bool){
+	"bios_vendor":       biosItem(func(b *smbios.BIOS) string { return b.Vendor }),
+	"bios_version":      biosItem(func(b *smbios.BIOS) string { return b.Version }),
+	"bios_date":         biosItem(func(b *smbios.BIOS) string { return b.ReleaseDate }),
+	"sys_vendor":        systemItem(func(s *smbios.System) string { return s.Manufacturer }),
+	"product_name":      systemItem(func(s *smbios.System) string { return s.ProductName }),
+	"product_version":   systemItem(func(s *smbios.System) string { return s.Version }),
+	"product_serial":    systemItem(func(s *smbios.System) string { return s.SerialNumber }),
+	"product_uuid":      systemItem(func(s *smbios.System) string { return s.UUID }),
+	"product_sku":       systemItem(func(s *smbios.System) string { return s.SKUNumber }),
+	"product_family":    systemItem(func(s *smbios.System) string { return s.Family }),
+	"board_vendor":      baseboardItem(func(b *smbios.Baseboard) string { return b.Manufacturer }),
+	"board_name":        baseboardItem(func(b *smbios.Baseboard) string { return b.Product }),
+	"board_version":     baseboardItem(func(b *smbios.Baseboard) string { return b.Version }),
+	"board_serial":      baseboardItem(func(b *smbios.Baseboard) string { return b.SerialNumber }),
+	"board_asset_tag":   baseboardItem(func(b *smbios.Baseboard) string { return b.AssetTag }),
+	"chassis_vendor":    chassisItem(func(c *smbios.Chassis) string { return c.Manufacturer }),
+	"chassis_type":      chassisItem(func(c *smbios.Chassis) string { return fmt.Sprintf("%d", c.Type) }),
+	"chassis_version":   chassisItem(func(c *smbios.Chassis) string { return c.Version }),
+	"chassis_serial":    chassisItem(func(c *smbios.Chassis) string { return c.SerialNumber }),
+	"chassis_asset_tag": chassisItem(func(c *smbios.Chassis) string { return c.AssetTag }),
+}
+
+func biosItem(get func(*smbios.BIOS) string) func(*smbios.Info) (string, bool) {
+	return func(info *smbios.Info) (string, bool) {
+		if info.BIOS == nil {
+			return "", false
+		}
+		return get(info.BIOS), true
+	}
+}
+
+func systemItem(get func(*smbios.System) string) func(*smbios.Info) (string, bool) {
+	return func(info *smbios.Info) (string, bool) {
+		if info.System == nil {
+			return "", false
+		}
+		return get(info.System), true
+	}
+}
+
+// like the linux kernel, consider only the first baseboard
+func baseboardItem(get func(*smbios.Baseboard) string) func(*smbios.Info) (string, bool) {
+	return func(info *smbios.Info) (string, bool) {
+		if len(info.Baseboards) == 0 {
+			return "", false
+		}
+		return get(info.Baseboards[0]), true
+	}
+}
+
+// like the linux kernel, consider only the first chassis
+func chassisItem(get func(*smbios.Chassis) string) func(*smbios.Info) (string, bool) {
+	return func(info *smbios.Info) (string, bool) {
+		if len(info.Chassis) == 0 {
+			return "", false
+		}
+		return get(info.Chassis[0]), true
+	}
+}
+
+// Items reads the DMI items of the host system. The SMBIOS structure table
+// is decoded at most once, the first time an item can't be read from sysfs,
+// so use a single Items to read several values.
+type Items struct {
+	ctx     *context.Context
+	smbios  *smbios.Info
+	decoded bool
+}
+
+// NewItems returns a pointer to an Items struct reading the DMI items with
+// the given context
+func NewItems(ctx *context.Context) *Items {
+	return &Items{ctx: ctx}
+}
+
+// Item returns the value of the given DMI item (e.g. "product_serial"). The
+// value is read from /sys/class/dmi/id, and, if that fails (e.g. because the
+// file is readable by root only), from the SMBIOS structure table.
+func (items *Items) Item(value string) string {
+	paths := linuxpath.New(items.ctx)
 	path := filepath.Join(paths.SysClassDMI, "id", value)
 
 	b, err := ioutil.ReadFile(path)
-	if err != nil {
-		ctx.Warn("Unable to read %s: %s\n", value, err)
-		return util.UNKNOWN
+	if err == nil {
+		return strings.TrimSpace(string(b))
+	}
+
+	// the table holds no string for the item, which is as unknown as the
+	// unreadable sysfs file
+	if item, ok := items.smbiosItem(value); ok && item != "" {
+		return item
 	}
+	items.ctx.Warn("Unable to read %s: %s\n", value, err)
+	return util.UNKNOWN
+}
 
-	return strings.TrimSpace(string(b))
+func (items *Items) smbiosItem(value string) (string, bool) {
+	get, ok := smbiosItems[value]
+	if !ok {
+		return "", false
+	}
+	if !items.decoded {
+		// if decoding fails, the caller warns about the original sysfs error
+		items.smbios, _ = smbios.NewWithContext(items.ctx)
+		items.decoded = true
+	}
+	if items.smbios == nil {
+		return "", false
+	}
+	return get(items.smbios)
+}
+
+// Item returns the value of the given DMI item (e.g. "product_serial"), like
+// Items.Item does
+func Item(ctx *context.Context, value string) string {
+	return NewItems(ctx).Item(value)
 }
@@ -60,15 +60,27 @@ type Paths struct {
 	ProcMeminfo            string
 	ProcCpuinfo            string
 	ProcMounts             string
+	ProcMountInfo          string
+	ProcNetVLANConfig      string
+	ProcNetIfInet6         string
+	ProcNetFibTrie         string
+	ProcNetRoute           string
+	ProcNetIPv6Route       string
+	ProcSysKernelOSRelease string
+	LibModules             string
 	SysKernelMMHugepages   string
 	SysBlock               string
 	SysDevicesSystemNode   string
 	SysDevicesSystemMemory string
 	SysBusPciDevices       string
+	SysBusPciDrivers       string
 	SysClassDRM            string
 	SysClassDMI            string
 	SysClassNet            string
+	SysClassNVMe           string
+	SysFirmwareDMITables   string
 	RunUdevData            string
+	Dev                    string
 }
 
 // New returns a new Paths struct containing filepath fields relative to the
@@ -80,15 +92,27 @@ func New(ctx *context.Context) *Paths {
 		ProcMeminfo:            filepath.Join(ctx.Chroot, roots.Proc, "meminfo"),
 		ProcCpuinfo:            filepath.Join(ctx.Chroot, roots.Proc, "cpuinfo"),
 		ProcMounts:             filepath.Join(ctx.Chroot, roots.Proc, "self", "mounts"),
+		ProcMountInfo:          filepath.Join(ctx.Chroot, roots.Proc, "self", "mountinfo"),
+		ProcNetVLANConfig:      filepath.Join(ctx.Chroot, roots.Proc, "net", "vlan", "config"),
+		ProcNetIfInet6:         filepath.Join(ctx.Chroot, roots.Proc, "net", "if_inet6"),
+		ProcNetFibTrie:         filepath.Join(ctx.Chroot, roots.Proc, "net", "fib_trie"),
+		ProcNetRoute:           filepath.Join(ctx.Chroot, roots.Proc, "net", "route"),
+		ProcNetIPv6Route:       filepath.Join(ctx.Chroot, roots.Proc, "net", "ipv6_route"),
+		ProcSysKernelOSRelease: filepath.Join(ctx.Chroot, roots.Proc, "sys", "kernel", "osrelease"),
+		LibModules:             filepath.Join(ctx.Chroot, "lib", "modules"),
 		SysKernelMMHugepages:   filepath.Join(ctx.Chroot, roots.Sys, "kernel", "mm", "hugepages"),
 		SysBlock:               filepath.Join(ctx.Chroot, roots.Sys, "block"),
 		SysDevicesSystemNode:   filepath.Join(ctx.Chroot, roots.Sys, "devices", "system", "node"),
 		SysDevicesSystemMemory: filepath.Join(ctx.Chroot, roots.Sys, "devices", "system", "memory"),
 		SysBusPciDevices:       filepath.Join(ctx.Chroot, roots.Sys, "bus", "pci", "devices"),
+		SysBusPciDrivers:       filepath.Join(ctx.Chroot, roots.Sys, "bus", "pci", "drivers"),
 		SysClassDRM:            filepath.Join(ctx.Chroot, roots.Sys, "class", "drm"),
 		SysClassDMI:            filepath.Join(ctx.Chroot, roots.Sys, "class", "dmi"),
 		SysClassNet:            filepath.Join(ctx.Chroot, roots.Sys, "class", "net"),
+		SysClassNVMe:           filepath.Join(ctx.Chroot, roots.Sys, "class", "nvme"),
+		SysFirmwareDMITables:   filepath.Join(ctx.Chroot, roots.Sys, "firmware", "dmi", "tables"),
 		RunUdevData:            filepath.Join(ctx.Chroot, roots.Run, "udev", "data"),
+		Dev:                    filepath.Join(ctx.Chroot, "dev"),
 	}
 }
 
@@ -23,6 +23,50 @@ type Module struct {
 	SerialNumber string `json:"serial_number"`
 	SizeBytes    int64  `json:"size_bytes"`
 	Vendor       string `json:"vendor"`
+	PartNumber   string `json:"part_number"`
+	// Type of memory technology (e.g. "DDR4")
+	Type string `json:"type"`
+	// Form factor of the module (e.g. "DIMM", "SODIMM")
+	FormFactor string `json:"form_factor"`
+	// Maximum and configured speeds of the module, in megatransfers per
+	// second (MT/s). Zero if unknown.
+	SpeedMTs           uint32 `json:"speed_mts"`
+	ConfiguredSpeedMTs uint32 `json:"configured_speed_mts"`
+}
+
+func (m *Module) String() string {
+	sizeStr := util.UNKNOWN
+	if m.SizeBytes > 0 {
+		unit, unitStr := unitutil.AmountString(m.SizeBytes)
+		size := int64(math.Ceil(float64(m.SizeBytes) / float64(unit)))
+		sizeStr = fmt.Sprintf("%d%s", size, unitStr)
+	}
+	typeStr := ""
+	if m.Type != "" {
+		typeStr = " " + m.Type
+	}
+	speedStr := ""
+	if m.SpeedMTs > 0 {
+		speedStr = fmt.Sprintf(" @%dMT/s", m.SpeedMTs)
+	}
+	vendorStr := ""
+	if m.Vendor != "" {
+		vendorStr = " vendor=" + m.Vendor
+	}
+	partStr := ""
+	if m.PartNumber != "" {
+		partStr = " part=" + m.PartNumber
+	}
+	return fmt.Sprintf(
+		"%s (%s%s%s) [%s]%s%s",
+		m.Location,
+		sizeStr,
+		typeStr,
+		speedStr,
+		m.Label,
+		vendorStr,
+		partStr,
+	)
 }
 
 type Info struct {
@@ -17,7 +17,9 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/jaypipes/ghw/pkg/context"
 	"github.com/jaypipes/ghw/pkg/linuxpath"
+	"github.com/jaypipes/ghw/pkg/smbios"
 	"github.com/jaypipes/ghw/pkg/unitutil"
 	"github.com/jaypipes/ghw/pkg/util"
 )
@@ -53,9 +55,50 @@ func (i *Info) load() error {
 		i.TotalPhysicalBytes = tub
 	}
 	i.SupportedPageSizes = memSupportedPageSizes(paths)
+	i.Modules = memModules(i.ctx)
 	return nil
 }
 
+func memModules(ctx *context.Context) []*Module {
+	// In Linux, the only source of per-module information which doesn't
+	// require external tools like dmidecode is the SMBIOS structure table the
+	// kernel exposes in sysfs. Each memory slot is described by a Memory
+	// Device (type 17) structure.
+	mods := make([]*Module, 0)
+	info, err := smbios.NewWithContext(ctx)
+	if err != nil {
+		// the table is missing on non-SMBIOS systems and only readable by
+		// root, both normal conditions not worth a warning
+		if !os.IsNotExist(err) && !os.IsPermission(err) {
+			ctx.Warn("unable to read memory modules information: %v", err)
+		}
+		return mods
+	}
+	for _, md := range info.MemoryDevices {
+		if md.SizeBytes == 0 {
+			// empty slot
+			continue
+		}
+		size := md.SizeBytes
+		if size < 0 {
+			size = 0
+		}
+		mods = append(mods, &Module{
+			Label:              md.BankLocator,
+			Location:           md.DeviceLocator,
+			SerialNumber:       md.SerialNumber,
+			SizeBytes:          size,
+			Vendor:             md.Manufacturer,
+			PartNumber:         md.PartNumber,
+			Type:               md.Type,
+			FormFactor:         md.FormFactor,
+			SpeedMTs:           md.SpeedMTs,
+			ConfiguredSpeedMTs: md.ConfiguredSpeedMTs,
+		})
+	}
+	return mods
+}
+
 func memTotalPhysicalBytes(paths *linuxpath.Paths) (total int64) {
 	defer func() {
 		// fallback to the syslog file approach in case of error
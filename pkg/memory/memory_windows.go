@@ -7,6 +7,8 @@
 package memory
 
 import (
+	"strings"
+
 	"github.com/StackExchange/wmi"
 
 	"github.com/jaypipes/ghw/pkg/unitutil"
@@ -52,13 +54,20 @@ func (i *Info) load() error {
 	i.Modules = make([]*Module, 0, len(win32MemDescriptions))
 	for _, description := range win32MemDescriptions {
 		totalPhysicalBytes += *description.Capacity
-		i.Modules = append(i.Modules, &Module{
+		mod := &Module{
 			Label:        *description.BankLabel,
 			Location:     *description.DeviceLocator,
 			SerialNumber: *description.SerialNumber,
 			SizeBytes:    int64(*description.Capacity),
 			Vendor:       *description.Manufacturer,
-		})
+		}
+		if description.PartNumber != nil {
+			mod.PartNumber = strings.TrimSpace(*description.PartNumber)
+		}
+		if description.Speed != nil {
+			mod.SpeedMTs = *description.Speed
+		}
+		i.Modules = append(i.Modules, mod)
 	}
 	var totalUsableBytes uint64
 	for _, description := range win32OSDescriptions {
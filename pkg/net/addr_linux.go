@@ -0,0 +1,374 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package net
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	stdnet "net"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/jaypipes/ghw/pkg/context"
+	"github.com/jaypipes/ghw/pkg/linuxpath"
+)
+
+const (
+	// flags of the routes, from <linux/route.h> and <linux/ipv6_route.h>
+	_RTF_UP      = 0x0001
+	_RTF_GATEWAY = 0x0002
+	_RTF_CACHE   = 0x01000000
+	_RTF_LOCAL   = 0x80000000
+)
+
+// the addresses of /proc/net/route are printed as numbers in the byte order
+// of the host
+var nativeEndian binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// netFillAddresses sets the IP addresses and the routes of the NICs, if
+// requested. The addresses of the running system are read through the
+// standard library, and the ones of a chroot from /proc/net.
+func netFillAddresses(ctx *context.Context, paths *linuxpath.Paths, nics []*NIC) {
+	if !ctx.NetAddresses {
+		return
+	}
+	var addrs map[string][]*NICAddress
+	if ctx.Chroot == "/" {
+		addrs = interfaceAddresses(nics)
+	} else {
+		addrs = procAddresses(paths)
+	}
+	routes := procRoutes(paths)
+	for _, nic := range nics {
+		nic.Addresses = addrs[nic.Name]
+		if nic.Addresses == nil {
+			nic.Addresses = []*NICAddress{}
+		}
+		nic.Routes = routes[nic.Name]
+		if nic.Routes == nil {
+			nic.Routes = []*NICRoute{}
+		}
+	}
+}
+
+// interfaceAddresses returns the addresses of the NICs of the running system,
+// by NIC name
+func interfaceAddresses(nics []*NIC) map[string][]*NICAddress {
+	addrs := make(map[string][]*NICAddress)
+	for _, nic := range nics {
+		iface, err := stdnet.InterfaceByName(nic.Name)
+		if err != nil {
+			continue
+		}
+		ifaceAddrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, ifaceAddr := range ifaceAddrs {
+			ipNet, ok := ifaceAddr.(*stdnet.IPNet)
+			if !ok {
+				continue
+			}
+			prefixLength, _ := ipNet.Mask.Size()
+			addrs[nic.Name] = append(addrs[nic.Name], &NICAddress{
+				Address:      ipNet.IP.String(),
+				PrefixLength: prefixLength,
+				Scope:        ipScope(ipNet.IP),
+			})
+		}
+	}
+	return addrs
+}
+
+// ipScope returns the scope of the given address
+func ipScope(ip stdnet.IP) string {
+	switch {
+	case ip.IsLoopback():
+		return "host"
+	case ip.IsLinkLocalUnicast():
+		return "link"
+	case ip.To4() == nil && ip[0] == 0xfe && ip[1]&0xc0 == 0xc0:
+		// deprecated IPv6 site-local addresses, fec0::/10
+		return "site"
+	}
+	return "global"
+}
+
+// procAddresses returns the addresses listed in /proc/net, by NIC name.
+// IPv4 addresses are found in the local routes of /proc/net/fib_trie, and
+// attributed to the NIC routing the network they belong to: addresses
+// outside the networks of the NICs are not reported.
+func procAddresses(paths *linuxpath.Paths) map[string][]*NICAddress {
+	addrs := make(map[string][]*NICAddress)
+	fibTrie, err := ioutil.ReadFile(paths.ProcNetFibTrie)
+	if err == nil {
+		routes, _ := ioutil.ReadFile(paths.ProcNetRoute)
+		ipv4Routes := parseProcNetRoute(string(routes))
+		for _, ip := range parseFibTrieLocalAddresses(string(fibTrie)) {
+			if name, prefixLength, ok := ipv4AddressNetwork(ip, ipv4Routes); ok {
+				addrs[name] = append(addrs[name], &NICAddress{
+					Address:      ip.String(),
+					PrefixLength: prefixLength,
+					Scope:        ipScope(ip),
+				})
+			}
+		}
+	}
+	if ifInet6, err := ioutil.ReadFile(paths.ProcNetIfInet6); err == nil {
+		for name, ipv6Addrs := range parseIfInet6(string(ifInet6)) {
+			addrs[name] = append(addrs[name], ipv6Addrs...)
+		}
+	}
+	return addrs
+}
+
+// parseFibTrieLocalAddresses returns the addresses of the host listed in
+// /proc/net/fib_trie, whose entries look like the following:
+//
+//	Main:
+//	  +-- 0.0.0.0/0 3 0 5
+//	     |-- 0.0.0.0
+//	        /0 universe UNICAST
+//	     +-- 192.168.1.0/24 2 0 2
+//	        |-- 192.168.1.0
+//	           /24 link UNICAST
+//	        |-- 192.168.1.10
+//	           /32 host LOCAL
+//
+// The addresses of the host are the /32 LOCAL routes. The local and the main
+// tables are listed with the same entries, which are only returned once.
+func parseFibTrieLocalAddresses(data string) []stdnet.IP {
+	ips := make([]stdnet.IP, 0)
+	seen := make(map[string]bool)
+	var leaf stdnet.IP
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "|--" {
+			leaf = stdnet.ParseIP(fields[1])
+			continue
+		}
+		if len(fields) == 3 && fields[0] == "/32" && fields[2] == "LOCAL" && leaf != nil {
+			if !seen[leaf.String()] {
+				seen[leaf.String()] = true
+				ips = append(ips, leaf)
+			}
+		}
+	}
+	return ips
+}
+
+// procRoute is a route of /proc/net/route or /proc/net/ipv6_route
+type procRoute struct {
+	iface       string
+	destination *stdnet.IPNet
+	gateway     stdnet.IP
+	metric      int
+}
+
+// parseProcNetRoute parses the IPv4 routes of /proc/net/route, which look
+// like the following:
+//
+// Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+// eth0	00000000	0101A8C0	0003	0	0	100	00000000	0	0	0
+// eth0	0001A8C0	00000000	0001	0	0	100	00FFFFFF	0	0	0
+func parseProcNetRoute(data string) []*procRoute {
+	routes := make([]*procRoute, 0)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[0] == "Iface" {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil || flags&_RTF_UP == 0 {
+			continue
+		}
+		dst, err1 := parseRouteIPv4(fields[1])
+		gateway, err2 := parseRouteIPv4(fields[2])
+		mask, err3 := parseRouteIPv4(fields[7])
+		metric, err4 := strconv.Atoi(fields[6])
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			continue
+		}
+		route := &procRoute{
+			iface:       fields[0],
+			destination: &stdnet.IPNet{IP: dst, Mask: stdnet.IPMask(mask)},
+			metric:      metric,
+		}
+		if flags&_RTF_GATEWAY != 0 {
+			route.gateway = gateway
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+func parseRouteIPv4(value string) (stdnet.IP, error) {
+	v, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return nil, err
+	}
+	ip := make(stdnet.IP, stdnet.IPv4len)
+	nativeEndian.PutUint32(ip, uint32(v))
+	return ip, nil
+}
+
+// ipv4AddressNetwork returns the name of the NIC directly connected to the
+// most specific network the address belongs to, and the prefix length of
+// this network
+func ipv4AddressNetwork(ip stdnet.IP, routes []*procRoute) (string, int, bool) {
+	name := ""
+	prefixLength := -1
+	for _, route := range routes {
+		if route.gateway != nil || !route.destination.Contains(ip) {
+			continue
+		}
+		// the default route doesn't tell the network of the address
+		ones, _ := route.destination.Mask.Size()
+		if ones > prefixLength && ones > 0 {
+			name = route.iface
+			prefixLength = ones
+		}
+	}
+	return name, prefixLength, name != ""
+}
+
+// parseIfInet6 parses the IPv6 addresses of /proc/net/if_inet6, whose lines
+// list the address, the index of the NIC, the prefix length, the scope and
+// the flags of the address, in hexadecimal, and the name of the NIC:
+//
+// fe800000000000000202b3fffe1e8329 02 40 20 80     eth0
+func parseIfInet6(data string) map[string][]*NICAddress {
+	addrs := make(map[string][]*NICAddress)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 6 {
+			continue
+		}
+		ip, err := parseRouteIPv6(fields[0])
+		if err != nil {
+			continue
+		}
+		prefixLength, err := strconv.ParseUint(fields[2], 16, 8)
+		if err != nil {
+			continue
+		}
+		addrs[fields[5]] = append(addrs[fields[5]], &NICAddress{
+			Address:      ip.String(),
+			PrefixLength: int(prefixLength),
+			Scope:        ipv6Scope(fields[3]),
+		})
+	}
+	return addrs
+}
+
+// ipv6Scope returns the scope of an IPv6 address from the scope flags of
+// /proc/net/if_inet6, from <net/ipv6.h>
+func ipv6Scope(value string) string {
+	scope, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case scope&0x10 != 0:
+		return "host"
+	case scope&0x20 != 0:
+		return "link"
+	case scope&0x40 != 0:
+		return "site"
+	}
+	return "global"
+}
+
+func parseRouteIPv6(value string) (stdnet.IP, error) {
+	ip, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(ip) != stdnet.IPv6len {
+		return nil, fmt.Errorf("invalid IPv6 address %q", value)
+	}
+	return stdnet.IP(ip), nil
+}
+
+// parseProcNetIPv6Route parses the IPv6 routes of /proc/net/ipv6_route,
+// whose lines list the destination and its prefix length, the source and its
+// prefix length, the next hop, the metric, the reference count, the use
+// count and the flags of the route, in hexadecimal, and the name of the NIC:
+//
+// 00000000000000000000000000000000 00 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000400 00000001 00000000 00000003     eth0
+//
+// The routes of all the tables are listed: the local routes and the routes
+// to multicast networks are skipped, like `ip -6 route` does.
+func parseProcNetIPv6Route(data string) []*procRoute {
+	routes := make([]*procRoute, 0)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 10 {
+			continue
+		}
+		flags, err := strconv.ParseUint(fields[8], 16, 32)
+		if err != nil || flags&_RTF_UP == 0 || flags&(_RTF_CACHE|_RTF_LOCAL) != 0 {
+			continue
+		}
+		dst, err1 := parseRouteIPv6(fields[0])
+		prefixLength, err2 := strconv.ParseUint(fields[1], 16, 8)
+		gateway, err3 := parseRouteIPv6(fields[4])
+		metric, err4 := strconv.ParseUint(fields[5], 16, 32)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil || dst.IsMulticast() {
+			continue
+		}
+		route := &procRoute{
+			iface: fields[9],
+			destination: &stdnet.IPNet{
+				IP:   dst,
+				Mask: stdnet.CIDRMask(int(prefixLength), 8*stdnet.IPv6len),
+			},
+			metric: int(metric),
+		}
+		if flags&_RTF_GATEWAY != 0 {
+			route.gateway = gateway
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// procRoutes returns the IPv4 and IPv6 routes of /proc/net, by NIC name
+func procRoutes(paths *linuxpath.Paths) map[string][]*NICRoute {
+	routes := make([]*procRoute, 0)
+	if data, err := ioutil.ReadFile(paths.ProcNetRoute); err == nil {
+		routes = append(routes, parseProcNetRoute(string(data))...)
+	}
+	if data, err := ioutil.ReadFile(paths.ProcNetIPv6Route); err == nil {
+		routes = append(routes, parseProcNetIPv6Route(string(data))...)
+	}
+	nicRoutes := make(map[string][]*NICRoute)
+	for _, route := range routes {
+		nicRoute := &NICRoute{
+			Destination: route.destination.String(),
+			Metric:      route.metric,
+		}
+		if route.gateway != nil {
+			nicRoute.Gateway = route.gateway.String()
+		}
+		nicRoutes[route.iface] = append(nicRoutes[route.iface], nicRoute)
+	}
+	return nicRoutes
+}
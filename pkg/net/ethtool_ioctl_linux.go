@@ -0,0 +1,401 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package net
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// The ethtool ioctl API, from <linux/ethtool.h> and <linux/sockios.h>
+const (
+	siocEthtool = 0x8946
+
+	ethtoolGDrvInfo      = 0x03
+	ethtoolGCoalesce     = 0x0e
+	ethtoolGRingParam    = 0x10
+	ethtoolGStrings      = 0x1b
+	ethtoolGSSetInfo     = 0x37
+	ethtoolGFeatures     = 0x3a
+	ethtoolGChannels     = 0x3c
+	ethtoolGLinkSettings = 0x4c
+
+	ethSSFeatures  = 4
+	ethSSLinkModes = 9
+
+	ethGStringLen = 32
+	autonegEnable = 1
+)
+
+// ifreq is the struct ifreq of <linux/if.h> carrying a pointer to the
+// ethtool command. The union of ifreq is the size of three pointers.
+type ifreq struct {
+	name [syscall.IFNAMSIZ]byte
+	data unsafe.Pointer
+	_    [2]uintptr
+}
+
+type ethtoolDrvInfo struct {
+	cmd         uint32
+	driver      [32]byte
+	version     [32]byte
+	fwVersion   [32]byte
+	busInfo     [32]byte
+	eromVersion [32]byte
+	reserved2   [12]byte
+	nPrivFlags  uint32
+	nStats      uint32
+	testInfoLen uint32
+	eedumpLen   uint32
+	regdumpLen  uint32
+}
+
+type ethtoolRingParam struct {
+	cmd               uint32
+	rxMaxPending      uint32
+	rxMiniMaxPending  uint32
+	rxJumboMaxPending uint32
+	txMaxPending      uint32
+	rxPending         uint32
+	rxMiniPending     uint32
+	rxJumboPending    uint32
+	txPending         uint32
+}
+
+type ethtoolChannels struct {
+	cmd           uint32
+	maxRX         uint32
+	maxTX         uint32
+	maxOther      uint32
+	maxCombined   uint32
+	rxCount       uint32
+	txCount       uint32
+	otherCount    uint32
+	combinedCount uint32
+}
+
+type ethtoolCoalesce struct {
+	cmd                      uint32
+	rxCoalesceUsecs          uint32
+	rxMaxCoalescedFrames     uint32
+	rxCoalesceUsecsIRQ       uint32
+	rxMaxCoalescedFramesIRQ  uint32
+	txCoalesceUsecs          uint32
+	txMaxCoalescedFrames     uint32
+	txCoalesceUsecsIRQ       uint32
+	txMaxCoalescedFramesIRQ  uint32
+	statsBlockCoalesceUsecs  uint32
+	useAdaptiveRXCoalesce    uint32
+	useAdaptiveTXCoalesce    uint32
+	pktRateLow               uint32
+	rxCoalesceUsecsLow       uint32
+	rxMaxCoalescedFramesLow  uint32
+	txCoalesceUsecsLow       uint32
+	txMaxCoalescedFramesLow  uint32
+	pktRateHigh              uint32
+	rxCoalesceUsecsHigh      uint32
+	rxMaxCoalescedFramesHigh uint32
+	txCoalesceUsecsHigh      uint32
+	txMaxCoalescedFramesHigh uint32
+	rateSampleInterval       uint32
+}
+
+// ethtoolLinkSettings is the fixed part of struct ethtool_link_settings,
+// followed by the supported, advertised and link partner link mode bitmaps
+type ethtoolLinkSettings struct {
+	cmd                 uint32
+	speed               uint32
+	duplex              uint8
+	port                uint8
+	phyAddress          uint8
+	autoneg             uint8
+	mdioSupport         uint8
+	ethTpMdix           uint8
+	ethTpMdixCtrl       uint8
+	linkModeMasksNwords int8
+	transceiver         uint8
+	masterSlaveCfg      uint8
+	masterSlaveState    uint8
+	rateMatching        uint8
+	reserved            [7]uint32
+}
+
+// ethtoolIoctl calls the ethtool ioctl API of the kernel
+type ethtoolIoctl struct{}
+
+func (e *ethtoolIoctl) ioctl(dev string, data unsafe.Pointer) error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+	ifr := ifreq{data: data}
+	copy(ifr.name[:syscall.IFNAMSIZ-1], dev)
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL, uintptr(fd), siocEthtool, uintptr(unsafe.Pointer(&ifr)),
+	)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func (e *ethtoolIoctl) DriverInfo(dev string) (*NICDriverInfo, error) {
+	drvinfo := &ethtoolDrvInfo{cmd: ethtoolGDrvInfo}
+	if err := e.ioctl(dev, unsafe.Pointer(drvinfo)); err != nil {
+		return nil, err
+	}
+	return &NICDriverInfo{
+		Driver:          cString(drvinfo.driver[:]),
+		Version:         cString(drvinfo.version[:]),
+		FirmwareVersion: cString(drvinfo.fwVersion[:]),
+		BusInfo:         cString(drvinfo.busInfo[:]),
+	}, nil
+}
+
+func (e *ethtoolIoctl) RingParams(dev string) (*NICRingParams, error) {
+	ring := &ethtoolRingParam{cmd: ethtoolGRingParam}
+	if err := e.ioctl(dev, unsafe.Pointer(ring)); err != nil {
+		return nil, err
+	}
+	return &NICRingParams{
+		RX:    ring.rxPending,
+		RXMax: ring.rxMaxPending,
+		TX:    ring.txPending,
+		TXMax: ring.txMaxPending,
+	}, nil
+}
+
+func (e *ethtoolIoctl) Channels(dev string) (*NICChannels, error) {
+	channels := &ethtoolChannels{cmd: ethtoolGChannels}
+	if err := e.ioctl(dev, unsafe.Pointer(channels)); err != nil {
+		return nil, err
+	}
+	return &NICChannels{
+		RX:          channels.rxCount,
+		RXMax:       channels.maxRX,
+		TX:          channels.txCount,
+		TXMax:       channels.maxTX,
+		Other:       channels.otherCount,
+		OtherMax:    channels.maxOther,
+		Combined:    channels.combinedCount,
+		CombinedMax: channels.maxCombined,
+	}, nil
+}
+
+func (e *ethtoolIoctl) Coalesce(dev string) (*NICCoalesce, error) {
+	coalesce := &ethtoolCoalesce{cmd: ethtoolGCoalesce}
+	if err := e.ioctl(dev, unsafe.Pointer(coalesce)); err != nil {
+		return nil, err
+	}
+	return &NICCoalesce{
+		IsAdaptiveRX: coalesce.useAdaptiveRXCoalesce != 0,
+		IsAdaptiveTX: coalesce.useAdaptiveTXCoalesce != 0,
+		RXUsecs:      coalesce.rxCoalesceUsecs,
+		RXFrames:     coalesce.rxMaxCoalescedFrames,
+		TXUsecs:      coalesce.txCoalesceUsecs,
+		TXFrames:     coalesce.txMaxCoalescedFrames,
+	}, nil
+}
+
+func (e *ethtoolIoctl) LinkModes(dev string) (*NICLinkModes, error) {
+	names, err := e.strings(dev, ethSSLinkModes)
+	if err != nil {
+		return nil, err
+	}
+	// the kernel replies to a request without bitmaps with the negated
+	// number of 32-bit words of each bitmap
+	hdrWords := int(unsafe.Sizeof(ethtoolLinkSettings{}) / 4)
+	buf := make([]uint32, hdrWords)
+	settings := (*ethtoolLinkSettings)(unsafe.Pointer(&buf[0]))
+	settings.cmd = ethtoolGLinkSettings
+	if err := e.ioctl(dev, unsafe.Pointer(&buf[0])); err != nil {
+		return nil, err
+	}
+	nwords := -int(settings.linkModeMasksNwords)
+	if nwords <= 0 {
+		return nil, syscall.EPROTO
+	}
+	buf = make([]uint32, hdrWords+3*nwords)
+	settings = (*ethtoolLinkSettings)(unsafe.Pointer(&buf[0]))
+	settings.cmd = ethtoolGLinkSettings
+	settings.linkModeMasksNwords = int8(nwords)
+	if err := e.ioctl(dev, unsafe.Pointer(&buf[0])); err != nil {
+		return nil, err
+	}
+	supported := buf[hdrWords : hdrWords+nwords]
+	advertised := buf[hdrWords+nwords : hdrWords+2*nwords]
+	return &NICLinkModes{
+		Supported:         linkModeNames(supported, names),
+		Advertised:        linkModeNames(advertised, names),
+		IsAutoNegotiation: settings.autoneg == autonegEnable,
+	}, nil
+}
+
+// linkModeNames returns the names of the link modes set in the bitmap. The
+// bits which are not link modes, like "Autoneg" or "Pause", are skipped.
+func linkModeNames(bitmap []uint32, names []string) []string {
+	modes := make([]string, 0)
+	for bit, name := range names {
+		if bit/32 >= len(bitmap) || bitmap[bit/32]&(1<<uint(bit%32)) == 0 {
+			continue
+		}
+		if !strings.Contains(name, "base") {
+			continue
+		}
+		modes = append(modes, name)
+	}
+	return modes
+}
+
+func (e *ethtoolIoctl) Features(dev string) ([]*NICCapability, error) {
+	names, err := e.strings(dev, ethSSFeatures)
+	if err != nil {
+		return nil, err
+	}
+	// struct ethtool_gfeatures is followed by a block of 4 32-bit words per
+	// 32 features: available, requested, active and never changed
+	nblocks := (len(names) + 31) / 32
+	buf := make([]uint32, 2+4*nblocks)
+	buf[0] = ethtoolGFeatures
+	buf[1] = uint32(nblocks)
+	if err := e.ioctl(dev, unsafe.Pointer(&buf[0])); err != nil {
+		return nil, err
+	}
+	features := make([]ethtoolFeature, len(names))
+	for x, name := range names {
+		block := buf[2+4*(x/32) : 2+4*(x/32)+4]
+		bit := uint32(1) << uint(x%32)
+		features[x] = ethtoolFeature{
+			name:     name,
+			isActive: block[2]&bit != 0,
+			// features which are not available or are never changed by the
+			// driver are shown as [fixed] by ethtool
+			isFixed: block[0]&bit == 0 || block[3]&bit != 0,
+		}
+	}
+	return ethtoolFeatureCapabilities(features), nil
+}
+
+// strings returns the strings of the given string set
+func (e *ethtoolIoctl) strings(dev string, stringSet uint32) ([]string, error) {
+	// struct ethtool_sset_info has its 64-bit set mask at offset 8, and is
+	// followed by the length of each requested set
+	info := make([]uint32, 5)
+	info[0] = ethtoolGSSetInfo
+	*(*uint64)(unsafe.Pointer(&info[2])) = 1 << stringSet
+	if err := e.ioctl(dev, unsafe.Pointer(&info[0])); err != nil {
+		return nil, err
+	}
+	if *(*uint64)(unsafe.Pointer(&info[2])) == 0 {
+		return nil, syscall.EOPNOTSUPP
+	}
+	count := int(info[4])
+	// struct ethtool_gstrings is followed by the strings
+	buf := make([]byte, 12+count*ethGStringLen)
+	hdr := (*[3]uint32)(unsafe.Pointer(&buf[0]))
+	hdr[0] = ethtoolGStrings
+	hdr[1] = stringSet
+	hdr[2] = uint32(count)
+	if err := e.ioctl(dev, unsafe.Pointer(&buf[0])); err != nil {
+		return nil, err
+	}
+	names := make([]string, count)
+	for x := range names {
+		names[x] = cString(buf[12+x*ethGStringLen : 12+(x+1)*ethGStringLen])
+	}
+	return names, nil
+}
+
+// ethtoolFeature is the state of one of the features the kernel names in
+// the ETH_SS_FEATURES string set, e.g. "tx-checksum-ipv4"
+type ethtoolFeature struct {
+	name     string
+	isActive bool
+	isFixed  bool
+}
+
+// ethtoolFeatureGroups are the features ethtool reports under the name of
+// the legacy setting they were controlled by, and the patterns of the
+// features of each group
+var ethtoolFeatureGroups = []struct {
+	name    string
+	pattern string
+}{
+	{"rx-checksumming", "rx-checksum"},
+	{"tx-checksumming", "tx-checksum-*"},
+	{"scatter-gather", "tx-scatter-gather*"},
+	{"tcp-segmentation-offload", "tx-tcp*-segmentation"},
+	{"udp-fragmentation-offload", "tx-udp-fragmentation"},
+	{"generic-segmentation-offload", "tx-generic-segmentation"},
+	{"generic-receive-offload", "rx-gro"},
+	{"large-receive-offload", "rx-lro"},
+	{"rx-vlan-offload", "rx-vlan-hw-parse"},
+	{"tx-vlan-offload", "tx-vlan-hw-insert"},
+	{"ntuple-filters", "rx-ntuple-filter"},
+	{"receive-hashing", "rx-hashing"},
+}
+
+// ethtoolFeatureCapabilities returns the capabilities `ethtool -k` reports
+// for the given features: the groups of features first, followed by the
+// features of the groups with more than one feature, and then the other
+// features. The unnamed slots of the string set are skipped, like ethtool
+// does.
+func ethtoolFeatureCapabilities(features []ethtoolFeature) []*NICCapability {
+	caps := make([]*NICCapability, 0, len(features))
+	grouped := make([]bool, len(features))
+	for _, group := range ethtoolFeatureGroups {
+		members := make([]int, 0)
+		for x, feature := range features {
+			if matched, _ := path.Match(group.pattern, feature.name); matched {
+				members = append(members, x)
+			}
+		}
+		if len(members) == 0 {
+			continue
+		}
+		// the group is enabled if any of its features is, and can be
+		// enabled if any of its features can
+		groupCap := &NICCapability{Name: group.name}
+		for _, x := range members {
+			groupCap.IsEnabled = groupCap.IsEnabled || features[x].isActive
+			groupCap.CanEnable = groupCap.CanEnable || !features[x].isFixed
+			grouped[x] = true
+		}
+		caps = append(caps, groupCap)
+		if len(members) == 1 {
+			continue
+		}
+		for _, x := range members {
+			caps = append(caps, features[x].capability())
+		}
+	}
+	for x, feature := range features {
+		if !grouped[x] && feature.name != "" {
+			caps = append(caps, feature.capability())
+		}
+	}
+	return caps
+}
+
+func (f ethtoolFeature) capability() *NICCapability {
+	return &NICCapability{
+		Name:      f.name,
+		IsEnabled: f.isActive,
+		CanEnable: !f.isFixed,
+	}
+}
+
+// cString returns the NUL-terminated string at the start of the buffer
+func cString(buf []byte) string {
+	if idx := bytes.IndexByte(buf, 0); idx >= 0 {
+		buf = buf[:idx]
+	}
+	return string(buf)
+}
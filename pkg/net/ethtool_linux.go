@@ -0,0 +1,388 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package net
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/jaypipes/ghw/pkg/context"
+)
+
+const (
+	_WARN_ETHTOOL_NOT_INSTALLED = `ethtool not installed. Cannot grab NIC capabilities`
+)
+
+// ethtool gets the settings of network devices which are only available
+// through the ethtool API of the kernel
+type ethtool interface {
+	Features(dev string) ([]*NICCapability, error)
+	DriverInfo(dev string) (*NICDriverInfo, error)
+	RingParams(dev string) (*NICRingParams, error)
+	Channels(dev string) (*NICChannels, error)
+	Coalesce(dev string) (*NICCoalesce, error)
+	LinkModes(dev string) (*NICLinkModes, error)
+}
+
+// newEthtool returns the ethtool to use with the given context, or nil if
+// the ethtool API can't be used. The ethtool API is called directly when
+// reading the running system, falling back on the ethtool program when
+// tools are enabled.
+func newEthtool(ctx *context.Context) ethtool {
+	chain := ethtoolChain{}
+	// the ethtool API reports the devices of the running system, not the
+	// ones of the chroot or snapshot
+	if ctx.Chroot == "/" {
+		chain = append(chain, &ethtoolIoctl{})
+	}
+	if ctx.EnableTools {
+		if ethtoolInstalled() {
+			chain = append(chain, &ethtoolCommand{run: runEthtool})
+		} else if len(chain) == 0 {
+			ctx.Warn(_WARN_ETHTOOL_NOT_INSTALLED)
+		}
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+	return chain
+}
+
+// netDeviceFillEthtool sets the capabilities and the ethtool settings of the
+// NIC. Missing settings are not an error, since most virtual devices and
+// many drivers only report some of them.
+func netDeviceFillEthtool(ctx *context.Context, et ethtool, nic *NIC) {
+	caps, err := et.Features(nic.Name)
+	if err != nil {
+		msg := fmt.Sprintf("could not grab NIC capabilities for %s: %s", nic.Name, err)
+		ctx.Warn(msg)
+	} else {
+		nic.Capabilities = caps
+	}
+	nic.DriverInfo, _ = et.DriverInfo(nic.Name)
+	nic.RingParams, _ = et.RingParams(nic.Name)
+	nic.Channels, _ = et.Channels(nic.Name)
+	nic.Coalesce, _ = et.Coalesce(nic.Name)
+	nic.LinkModes, _ = et.LinkModes(nic.Name)
+}
+
+// ethtoolChain tries each of its ethtools in turn, until one of them
+// returns the requested settings
+type ethtoolChain []ethtool
+
+func (c ethtoolChain) Features(dev string) (caps []*NICCapability, err error) {
+	for _, et := range c {
+		if caps, err = et.Features(dev); err == nil {
+			return caps, nil
+		}
+	}
+	return nil, err
+}
+
+func (c ethtoolChain) DriverInfo(dev string) (info *NICDriverInfo, err error) {
+	for _, et := range c {
+		if info, err = et.DriverInfo(dev); err == nil {
+			return info, nil
+		}
+	}
+	return nil, err
+}
+
+func (c ethtoolChain) RingParams(dev string) (ring *NICRingParams, err error) {
+	for _, et := range c {
+		if ring, err = et.RingParams(dev); err == nil {
+			return ring, nil
+		}
+	}
+	return nil, err
+}
+
+func (c ethtoolChain) Channels(dev string) (channels *NICChannels, err error) {
+	for _, et := range c {
+		if channels, err = et.Channels(dev); err == nil {
+			return channels, nil
+		}
+	}
+	return nil, err
+}
+
+func (c ethtoolChain) Coalesce(dev string) (coalesce *NICCoalesce, err error) {
+	for _, et := range c {
+		if coalesce, err = et.Coalesce(dev); err == nil {
+			return coalesce, nil
+		}
+	}
+	return nil, err
+}
+
+func (c ethtoolChain) LinkModes(dev string) (modes *NICLinkModes, err error) {
+	for _, et := range c {
+		if modes, err = et.LinkModes(dev); err == nil {
+			return modes, nil
+		}
+	}
+	return nil, err
+}
+
+func ethtoolInstalled() bool {
+	_, err := exec.LookPath("ethtool")
+	return err == nil
+}
+
+func runEthtool(args ...string) ([]byte, error) {
+	return exec.Command("ethtool", args...).Output()
+}
+
+// ethtoolCommand parses the output of the ethtool program
+type ethtoolCommand struct {
+	// run returns the output of ethtool called with the given arguments, so
+	// that recorded outputs can be used in tests
+	run func(args ...string) ([]byte, error)
+}
+
+func (c *ethtoolCommand) Features(dev string) ([]*NICCapability, error) {
+	out, err := c.run("-k", dev)
+	if err != nil {
+		return nil, err
+	}
+	return parseEthtoolFeatures(out), nil
+}
+
+func (c *ethtoolCommand) DriverInfo(dev string) (*NICDriverInfo, error) {
+	out, err := c.run("-i", dev)
+	if err != nil {
+		return nil, err
+	}
+	info := &NICDriverInfo{}
+	for _, field := range parseEthtoolFields(out) {
+		switch field.key {
+		case "driver":
+			info.Driver = field.value
+		case "version":
+			info.Version = field.value
+		case "firmware-version":
+			info.FirmwareVersion = field.value
+		case "bus-info":
+			info.BusInfo = field.value
+		}
+	}
+	return info, nil
+}
+
+func (c *ethtoolCommand) RingParams(dev string) (*NICRingParams, error) {
+	out, err := c.run("-g", dev)
+	if err != nil {
+		return nil, err
+	}
+	ring := &NICRingParams{}
+	maximums, current := parseEthtoolSettings(out)
+	ring.RXMax = ethtoolUint(maximums["RX"])
+	ring.TXMax = ethtoolUint(maximums["TX"])
+	ring.RX = ethtoolUint(current["RX"])
+	ring.TX = ethtoolUint(current["TX"])
+	return ring, nil
+}
+
+func (c *ethtoolCommand) Channels(dev string) (*NICChannels, error) {
+	out, err := c.run("-l", dev)
+	if err != nil {
+		return nil, err
+	}
+	channels := &NICChannels{}
+	maximums, current := parseEthtoolSettings(out)
+	channels.RXMax = ethtoolUint(maximums["RX"])
+	channels.TXMax = ethtoolUint(maximums["TX"])
+	channels.OtherMax = ethtoolUint(maximums["Other"])
+	channels.CombinedMax = ethtoolUint(maximums["Combined"])
+	channels.RX = ethtoolUint(current["RX"])
+	channels.TX = ethtoolUint(current["TX"])
+	channels.Other = ethtoolUint(current["Other"])
+	channels.Combined = ethtoolUint(current["Combined"])
+	return channels, nil
+}
+
+func (c *ethtoolCommand) Coalesce(dev string) (*NICCoalesce, error) {
+	out, err := c.run("-c", dev)
+	if err != nil {
+		return nil, err
+	}
+	coalesce := &NICCoalesce{}
+	for _, field := range parseEthtoolFields(out) {
+		switch field.key {
+		case "Adaptive RX":
+			// the adaptive settings share a line: "Adaptive RX: on  TX: off"
+			values := strings.Fields(field.value)
+			coalesce.IsAdaptiveRX = len(values) > 0 && values[0] == "on"
+			coalesce.IsAdaptiveTX = len(values) > 2 && values[1] == "TX:" && values[2] == "on"
+		case "rx-usecs":
+			coalesce.RXUsecs = ethtoolUint(field.value)
+		case "rx-frames":
+			coalesce.RXFrames = ethtoolUint(field.value)
+		case "tx-usecs":
+			coalesce.TXUsecs = ethtoolUint(field.value)
+		case "tx-frames":
+			coalesce.TXFrames = ethtoolUint(field.value)
+		}
+	}
+	return coalesce, nil
+}
+
+func (c *ethtoolCommand) LinkModes(dev string) (*NICLinkModes, error) {
+	out, err := c.run(dev)
+	if err != nil {
+		return nil, err
+	}
+	modes := &NICLinkModes{
+		Supported:  []string{},
+		Advertised: []string{},
+	}
+	for _, field := range parseEthtoolFields(out) {
+		switch field.key {
+		case "Supported link modes":
+			modes.Supported = ethtoolLinkModes(field.value)
+		case "Advertised link modes":
+			modes.Advertised = ethtoolLinkModes(field.value)
+		case "Auto-negotiation":
+			modes.IsAutoNegotiation = field.value == "on"
+		}
+	}
+	return modes, nil
+}
+
+// parseEthtoolFeatures parses the output of `ethtool -k`
+func parseEthtoolFeatures(out []byte) []*NICCapability {
+	caps := make([]*NICCapability, 0)
+	// The output looks like the following, with the features a feature
+	// (e.g. "tx-checksumming") groups indented with a tab:
+	//
+	// Features for enp58s0f1:
+	// rx-checksumming: on
+	// tx-checksumming: off
+	//     tx-checksum-ipv4: off
+	//     tx-checksum-ip-generic: off [fixed]
+	//     tx-checksum-ipv6: off
+	//     tx-checksum-fcoe-crc: off [fixed]
+	//     tx-checksum-sctp: off [fixed]
+	// scatter-gather: off
+	//     tx-scatter-gather: off
+	//     tx-scatter-gather-fraglist: off [fixed]
+	// tcp-segmentation-offload: off
+	//     tx-tcp-segmentation: off
+	//     tx-tcp-ecn-segmentation: off [fixed]
+	//     tx-tcp-mangleid-segmentation: off
+	//     tx-tcp6-segmentation: off
+	// < snipped >
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Features for ") {
+			continue
+		}
+		if cap := netParseEthtoolFeature(strings.TrimPrefix(line, "\t")); cap != nil {
+			caps = append(caps, cap)
+		}
+	}
+	return caps
+}
+
+// netParseEthtoolFeature parses a line from the ethtool -k output and returns
+// a NICCapability, or nil if the line is not a feature.
+//
+// The supplied line will look like the following:
+//
+// tx-checksum-ip-generic: off [fixed]
+//
+// [fixed] indicates that the feature may not be turned on/off. Note: it makes
+// no difference whether a privileged user runs `ethtool -k` when determining
+// whether [fixed] appears for a feature.
+func netParseEthtoolFeature(line string) *NICCapability {
+	parts := strings.Fields(line)
+	if len(parts) < 2 || !strings.HasSuffix(parts[0], ":") {
+		return nil
+	}
+	cap := strings.TrimSuffix(parts[0], ":")
+	enabled := parts[1] == "on"
+	fixed := len(parts) == 3 && parts[2] == "[fixed]"
+	return &NICCapability{
+		Name:      cap,
+		IsEnabled: enabled,
+		CanEnable: !fixed,
+	}
+}
+
+// ethtoolField is a "key: value" line of the ethtool output
+type ethtoolField struct {
+	key   string
+	value string
+}
+
+// parseEthtoolFields returns the "key: value" lines of the ethtool output, in
+// order. The values which are continued on the following lines, like the
+// link modes, are joined with a space. Section headers, like "Current
+// hardware settings:", are returned with an empty value.
+func parseEthtoolFields(out []byte) []ethtoolField {
+	fields := make([]ethtoolField, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		idx := strings.Index(line, ":")
+		if idx < 0 {
+			if len(fields) > 0 {
+				last := &fields[len(fields)-1]
+				last.value = strings.TrimSpace(last.value + " " + line)
+			}
+			continue
+		}
+		fields = append(fields, ethtoolField{
+			key:   strings.TrimSpace(line[:idx]),
+			value: strings.TrimSpace(line[idx+1:]),
+		})
+	}
+	return fields
+}
+
+// parseEthtoolSettings parses the output of `ethtool -g` and `ethtool -l`,
+// which list the maximum settings and then the current settings, and
+// returns both
+func parseEthtoolSettings(out []byte) (map[string]string, map[string]string) {
+	maximums := make(map[string]string)
+	current := make(map[string]string)
+	section := maximums
+	for _, field := range parseEthtoolFields(out) {
+		if field.key == "Current hardware settings" && field.value == "" {
+			section = current
+			continue
+		}
+		section[field.key] = field.value
+	}
+	return maximums, current
+}
+
+// ethtoolUint returns the value of a numeric setting, or 0 for the settings
+// the device doesn't support, shown as "n/a"
+func ethtoolUint(value string) uint32 {
+	v, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(v)
+}
+
+// ethtoolLinkModes returns the link modes listed in the given value, e.g.
+// "1000baseT/Full 10000baseT/Full", which is "Not reported" if there are none
+func ethtoolLinkModes(value string) []string {
+	if value == "Not reported" {
+		return []string{}
+	}
+	return strings.Fields(value)
+}
@@ -7,19 +7,209 @@
 package net
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/jaypipes/ghw/pkg/context"
 	"github.com/jaypipes/ghw/pkg/marshal"
 	"github.com/jaypipes/ghw/pkg/option"
+	"github.com/jaypipes/ghw/pkg/pci"
 )
 
+// NICKind describes the kind of a NIC, physical or one of the kinds of
+// virtual interfaces
+type NICKind int
+
+const (
+	NIC_KIND_UNKNOWN     NICKind = iota
+	NIC_KIND_PHYSICAL            // Network device, or a function of one
+	NIC_KIND_BOND                // Bonding of several NICs
+	NIC_KIND_BRIDGE              // Software bridge
+	NIC_KIND_VLAN                // 802.1Q VLAN on a NIC
+	NIC_KIND_MACVLAN             // Interface with its own MAC address on a NIC
+	NIC_KIND_IPVLAN              // Interface sharing the MAC address of a NIC
+	NIC_KIND_VETH                // End of a virtual Ethernet pair
+	NIC_KIND_TEAM                // Teaming of several NICs
+	NIC_KIND_REPRESENTOR         // Representor of a SR-IOV virtual function
+	NIC_KIND_VIRTUAL             // Other virtual interface, e.g. dummy or tun
+)
+
+var (
+	nicKindString = map[NICKind]string{
+		NIC_KIND_UNKNOWN:     "Unknown",
+		NIC_KIND_PHYSICAL:    "Physical",
+		NIC_KIND_BOND:        "Bond",
+		NIC_KIND_BRIDGE:      "Bridge",
+		NIC_KIND_VLAN:        "VLAN",
+		NIC_KIND_MACVLAN:     "MACVLAN",
+		NIC_KIND_IPVLAN:      "IPVLAN",
+		NIC_KIND_VETH:        "veth",
+		NIC_KIND_TEAM:        "Team",
+		NIC_KIND_REPRESENTOR: "Representor",
+		NIC_KIND_VIRTUAL:     "Virtual",
+	}
+)
+
+func (k NICKind) String() string {
+	return nicKindString[k]
+}
+
+// NOTE(jaypipes): since serialized output is as "official" as we're going to
+// get, let's lowercase the string output when serializing, in order to
+// "normalize" the expected serialized output
+func (k NICKind) MarshalJSON() ([]byte, error) {
+	return []byte("\"" + strings.ToLower(k.String()) + "\""), nil
+}
+
 type NICCapability struct {
 	Name      string `json:"name"`
 	IsEnabled bool   `json:"is_enabled"`
 	CanEnable bool   `json:"can_enable"`
 }
 
+// NICLink describes the state and the settings of the link of a NIC
+type NICLink struct {
+	// Index is the interface index of the NIC (ifindex)
+	Index int `json:"index"`
+	// Type is the hardware type of the link, as an ARPHRD_* value of
+	// <linux/if_arp.h>, e.g. 1 for Ethernet
+	Type int `json:"type"`
+	// OperState is the RFC 2863 operational state of the link, e.g. "up",
+	// "down", "dormant" or "unknown"
+	OperState string `json:"oper_state"`
+	// HasCarrier is true if the physical link is up. Always false for NICs
+	// which are administratively down.
+	HasCarrier bool `json:"carrier"`
+	// SpeedMbps is the negotiated speed of the link, or -1 if unknown
+	SpeedMbps int `json:"speed_mbps"`
+	// Duplex is "full", "half" or "unknown"
+	Duplex     string `json:"duplex"`
+	MTU        int    `json:"mtu"`
+	TxQueueLen int    `json:"tx_queue_len"`
+	// DevPort is the index of the port of the device the NIC is, for
+	// devices with several ports
+	DevPort int `json:"dev_port"`
+	// PhysPortName is the name of the physical port of the NIC given by the
+	// driver, e.g. "p0" or "pf0vf1". Empty if the driver doesn't name ports.
+	PhysPortName string `json:"phys_port_name"`
+}
+
+// NICDriverInfo describes the driver of a NIC, as reported by ethtool
+type NICDriverInfo struct {
+	Driver          string `json:"driver"`
+	Version         string `json:"version"`
+	FirmwareVersion string `json:"firmware_version"`
+	// BusInfo is the address of the device on its bus, e.g. the PCI address
+	BusInfo string `json:"bus_info"`
+}
+
+// NICRingParams describes the number of descriptors of the RX and TX rings of
+// a NIC
+type NICRingParams struct {
+	RX    uint32 `json:"rx"`
+	RXMax uint32 `json:"rx_max"`
+	TX    uint32 `json:"tx"`
+	TXMax uint32 `json:"tx_max"`
+}
+
+// NICChannels describes the channels of a NIC, i.e. the queues and the
+// interrupts serving them. Combined channels serve both RX and TX.
+type NICChannels struct {
+	RX          uint32 `json:"rx"`
+	RXMax       uint32 `json:"rx_max"`
+	TX          uint32 `json:"tx"`
+	TXMax       uint32 `json:"tx_max"`
+	Other       uint32 `json:"other"`
+	OtherMax    uint32 `json:"other_max"`
+	Combined    uint32 `json:"combined"`
+	CombinedMax uint32 `json:"combined_max"`
+}
+
+// NICCoalesce describes the interrupt coalescing settings of a NIC. An
+// interrupt is raised after the given number of microseconds or of frames.
+type NICCoalesce struct {
+	IsAdaptiveRX bool   `json:"adaptive_rx"`
+	IsAdaptiveTX bool   `json:"adaptive_tx"`
+	RXUsecs      uint32 `json:"rx_usecs"`
+	RXFrames     uint32 `json:"rx_frames"`
+	TXUsecs      uint32 `json:"tx_usecs"`
+	TXFrames     uint32 `json:"tx_frames"`
+}
+
+// NICLinkModes describes the link modes of a NIC, e.g. "1000baseT/Full"
+type NICLinkModes struct {
+	Supported         []string `json:"supported"`
+	Advertised        []string `json:"advertised"`
+	IsAutoNegotiation bool     `json:"auto_negotiation"`
+}
+
+// NICQueues describes the hardware queues of a NIC
+type NICQueues struct {
+	// RX and TX are the number of receive and transmit queues of the NIC,
+	// combined queues included
+	RX int `json:"rx"`
+	TX int `json:"tx"`
+	// Combined is the number of queues serving both RX and TX, or 0 if
+	// unknown
+	Combined int `json:"combined"`
+}
+
+// NICBond describes the settings of a bond
+type NICBond struct {
+	// Mode is the bonding mode, e.g. "active-backup" or "802.3ad"
+	Mode string `json:"mode"`
+	// TransmitHashPolicy is the policy selecting the slave to transmit a
+	// packet on, e.g. "layer2", in the modes balancing the traffic
+	TransmitHashPolicy string `json:"transmit_hash_policy"`
+}
+
+// NICVLAN describes a VLAN interface
+type NICVLAN struct {
+	// ID is the 802.1Q VLAN identifier
+	ID int `json:"id"`
+}
+
+// NICRepresentor describes the SR-IOV virtual function a representor stands
+// for, on the switch of the physical function
+type NICRepresentor struct {
+	// Controller is the number of the controller of multi-host devices
+	// (e.g. SmartNICs) the virtual function belongs to, as named by the
+	// phys_port_name of the representor, e.g. "c1pf0vf3". The virtual
+	// functions of other controllers belong to other hosts. -1 if the
+	// representor names no controller.
+	Controller int `json:"controller"`
+	// PFIndex and VFIndex are the indexes of the physical function and of
+	// the virtual function, as named by the phys_port_name of the
+	// representor, e.g. "pf0vf3"
+	PFIndex int `json:"pf_index"`
+	VFIndex int `json:"vf_index"`
+	// VF is the PCI device of the virtual function. Will be nil if the
+	// virtual function is not found, or belongs to another host.
+	VF *pci.Device `json:"-"`
+}
+
+// NICAddress describes an IP address of a NIC
+type NICAddress struct {
+	// Address is the IPv4 or IPv6 address, e.g. "192.168.1.10" or "fe80::1"
+	Address      string `json:"address"`
+	PrefixLength int    `json:"prefix_length"`
+	// Scope is the scope the address is valid in: "global", "site", "link"
+	// or "host"
+	Scope string `json:"scope"`
+}
+
+// NICRoute describes a route through a NIC
+type NICRoute struct {
+	// Destination is the destination network, e.g. "10.0.0.0/8", or
+	// "0.0.0.0/0" and "::/0" for the default routes
+	Destination string `json:"destination"`
+	// Gateway is the address of the next hop, or empty for the networks the
+	// NIC is directly connected to
+	Gateway string `json:"gateway,omitempty"`
+	Metric  int    `json:"metric"`
+}
+
 type NIC struct {
 	Name         string           `json:"name"`
 	MacAddress   string           `json:"mac_address"`
@@ -27,6 +217,55 @@ type NIC struct {
 	Capabilities []*NICCapability `json:"capabilities"`
 	PCIAddress   *string          `json:"pci_address,omitempty"`
 	// TODO(fromani): add other hw addresses (USB) when we support them
+
+	// PCI device backing the NIC. Will be nil if the NIC is not a PCI
+	// device.
+	PCI *pci.Device `json:"pci,omitempty"`
+	// Driver is the name of the kernel driver bound to the device of the
+	// NIC, or the driver reported by ethtool. Empty if unknown.
+	Driver string `json:"driver,omitempty"`
+	// DriverVersion is the version of the kernel module of the driver.
+	// Empty if unknown, or if the module has no version of its own, like
+	// most in-tree modules.
+	DriverVersion string `json:"driver_version,omitempty"`
+	// FirmwareVersion is the version of the firmware of the NIC reported by
+	// ethtool. Empty if unknown.
+	FirmwareVersion string `json:"firmware_version,omitempty"`
+	// Hardware queues of the NIC. Will be nil if unknown. Linux only.
+	Queues *NICQueues `json:"queues,omitempty"`
+	// Kind of the NIC. Linux only.
+	Kind NICKind `json:"kind"`
+	// Master is the bond, bridge or team the NIC is a port of. Will be nil
+	// if the NIC is not a port.
+	Master *NIC `json:"-"`
+	// Lower are the NICs this NIC is stacked on, e.g. the slaves of a bond,
+	// the ports of a bridge or the parent of a VLAN, sorted by name
+	Lower []*NIC `json:"-"`
+	// Upper are the NICs stacked on this NIC, sorted by name
+	Upper []*NIC `json:"-"`
+	// Peer is the other end of a veth pair. Will be nil if the NIC is not a
+	// veth, or if the peer is in another network namespace.
+	Peer *NIC `json:"-"`
+	// Settings of the bond, VLAN or representor, depending on the kind of
+	// the NIC. Nil for the other kinds.
+	Bond        *NICBond        `json:"bond,omitempty"`
+	VLAN        *NICVLAN        `json:"vlan,omitempty"`
+	Representor *NICRepresentor `json:"representor,omitempty"`
+	// IP addresses and routes of the NIC. Only reported with the
+	// IncludeAddresses net option, nil otherwise. Linux only.
+	Addresses []*NICAddress `json:"addresses,omitempty"`
+	Routes    []*NICRoute   `json:"routes,omitempty"`
+
+	// Link state and settings of the NIC. Will be nil if they are not
+	// available. Linux only.
+	Link *NICLink `json:"link,omitempty"`
+	// Settings reported by ethtool. Each of them will be nil if the NIC
+	// doesn't report it, or if ethtool is not available. Linux only.
+	DriverInfo *NICDriverInfo `json:"driver_info,omitempty"`
+	RingParams *NICRingParams `json:"ring_params,omitempty"`
+	Channels   *NICChannels   `json:"channels,omitempty"`
+	Coalesce   *NICCoalesce   `json:"coalesce,omitempty"`
+	LinkModes  *NICLinkModes  `json:"link_modes,omitempty"`
 }
 
 func (n *NIC) String() string {
@@ -34,13 +273,76 @@ func (n *NIC) String() string {
 	if n.IsVirtual {
 		isVirtualStr = " (virtual)"
 	}
+	linkStr := ""
+	if n.Link != nil {
+		linkStr = " " + n.Link.OperState
+		if n.Link.SpeedMbps >= 0 {
+			linkStr += fmt.Sprintf(" %dMb/s", n.Link.SpeedMbps)
+		}
+	}
+	driverStr := ""
+	if n.Driver != "" {
+		driverStr = " [" + n.Driver
+		if n.DriverVersion != "" {
+			driverStr += " " + n.DriverVersion
+		}
+		if n.FirmwareVersion != "" {
+			driverStr += ", firmware " + n.FirmwareVersion
+		}
+		driverStr += "]"
+	}
+	queuesStr := ""
+	if n.Queues != nil {
+		queuesStr = fmt.Sprintf(" (%d RX, %d TX queues)", n.Queues.RX, n.Queues.TX)
+	}
 	return fmt.Sprintf(
-		"%s%s",
+		"%s%s%s%s%s",
 		n.Name,
 		isVirtualStr,
+		linkStr,
+		driverStr,
+		queuesStr,
 	)
 }
 
+// nicNames returns the names of the given NICs
+func nicNames(nics []*NIC) []string {
+	names := make([]string, 0, len(nics))
+	for _, nic := range nics {
+		names = append(names, nic.Name)
+	}
+	return names
+}
+
+// MarshalJSON emits the names of the NICs related to the NIC, instead of the
+// NICs themselves
+func (n *NIC) MarshalJSON() ([]byte, error) {
+	type nic NIC
+	nm := struct {
+		*nic
+		Master string   `json:"master,omitempty"`
+		Lower  []string `json:"lower,omitempty"`
+		Upper  []string `json:"upper,omitempty"`
+		Peer   string   `json:"peer,omitempty"`
+		// the address of the virtual function a representor stands for
+		RepresentedVF string `json:"represented_vf,omitempty"`
+	}{
+		nic:   (*nic)(n),
+		Lower: nicNames(n.Lower),
+		Upper: nicNames(n.Upper),
+	}
+	if n.Master != nil {
+		nm.Master = n.Master.Name
+	}
+	if n.Peer != nil {
+		nm.Peer = n.Peer.Name
+	}
+	if n.Representor != nil && n.Representor.VF != nil {
+		nm.RepresentedVF = n.Representor.VF.Address
+	}
+	return json.Marshal(nm)
+}
+
 type Info struct {
 	ctx  *context.Context
 	NICs []*NIC `json:"nics"`
@@ -64,6 +366,40 @@ func (i *Info) String() string {
 	)
 }
 
+// TopologyString returns the NICs as trees, from the NICs which are not
+// stacked on another NIC, like the physical ports, up to the logical
+// interfaces stacked on them. A NIC stacked on several NICs, like a bond, is
+// shown under each of them.
+func (i *Info) TopologyString() string {
+	var b strings.Builder
+	for _, nic := range i.NICs {
+		if len(nic.Lower) == 0 {
+			writeNICTree(&b, nic, 0)
+		}
+	}
+	return b.String()
+}
+
+func writeNICTree(b *strings.Builder, nic *NIC, depth int) {
+	peerStr := ""
+	if nic.Peer != nil {
+		peerStr = ", peer " + nic.Peer.Name
+	}
+	vlanStr := ""
+	if nic.VLAN != nil {
+		vlanStr = fmt.Sprintf(" %d", nic.VLAN.ID)
+	}
+	fmt.Fprintf(b, "%s%s (%s%s%s)\n", strings.Repeat("  ", depth), nic.Name, nic.Kind, vlanStr, peerStr)
+	// the kernel nests devices up to 8 levels (MAX_NEST_DEV): going deeper
+	// means a loop, which only broken snapshots could have
+	if depth >= 8 {
+		return
+	}
+	for _, upper := range nic.Upper {
+		writeNICTree(b, upper, depth+1)
+	}
+}
+
 // simple private struct used to encapsulate net information in a
 // top-level "net" YAML/JSON map/object key
 type netPrinter struct {
@@ -6,21 +6,16 @@
 package net
 
 import (
-	"bufio"
-	"bytes"
-	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/jaypipes/ghw/pkg/context"
 	"github.com/jaypipes/ghw/pkg/linuxpath"
-)
-
-const (
-	_WARN_ETHTOOL_NOT_INSTALLED = `ethtool not installed. Cannot grab NIC capabilities`
+	"github.com/jaypipes/ghw/pkg/pci"
 )
 
 func (i *Info) load() error {
@@ -37,13 +32,8 @@ func nics(ctx *context.Context) []*NIC {
 		return nics
 	}
 
-	etAvailable := ctx.EnableTools
-	if etAvailable {
-		if etInstalled := ethtoolInstalled(); !etInstalled {
-			ctx.Warn(_WARN_ETHTOOL_NOT_INSTALLED)
-			etAvailable = false
-		}
-	}
+	et := newEthtool(ctx)
+	vlans := netVLANs(paths)
 
 	for _, file := range files {
 		filename := file.Name()
@@ -66,19 +56,57 @@ func nics(ctx *context.Context) []*NIC {
 
 		mac := netDeviceMacAddress(paths, filename)
 		nic.MacAddress = mac
-		if etAvailable {
-			nic.Capabilities = netDeviceCapabilities(ctx, filename)
-		} else {
-			nic.Capabilities = []*NICCapability{}
+		nic.Capabilities = []*NICCapability{}
+		if et != nil {
+			netDeviceFillEthtool(ctx, et, nic)
 		}
 
 		nic.PCIAddress = netDevicePCIAddress(paths.SysClassNet, filename)
+		nic.Link = netDeviceLink(paths, filename)
+		nic.Driver, nic.DriverVersion = netDeviceDriver(paths, filename)
+		nic.Queues = netDeviceQueues(paths, filename)
+		if nic.DriverInfo != nil {
+			// virtual NICs have no backing device, but ethtool reports their
+			// driver, e.g. "veth"
+			if nic.Driver == "" {
+				nic.Driver = nic.DriverInfo.Driver
+			}
+			nic.FirmwareVersion = nic.DriverInfo.FirmwareVersion
+		}
+		if nic.Queues != nil && nic.Channels != nil {
+			nic.Queues.Combined = int(nic.Channels.Combined)
+		}
+		nic.Kind = netDeviceKind(paths, nic, vlans)
+		netDeviceFillKind(paths, nic, vlans)
 
 		nics = append(nics, nic)
 	}
+	netFillPCIDevices(ctx, nics)
+	netLinkStackedNICs(paths, nics, vlans)
+	netFillAddresses(ctx, paths, nics)
 	return nics
 }
 
+// netFillPCIDevices sets the PCI device of the NICs which have a PCI address
+func netFillPCIDevices(ctx *context.Context, nics []*NIC) {
+	found := false
+	for _, nic := range nics {
+		found = found || nic.PCIAddress != nil
+	}
+	if !found {
+		return
+	}
+	pciInfo, err := pci.NewWithContext(ctx)
+	if err != nil {
+		return
+	}
+	for _, nic := range nics {
+		if nic.PCIAddress != nil {
+			nic.PCI = pciInfo.GetDevice(*nic.PCIAddress)
+		}
+	}
+}
+
 func netDeviceMacAddress(paths *linuxpath.Paths, dev string) string {
 	// Instead of use udevadm, we can get the device's MAC address by examing
 	// the /sys/class/net/$DEVICE/address file in sysfs. However, for devices
@@ -100,73 +128,87 @@ func netDeviceMacAddress(paths *linuxpath.Paths, dev string) string {
 	return strings.TrimSpace(string(contents))
 }
 
-func ethtoolInstalled() bool {
-	_, err := exec.LookPath("ethtool")
-	return err == nil
+// netDeviceLink returns the link state and settings of the network device,
+// from the attributes in the /sys/class/net/$DEVICE directory, or nil if the
+// device has no operstate attribute
+func netDeviceLink(paths *linuxpath.Paths, dev string) *NICLink {
+	devPath := filepath.Join(paths.SysClassNet, dev)
+	operState, err := ioutil.ReadFile(filepath.Join(devPath, "operstate"))
+	if err != nil {
+		return nil
+	}
+	// carrier, speed and duplex can't be read while the device is down, and
+	// phys_port_name can't be read if the driver doesn't name ports
+	duplex := netDeviceStringAttr(devPath, "duplex")
+	if duplex == "" {
+		duplex = "unknown"
+	}
+	speed, err := strconv.ParseInt(netDeviceStringAttr(devPath, "speed"), 10, 64)
+	if err != nil || speed < 0 || speed == math.MaxUint32 {
+		// SPEED_UNKNOWN, printed as an unsigned value by old kernels
+		speed = -1
+	}
+	return &NICLink{
+		Index:        netDeviceIntAttr(devPath, "ifindex", 0),
+		Type:         netDeviceIntAttr(devPath, "type", 0),
+		OperState:    strings.TrimSpace(string(operState)),
+		HasCarrier:   netDeviceIntAttr(devPath, "carrier", 0) == 1,
+		SpeedMbps:    int(speed),
+		Duplex:       duplex,
+		MTU:          netDeviceIntAttr(devPath, "mtu", 0),
+		TxQueueLen:   netDeviceIntAttr(devPath, "tx_queue_len", 0),
+		DevPort:      netDeviceIntAttr(devPath, "dev_port", 0),
+		PhysPortName: netDeviceStringAttr(devPath, "phys_port_name"),
+	}
+}
+
+// netDeviceStringAttr returns the trimmed content of the given attribute of
+// the network device, or an empty string if it can't be read
+func netDeviceStringAttr(devPath string, attr string) string {
+	contents, err := ioutil.ReadFile(filepath.Join(devPath, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(contents))
+}
+
+// netDeviceIntAttr returns the value of the given integer attribute of the
+// network device, or the supplied default value if it can't be read
+func netDeviceIntAttr(devPath string, attr string, defaultValue int) int {
+	value, err := strconv.Atoi(netDeviceStringAttr(devPath, attr))
+	if err != nil {
+		return defaultValue
+	}
+	return value
 }
 
-func netDeviceCapabilities(ctx *context.Context, dev string) []*NICCapability {
-	caps := make([]*NICCapability, 0)
-	path, _ := exec.LookPath("ethtool")
-	cmd := exec.Command(path, "-k", dev)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	err := cmd.Run()
+// netDeviceDriver returns the name of the driver bound to the device of the
+// network device, and the version of its kernel module. Both are empty if the
+// network device has no backing device, and the version is empty for the
+// built-in drivers and the modules which don't declare a version.
+func netDeviceDriver(paths *linuxpath.Paths, dev string) (string, string) {
+	driverPath := filepath.Join(paths.SysClassNet, dev, "device", "driver")
+	dest, err := os.Readlink(driverPath)
 	if err != nil {
-		msg := fmt.Sprintf("could not grab NIC capabilities for %s: %s", dev, err)
-		ctx.Warn(msg)
-		return caps
-	}
-
-	// The out variable will now contain something that looks like the
-	// following.
-	//
-	// Features for enp58s0f1:
-	// rx-checksumming: on
-	// tx-checksumming: off
-	//     tx-checksum-ipv4: off
-	//     tx-checksum-ip-generic: off [fixed]
-	//     tx-checksum-ipv6: off
-	//     tx-checksum-fcoe-crc: off [fixed]
-	//     tx-checksum-sctp: off [fixed]
-	// scatter-gather: off
-	//     tx-scatter-gather: off
-	//     tx-scatter-gather-fraglist: off [fixed]
-	// tcp-segmentation-offload: off
-	//     tx-tcp-segmentation: off
-	//     tx-tcp-ecn-segmentation: off [fixed]
-	//     tx-tcp-mangleid-segmentation: off
-	//     tx-tcp6-segmentation: off
-	// < snipped >
-	scanner := bufio.NewScanner(&out)
-	// Skip the first line...
-	scanner.Scan()
-	for scanner.Scan() {
-		line := strings.TrimPrefix(scanner.Text(), "\t")
-		caps = append(caps, netParseEthtoolFeature(line))
-	}
-	return caps
+		return "", ""
+	}
+	version := netDeviceStringAttr(filepath.Join(driverPath, "module"), "version")
+	return filepath.Base(dest), version
 }
 
-// netParseEthtoolFeature parses a line from the ethtool -k output and returns
-// a NICCapability.
-//
-// The supplied line will look like the following:
-//
-// tx-checksum-ip-generic: off [fixed]
-//
-// [fixed] indicates that the feature may not be turned on/off. Note: it makes
-// no difference whether a privileged user runs `ethtool -k` when determining
-// whether [fixed] appears for a feature.
-func netParseEthtoolFeature(line string) *NICCapability {
-	parts := strings.Fields(line)
-	cap := strings.TrimSuffix(parts[0], ":")
-	enabled := parts[1] == "on"
-	fixed := len(parts) == 3 && parts[2] == "[fixed]"
-	return &NICCapability{
-		Name:      cap,
-		IsEnabled: enabled,
-		CanEnable: !fixed,
+// netDeviceQueues returns the number of hardware queues of the network
+// device, from the queues/rx-N and queues/tx-N directories, or nil if it has
+// none
+func netDeviceQueues(paths *linuxpath.Paths, dev string) *NICQueues {
+	queuesPath := filepath.Join(paths.SysClassNet, dev, "queues")
+	rxQueues, _ := filepath.Glob(filepath.Join(queuesPath, "rx-*"))
+	txQueues, _ := filepath.Glob(filepath.Join(queuesPath, "tx-*"))
+	if len(rxQueues) == 0 && len(txQueues) == 0 {
+		return nil
+	}
+	return &NICQueues{
+		RX: len(rxQueues),
+		TX: len(txQueues),
 	}
 }
 
@@ -9,9 +9,17 @@
 package net
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	stdnet "net"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+
+	"github.com/jaypipes/ghw/pkg/option"
+	"github.com/jaypipes/ghw/testdata"
 )
 
 func TestParseEthtoolFeature(t *testing.T) {
@@ -47,6 +55,18 @@ func TestParseEthtoolFeature(t *testing.T) {
 				CanEnable: false,
 			},
 		},
+		{
+			line:     "",
+			expected: nil,
+		},
+		{
+			line:     "scatter-gather:",
+			expected: nil,
+		},
+		{
+			line:     "Features for eth0:",
+			expected: nil,
+		},
 	}
 
 	for x, test := range tests {
@@ -56,3 +76,583 @@ func TestParseEthtoolFeature(t *testing.T) {
 		}
 	}
 }
+
+// ethtoolFixture returns an ethtool runner that reads the recorded outputs of
+// the given driver
+func ethtoolFixture(t *testing.T, driver string) func(args ...string) ([]byte, error) {
+	ethtoolDir, err := testdata.EthtoolDirectory()
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	files := map[string]string{
+		"-k": "features.txt",
+		"-i": "driver.txt",
+		"-g": "ring.txt",
+		"-l": "channels.txt",
+		"-c": "coalesce.txt",
+	}
+	return func(args ...string) ([]byte, error) {
+		name, ok := files[args[0]]
+		if !ok {
+			name = "settings.txt"
+		}
+		return ioutil.ReadFile(filepath.Join(ethtoolDir, driver, name))
+	}
+}
+
+func TestEthtoolCommand(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_NET"); ok {
+		t.Skip("Skipping network tests.")
+	}
+
+	et := &ethtoolCommand{run: ethtoolFixture(t, "ixgbe")}
+
+	caps, err := et.Features("eth0")
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if len(caps) != 65 {
+		t.Fatalf("Expected 65 capabilities, got %d", len(caps))
+	}
+	expectedCap := &NICCapability{Name: "tx-checksum-ipv4", IsEnabled: false, CanEnable: false}
+	if !reflect.DeepEqual(expectedCap, caps[2]) {
+		t.Fatalf("Expected %v, got %v", expectedCap, caps[2])
+	}
+
+	info, err := et.DriverInfo("eth0")
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	expectedInfo := &NICDriverInfo{
+		Driver:          "ixgbe",
+		Version:         "6.8.0-45-generic",
+		FirmwareVersion: "0x800006da, 1.3177.0",
+		BusInfo:         "0000:03:00.0",
+	}
+	if !reflect.DeepEqual(expectedInfo, info) {
+		t.Fatalf("Expected %v, got %v", expectedInfo, info)
+	}
+
+	ring, err := et.RingParams("eth0")
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	expectedRing := &NICRingParams{RX: 512, RXMax: 8192, TX: 1024, TXMax: 8192}
+	if !reflect.DeepEqual(expectedRing, ring) {
+		t.Fatalf("Expected %v, got %v", expectedRing, ring)
+	}
+
+	channels, err := et.Channels("eth0")
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	expectedChannels := &NICChannels{Other: 1, OtherMax: 1, Combined: 12, CombinedMax: 63}
+	if !reflect.DeepEqual(expectedChannels, channels) {
+		t.Fatalf("Expected %v, got %v", expectedChannels, channels)
+	}
+
+	coalesce, err := et.Coalesce("eth0")
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	expectedCoalesce := &NICCoalesce{IsAdaptiveRX: true, RXUsecs: 1}
+	if !reflect.DeepEqual(expectedCoalesce, coalesce) {
+		t.Fatalf("Expected %v, got %v", expectedCoalesce, coalesce)
+	}
+
+	modes, err := et.LinkModes("eth0")
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	expectedModes := &NICLinkModes{
+		Supported:         []string{"1000baseT/Full", "10000baseSR/Full"},
+		Advertised:        []string{"10000baseSR/Full"},
+		IsAutoNegotiation: true,
+	}
+	if !reflect.DeepEqual(expectedModes, modes) {
+		t.Fatalf("Expected %v, got %v", expectedModes, modes)
+	}
+
+	// virtio_net doesn't report link modes, nor support coalescing settings
+	et = &ethtoolCommand{run: ethtoolFixture(t, "virtio_net")}
+	modes, err = et.LinkModes("eth0")
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	expectedModes = &NICLinkModes{Supported: []string{}, Advertised: []string{}}
+	if !reflect.DeepEqual(expectedModes, modes) {
+		t.Fatalf("Expected %v, got %v", expectedModes, modes)
+	}
+	if _, err := et.Coalesce("eth0"); err == nil {
+		t.Fatalf("Expected an error for the coalescing settings")
+	}
+}
+
+func TestEthtoolFeatureCapabilities(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_NET"); ok {
+		t.Skip("Skipping network tests.")
+	}
+
+	features := []ethtoolFeature{
+		{name: "tx-scatter-gather", isActive: true},
+		// the kernel leaves some slots of the string set unnamed
+		{name: "", isFixed: true},
+		{name: "tx-checksum-ipv4", isFixed: true},
+		{name: "tx-checksum-ip-generic", isActive: true},
+		{name: "rx-gro", isActive: true},
+		{name: "tx-scatter-gather-fraglist", isFixed: true},
+		{name: "highdma", isActive: true, isFixed: true},
+		{name: ""},
+	}
+	expected := []*NICCapability{
+		{Name: "tx-checksumming", IsEnabled: true, CanEnable: true},
+		{Name: "tx-checksum-ipv4", IsEnabled: false, CanEnable: false},
+		{Name: "tx-checksum-ip-generic", IsEnabled: true, CanEnable: true},
+		{Name: "scatter-gather", IsEnabled: true, CanEnable: true},
+		{Name: "tx-scatter-gather", IsEnabled: true, CanEnable: true},
+		{Name: "tx-scatter-gather-fraglist", IsEnabled: false, CanEnable: false},
+		{Name: "generic-receive-offload", IsEnabled: true, CanEnable: true},
+		{Name: "highdma", IsEnabled: true, CanEnable: false},
+	}
+	actual := ethtoolFeatureCapabilities(features)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %v, got %v", expected, actual)
+	}
+}
+
+func TestNICLink(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_NET"); ok {
+		t.Skip("Skipping network tests.")
+	}
+
+	// eth1 is down, so its carrier, speed and duplex can't be read
+	devDir := "sys/devices/pci0000:00/0000:00:1f.6/net"
+	files := map[string]string{
+		devDir + "/eth0/ifindex":        "2",
+		devDir + "/eth0/type":           "1",
+		devDir + "/eth0/operstate":      "up",
+		devDir + "/eth0/carrier":        "1",
+		devDir + "/eth0/speed":          "10000",
+		devDir + "/eth0/duplex":         "full",
+		devDir + "/eth0/mtu":            "9000",
+		devDir + "/eth0/tx_queue_len":   "1000",
+		devDir + "/eth0/dev_port":       "1",
+		devDir + "/eth0/phys_port_name": "p1",
+		devDir + "/eth1/ifindex":        "3",
+		devDir + "/eth1/type":           "1",
+		devDir + "/eth1/operstate":      "down",
+		devDir + "/eth1/mtu":            "1500",
+		devDir + "/eth1/tx_queue_len":   "1000",
+		devDir + "/eth1/dev_port":       "0",
+	}
+	links := map[string]string{
+		"sys/class/net/eth0": "../../devices/pci0000:00/0000:00:1f.6/net/eth0",
+		"sys/class/net/eth1": "../../devices/pci0000:00/0000:00:1f.6/net/eth1",
+	}
+	root := netTestTree(t, files, links)
+	defer os.RemoveAll(root)
+
+	info, err := New(option.WithChroot(root), option.WithDisableTools(), option.WithNullAlerter())
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if len(info.NICs) != 2 {
+		t.Fatalf("Expected 2 NICs, got %d", len(info.NICs))
+	}
+
+	expected := map[string]*NICLink{
+		"eth0": {
+			Index:        2,
+			Type:         1,
+			OperState:    "up",
+			HasCarrier:   true,
+			SpeedMbps:    10000,
+			Duplex:       "full",
+			MTU:          9000,
+			TxQueueLen:   1000,
+			DevPort:      1,
+			PhysPortName: "p1",
+		},
+		"eth1": {
+			Index:      3,
+			Type:       1,
+			OperState:  "down",
+			SpeedMbps:  -1,
+			Duplex:     "unknown",
+			MTU:        1500,
+			TxQueueLen: 1000,
+		},
+	}
+	for _, nic := range info.NICs {
+		if !reflect.DeepEqual(nic.Link, expected[nic.Name]) {
+			t.Errorf("Expected %s link %+v, got %+v", nic.Name, expected[nic.Name], nic.Link)
+		}
+	}
+}
+
+func TestNICDriverAndQueues(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_NET"); ok {
+		t.Skip("Skipping network tests.")
+	}
+
+	// eth0 is backed by a device bound to the ixgbe module, veth0 is virtual
+	files := map[string]string{
+		"sys/devices/pci0000:00/0000:03:00.0/net/eth0/queues/rx-0/rps_flow_cnt": "0",
+		"sys/devices/pci0000:00/0000:03:00.0/net/eth0/queues/rx-1/rps_flow_cnt": "0",
+		"sys/devices/pci0000:00/0000:03:00.0/net/eth0/queues/tx-0/tx_timeout":   "0",
+		"sys/devices/pci0000:00/0000:03:00.0/net/eth0/queues/tx-1/tx_timeout":   "0",
+		"sys/devices/pci0000:00/0000:03:00.0/net/eth0/queues/tx-2/tx_timeout":   "0",
+		"sys/devices/virtual/net/veth0/queues/rx-0/rps_flow_cnt":                "0",
+		"sys/devices/virtual/net/veth0/queues/tx-0/tx_timeout":                  "0",
+		"sys/module/ixgbe/version":                                              "5.19.6",
+	}
+	links := map[string]string{
+		"sys/class/net/eth0":  "../../devices/pci0000:00/0000:03:00.0/net/eth0",
+		"sys/class/net/veth0": "../../devices/virtual/net/veth0",
+		"sys/devices/pci0000:00/0000:03:00.0/net/eth0/device": "../../../0000:03:00.0",
+		"sys/devices/pci0000:00/0000:03:00.0/driver":          "../../../bus/pci/drivers/ixgbe",
+		"sys/bus/pci/drivers/ixgbe/module":                    "../../../../module/ixgbe",
+	}
+	root := netTestTree(t, files, links)
+	defer os.RemoveAll(root)
+
+	info, err := New(option.WithChroot(root), option.WithDisableTools(), option.WithNullAlerter())
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if len(info.NICs) != 2 {
+		t.Fatalf("Expected 2 NICs, got %d", len(info.NICs))
+	}
+
+	for _, nic := range info.NICs {
+		switch nic.Name {
+		case "eth0":
+			if nic.Driver != "ixgbe" || nic.DriverVersion != "5.19.6" {
+				t.Errorf("Expected eth0 driver ixgbe 5.19.6, got %q %q", nic.Driver, nic.DriverVersion)
+			}
+			expected := &NICQueues{RX: 2, TX: 3}
+			if !reflect.DeepEqual(nic.Queues, expected) {
+				t.Errorf("Expected eth0 queues %+v, got %+v", expected, nic.Queues)
+			}
+		case "veth0":
+			if nic.Driver != "" || nic.DriverVersion != "" {
+				t.Errorf("Expected no veth0 driver, got %q %q", nic.Driver, nic.DriverVersion)
+			}
+			expected := &NICQueues{RX: 1, TX: 1}
+			if !reflect.DeepEqual(nic.Queues, expected) {
+				t.Errorf("Expected veth0 queues %+v, got %+v", expected, nic.Queues)
+			}
+		}
+	}
+}
+
+func TestNICStacking(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_NET"); ok {
+		t.Skip("Skipping network tests.")
+	}
+
+	// eth0 and eth1 are bonded, the bond is a port of br0 and br0.100 is a
+	// VLAN on br0. eth2, eth3 and eth4 are the representors of virtual
+	// functions: of the fourth virtual function of the physical function
+	// 0000:03:00.0, whose second one is not listed, of one of another host
+	// and of one of another physical function.
+	pciDev := "sys/devices/pci0000:00/0000:03:00.0"
+	pciNet := pciDev + "/net"
+	virtNet := "sys/devices/virtual/net"
+	files := map[string]string{
+		"usr/share/hwdata/pci.ids": "8086  Intel Corporation\n\t10fb  82599ES 10-Gigabit SFI/SFP+ Network Connection\n" +
+			"\t10ed  82599 Ethernet Controller Virtual Function\nC 02  Network controller\n\t00  Ethernet controller",
+		pciDev + "/modalias":                        "pci:v00008086d000010FBsv00008086sd00000003bc02sc00i00\n",
+		pciDev + "/sriov_totalvfs":                  "4",
+		pciDev + "/sriov_numvfs":                    "4",
+		pciNet + "/eth0/ifindex":                    "2",
+		pciNet + "/eth1/ifindex":                    "3",
+		pciNet + "/eth2/ifindex":                    "4",
+		pciNet + "/eth2/phys_port_name":             "pf0vf3",
+		pciNet + "/eth3/ifindex":                    "8",
+		pciNet + "/eth3/phys_port_name":             "c1pf0vf3",
+		pciNet + "/eth4/ifindex":                    "9",
+		pciNet + "/eth4/phys_port_name":             "pf1vf0",
+		virtNet + "/bond0/ifindex":                  "5",
+		virtNet + "/bond0/bonding/mode":             "802.3ad 4",
+		virtNet + "/bond0/bonding/slaves":           "eth0 eth1",
+		virtNet + "/bond0/bonding/xmit_hash_policy": "layer3+4 1",
+		virtNet + "/br0/ifindex":                    "6",
+		virtNet + "/br0/bridge/stp_state":           "0",
+		virtNet + "/br0.100/ifindex":                "7",
+		virtNet + "/br0.100/uevent":                 "DEVTYPE=vlan\nINTERFACE=br0.100\nIFINDEX=7",
+		"proc/net/vlan/config":                      "VLAN Dev name	 | VLAN ID\nName-Type: VLAN_NAME_TYPE_RAW_PLUS_VID_NO_PAD\nbr0.100        | 100  | br0\n",
+	}
+	links := map[string]string{
+		"sys/class/net/eth0":               "../../devices/pci0000:00/0000:03:00.0/net/eth0",
+		"sys/class/net/eth1":               "../../devices/pci0000:00/0000:03:00.0/net/eth1",
+		"sys/class/net/bond0":              "../../devices/virtual/net/bond0",
+		"sys/class/net/br0":                "../../devices/virtual/net/br0",
+		"sys/class/net/br0.100":            "../../devices/virtual/net/br0.100",
+		pciNet + "/eth0/master":            "../../../../virtual/net/bond0",
+		pciNet + "/eth1/master":            "../../../../virtual/net/bond0",
+		virtNet + "/bond0/master":          "../br0",
+		virtNet + "/br0/brif/bond0":        "../../bond0/brport",
+		virtNet + "/br0.100/lower_br0":     "../br0",
+		"sys/bus/pci/devices/0000:03:00.0": "../../../devices/pci0000:00/0000:03:00.0",
+		pciDev + "/subsystem":              "../../../bus/pci",
+	}
+	for _, name := range []string{"eth2", "eth3", "eth4"} {
+		links["sys/class/net/"+name] = "../../devices/pci0000:00/0000:03:00.0/net/" + name
+		links[pciNet+"/"+name+"/device"] = "../../../0000:03:00.0"
+	}
+	for vf := 0; vf < 4; vf++ {
+		addr := fmt.Sprintf("0000:03:02.%d", vf)
+		links[fmt.Sprintf("%s/virtfn%d", pciDev, vf)] = "../" + addr
+		if vf == 1 {
+			continue
+		}
+		files["sys/devices/pci0000:00/"+addr+"/modalias"] = "pci:v00008086d000010EDsv00008086sd00000003bc02sc00i00\n"
+		links["sys/bus/pci/devices/"+addr] = "../../../devices/pci0000:00/" + addr
+	}
+	root := netTestTree(t, files, links)
+	defer os.RemoveAll(root)
+
+	info, err := New(option.WithChroot(root), option.WithDisableTools(), option.WithNullAlerter())
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	nics := make(map[string]*NIC)
+	for _, nic := range info.NICs {
+		nics[nic.Name] = nic
+	}
+	if len(nics) != 8 {
+		t.Fatalf("Expected 8 NICs, got %d", len(nics))
+	}
+
+	expectedKinds := map[string]NICKind{
+		"eth0":    NIC_KIND_PHYSICAL,
+		"eth1":    NIC_KIND_PHYSICAL,
+		"eth2":    NIC_KIND_REPRESENTOR,
+		"eth3":    NIC_KIND_REPRESENTOR,
+		"eth4":    NIC_KIND_REPRESENTOR,
+		"bond0":   NIC_KIND_BOND,
+		"br0":     NIC_KIND_BRIDGE,
+		"br0.100": NIC_KIND_VLAN,
+	}
+	for name, kind := range expectedKinds {
+		if nics[name].Kind != kind {
+			t.Errorf("Expected %s to be a %s, got %s", name, kind, nics[name].Kind)
+		}
+	}
+
+	bond := nics["bond0"]
+	if !reflect.DeepEqual(nicNames(bond.Lower), []string{"eth0", "eth1"}) {
+		t.Errorf("Expected bond0 slaves eth0 and eth1, got %v", nicNames(bond.Lower))
+	}
+	if bond.Master != nics["br0"] || nics["eth0"].Master != bond {
+		t.Errorf("Expected eth0 to be a slave of bond0, itself a port of br0")
+	}
+	expectedBond := &NICBond{Mode: "802.3ad", TransmitHashPolicy: "layer3+4"}
+	if !reflect.DeepEqual(bond.Bond, expectedBond) {
+		t.Errorf("Expected bond %+v, got %+v", expectedBond, bond.Bond)
+	}
+	if !reflect.DeepEqual(nicNames(nics["br0"].Lower), []string{"bond0"}) {
+		t.Errorf("Expected br0 ports bond0, got %v", nicNames(nics["br0"].Lower))
+	}
+	vlan := nics["br0.100"]
+	if !reflect.DeepEqual(nicNames(vlan.Lower), []string{"br0"}) || vlan.VLAN == nil || vlan.VLAN.ID != 100 {
+		t.Errorf("Expected br0.100 to be VLAN 100 of br0, got %v %+v", nicNames(vlan.Lower), vlan.VLAN)
+	}
+	expectedReprs := map[string]struct {
+		controller, pfIndex, vfIndex int
+		vf                           string
+	}{
+		"eth2": {-1, 0, 3, "0000:03:02.3"},
+		"eth3": {1, 0, 3, ""},
+		"eth4": {-1, 1, 0, ""},
+	}
+	for name, expected := range expectedReprs {
+		repr := nics[name].Representor
+		if repr == nil || repr.Controller != expected.controller || repr.PFIndex != expected.pfIndex || repr.VFIndex != expected.vfIndex {
+			t.Errorf("Expected %s to represent %+v, got %+v", name, expected, repr)
+			continue
+		}
+		vf := ""
+		if repr.VF != nil {
+			vf = repr.VF.Address
+		}
+		if vf != expected.vf {
+			t.Errorf("Expected %s to represent the virtual function %q, got %q", name, expected.vf, vf)
+		}
+	}
+
+	expectedTopology := `eth0 (Physical)
+  bond0 (Bond)
+    br0 (Bridge)
+      br0.100 (VLAN 100)
+eth1 (Physical)
+  bond0 (Bond)
+    br0 (Bridge)
+      br0.100 (VLAN 100)
+eth2 (Representor)
+eth3 (Representor)
+eth4 (Representor)
+`
+	if topology := info.TopologyString(); topology != expectedTopology {
+		t.Errorf("Expected topology:\n%s\ngot:\n%s", expectedTopology, topology)
+	}
+
+	// the related NICs are serialized by name
+	data, err := json.Marshal(bond)
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	var actual struct {
+		Kind   string   `json:"kind"`
+		Master string   `json:"master"`
+		Lower  []string `json:"lower"`
+	}
+	if err := json.Unmarshal(data, &actual); err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if actual.Kind != "bond" || actual.Master != "br0" || !reflect.DeepEqual(actual.Lower, []string{"eth0", "eth1"}) {
+		t.Errorf("Expected bond0 serialized with its master and slaves, got %s", data)
+	}
+}
+
+func TestParseVLANConfig(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_NET"); ok {
+		t.Skip("Skipping network tests.")
+	}
+
+	config := `VLAN Dev name	 | VLAN ID
+Name-Type: VLAN_NAME_TYPE_RAW_PLUS_VID_NO_PAD
+eth0.100       | 100  | eth0
+vlan4094       | 4094  | bond0
+`
+	expected := map[string]vlanEntry{
+		"eth0.100": {id: 100, parent: "eth0"},
+		"vlan4094": {id: 4094, parent: "bond0"},
+	}
+	if actual := parseVLANConfig(config); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("Expected %v, got %v", expected, actual)
+	}
+}
+
+func TestNICAddresses(t *testing.T) {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_NET"); ok {
+		t.Skip("Skipping network tests.")
+	}
+
+	// the routes are printed in the byte order of the host
+	routeIPv4 := func(ip string) string {
+		return fmt.Sprintf("%08X", nativeEndian.Uint32(stdnet.ParseIP(ip).To4()))
+	}
+	files := map[string]string{
+		"sys/devices/virtual/net/eth0/ifindex": "2",
+		"proc/net/fib_trie": `Main:
+  +-- 0.0.0.0/0 3 0 5
+     |-- 0.0.0.0
+        /0 universe UNICAST
+     +-- 127.0.0.0/8 2 0 2
+        +-- 127.0.0.0/31 1 0 0
+           |-- 127.0.0.0
+              /8 host LOCAL
+           |-- 127.0.0.1
+              /32 host LOCAL
+        |-- 127.255.255.255
+           /32 link BROADCAST
+     +-- 192.0.2.0/24 2 0 2
+        +-- 192.0.2.0/30 2 0 2
+           |-- 192.0.2.0
+              /24 link UNICAST
+           |-- 192.0.2.2
+              /32 host LOCAL
+        |-- 192.0.2.255
+           /32 link BROADCAST
+Local:
+  +-- 192.0.2.0/24 2 0 2
+     |-- 192.0.2.2
+        /32 host LOCAL
+`,
+		"proc/net/route": "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+			"eth0\t00000000\t" + routeIPv4("192.0.2.1") + "\t0003\t0\t0\t100\t00000000\t0\t0\t0\n" +
+			"eth0\t" + routeIPv4("192.0.2.0") + "\t00000000\t0001\t0\t0\t100\t" + routeIPv4("255.255.255.0") + "\t0\t0\t0\n",
+		"proc/net/if_inet6": `00000000000000000000000000000001 01 80 10 80       lo
+fe8000000000000000fc00fffe000001 02 40 20 80     eth0
+fd000000000000000000000000000002 02 40 00 80     eth0
+`,
+		"proc/net/ipv6_route": `fd000000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0
+00000000000000000000000000000000 00 00000000000000000000000000000000 00 fd000000000000000000000000000001 00000400 00000001 00000000 00000003     eth0
+fd000000000000000000000000000002 80 00000000000000000000000000000000 00 00000000000000000000000000000000 00000000 00000002 00000000 80200001     eth0
+ff000000000000000000000000000000 08 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000004 00000000 00000001     eth0
+`,
+	}
+	links := map[string]string{
+		"sys/class/net/eth0": "../../devices/virtual/net/eth0",
+	}
+	root := netTestTree(t, files, links)
+	defer os.RemoveAll(root)
+
+	// addresses are not reported by default
+	info, err := New(option.WithChroot(root), option.WithDisableTools(), option.WithNullAlerter())
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if len(info.NICs) != 1 || info.NICs[0].Addresses != nil || info.NICs[0].Routes != nil {
+		t.Fatalf("Expected a NIC without addresses nor routes, got %+v", info.NICs)
+	}
+
+	info, err = New(
+		option.WithChroot(root),
+		option.WithDisableTools(),
+		option.WithNullAlerter(),
+		option.WithNetOptions(option.NetOptions{IncludeAddresses: true}),
+	)
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	nic := info.NICs[0]
+	expectedAddrs := []*NICAddress{
+		{Address: "192.0.2.2", PrefixLength: 24, Scope: "global"},
+		{Address: "fe80::fc:ff:fe00:1", PrefixLength: 64, Scope: "link"},
+		{Address: "fd00::2", PrefixLength: 64, Scope: "global"},
+	}
+	if !reflect.DeepEqual(expectedAddrs, nic.Addresses) {
+		t.Errorf("Expected addresses %v, got %v", expectedAddrs, nic.Addresses)
+	}
+	expectedRoutes := []*NICRoute{
+		{Destination: "0.0.0.0/0", Gateway: "192.0.2.1", Metric: 100},
+		{Destination: "192.0.2.0/24", Metric: 100},
+		{Destination: "fd00::/64", Metric: 256},
+		{Destination: "::/0", Gateway: "fd00::1", Metric: 1024},
+	}
+	if !reflect.DeepEqual(expectedRoutes, nic.Routes) {
+		t.Errorf("Expected routes %v, got %v", expectedRoutes, nic.Routes)
+	}
+}
+
+// netTestTree creates a fake sysfs and procfs tree in a temporary directory,
+// with the given files (path -> contents) and symlinks (path -> destination),
+// and returns its root. The caller is responsible for removing it.
+func netTestTree(t *testing.T, files map[string]string, links map[string]string) string {
+	root, err := ioutil.TempDir("", "ghw-net-testing-*")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	for path, contents := range files {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("Unable to create %q: %v", filepath.Dir(path), err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("Unable to write %q: %v", path, err)
+		}
+	}
+	for path, dest := range links {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatalf("Unable to create %q: %v", filepath.Dir(path), err)
+		}
+		if err := os.Symlink(dest, path); err != nil {
+			t.Fatalf("Unable to link %q: %v", path, err)
+		}
+	}
+	return root
+}
@@ -0,0 +1,285 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package net
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jaypipes/ghw/pkg/linuxpath"
+	"github.com/jaypipes/ghw/pkg/pci"
+	pciaddr "github.com/jaypipes/ghw/pkg/pci/address"
+)
+
+var (
+	// the phys_port_name of the representors of the virtual functions, e.g.
+	// "pf0vf3", with the controller number on multi-host devices, e.g.
+	// "c1pf0vf3"
+	representorPortName = regexp.MustCompile(`^(?:c(\d+))?pf(\d+)vf(\d+)$`)
+
+	// the kinds of the virtual NICs sysfs doesn't tell apart, by the name of
+	// their driver as reported by ethtool
+	nicKindByDriver = map[string]NICKind{
+		"bonding":             NIC_KIND_BOND,
+		"bridge":              NIC_KIND_BRIDGE,
+		"802.1Q VLAN Support": NIC_KIND_VLAN,
+		"macvlan":             NIC_KIND_MACVLAN,
+		"macvtap":             NIC_KIND_MACVLAN,
+		"ipvlan":              NIC_KIND_IPVLAN,
+		"ipvtap":              NIC_KIND_IPVLAN,
+		"veth":                NIC_KIND_VETH,
+		"team":                NIC_KIND_TEAM,
+	}
+)
+
+// vlanEntry is an entry of /proc/net/vlan/config
+type vlanEntry struct {
+	id     int
+	parent string
+}
+
+// netVLANs returns the VLAN interfaces listed in /proc/net/vlan/config, by
+// name. The file only exists once the 8021q module is loaded.
+func netVLANs(paths *linuxpath.Paths) map[string]vlanEntry {
+	data, err := ioutil.ReadFile(paths.ProcNetVLANConfig)
+	if err != nil {
+		return map[string]vlanEntry{}
+	}
+	return parseVLANConfig(string(data))
+}
+
+// parseVLANConfig parses the content of /proc/net/vlan/config, which looks
+// like the following:
+//
+// VLAN Dev name	 | VLAN ID
+// Name-Type: VLAN_NAME_TYPE_RAW_PLUS_VID_NO_PAD
+// eth0.100       | 100  | eth0
+func parseVLANConfig(data string) map[string]vlanEntry {
+	vlans := make(map[string]vlanEntry)
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "|")
+		if len(fields) != 3 {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		if err != nil {
+			continue
+		}
+		vlans[strings.TrimSpace(fields[0])] = vlanEntry{
+			id:     id,
+			parent: strings.TrimSpace(fields[2]),
+		}
+	}
+	return vlans
+}
+
+// netDeviceKind returns the kind of the network device. Bonds, bridges and
+// VLANs are recognized from sysfs, the other kinds of virtual devices only
+// from the name of their driver, which ethtool reports for the running
+// system only.
+func netDeviceKind(paths *linuxpath.Paths, nic *NIC, vlans map[string]vlanEntry) NICKind {
+	devPath := filepath.Join(paths.SysClassNet, nic.Name)
+	if _, err := os.Stat(filepath.Join(devPath, "bonding")); err == nil {
+		return NIC_KIND_BOND
+	}
+	if _, err := os.Stat(filepath.Join(devPath, "bridge")); err == nil {
+		return NIC_KIND_BRIDGE
+	}
+	if _, ok := vlans[nic.Name]; ok {
+		return NIC_KIND_VLAN
+	}
+	switch netDeviceDevType(devPath) {
+	case "bond":
+		return NIC_KIND_BOND
+	case "bridge":
+		return NIC_KIND_BRIDGE
+	case "vlan":
+		return NIC_KIND_VLAN
+	}
+	if representorPortName.MatchString(netDeviceStringAttr(devPath, "phys_port_name")) {
+		return NIC_KIND_REPRESENTOR
+	}
+	if kind, ok := nicKindByDriver[nic.Driver]; ok {
+		return kind
+	}
+	if !nic.IsVirtual {
+		return NIC_KIND_PHYSICAL
+	}
+	return NIC_KIND_VIRTUAL
+}
+
+// netDeviceDevType returns the DEVTYPE of the uevent attribute of the network
+// device, e.g. "bridge" or "vlan", or an empty string if it has none
+func netDeviceDevType(devPath string) string {
+	for _, line := range strings.Split(netDeviceStringAttr(devPath, "uevent"), "\n") {
+		if strings.HasPrefix(line, "DEVTYPE=") {
+			return strings.TrimPrefix(line, "DEVTYPE=")
+		}
+	}
+	return ""
+}
+
+// netDeviceFillKind sets the settings specific to the kind of the NIC
+func netDeviceFillKind(paths *linuxpath.Paths, nic *NIC, vlans map[string]vlanEntry) {
+	devPath := filepath.Join(paths.SysClassNet, nic.Name)
+	switch nic.Kind {
+	case NIC_KIND_BOND:
+		// the attributes include the number of the setting, e.g.
+		// "802.3ad 4"
+		nic.Bond = &NICBond{
+			Mode:               firstField(netDeviceStringAttr(devPath, "bonding/mode")),
+			TransmitHashPolicy: firstField(netDeviceStringAttr(devPath, "bonding/xmit_hash_policy")),
+		}
+	case NIC_KIND_VLAN:
+		if vlan, ok := vlans[nic.Name]; ok {
+			nic.VLAN = &NICVLAN{ID: vlan.id}
+		}
+	case NIC_KIND_REPRESENTOR:
+		m := representorPortName.FindStringSubmatch(netDeviceStringAttr(devPath, "phys_port_name"))
+		controller := -1
+		if m[1] != "" {
+			controller, _ = strconv.Atoi(m[1])
+		}
+		pfIndex, _ := strconv.Atoi(m[2])
+		vfIndex, _ := strconv.Atoi(m[3])
+		nic.Representor = &NICRepresentor{
+			Controller: controller,
+			PFIndex:    pfIndex,
+			VFIndex:    vfIndex,
+		}
+	}
+}
+
+func firstField(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// netLinkStackedNICs sets the relationships between the NICs: the master and
+// the lower and upper NICs of each NIC, the peers of the veths and the
+// virtual functions of the representors. Must be called once the kind and
+// the PCI device of the NICs are known.
+func netLinkStackedNICs(paths *linuxpath.Paths, nics []*NIC, vlans map[string]vlanEntry) {
+	byName := make(map[string]*NIC, len(nics))
+	byIndex := make(map[int]*NIC, len(nics))
+	for _, nic := range nics {
+		byName[nic.Name] = nic
+		if nic.Link != nil {
+			byIndex[nic.Link.Index] = nic
+		}
+	}
+	for _, nic := range nics {
+		devPath := filepath.Join(paths.SysClassNet, nic.Name)
+		if dest, err := os.Readlink(filepath.Join(devPath, "master")); err == nil {
+			nic.Master = byName[filepath.Base(dest)]
+		}
+		for _, name := range netDeviceLowerNames(devPath, nic, vlans, byIndex) {
+			if lower, ok := byName[name]; ok && lower != nic {
+				nic.Lower = append(nic.Lower, lower)
+				lower.Upper = append(lower.Upper, nic)
+			}
+		}
+		if nic.Kind == NIC_KIND_VETH && nic.Link != nil {
+			// iflink is the index of the peer in the namespace of the peer,
+			// so make sure the NIC found is a veth pointing back at us
+			peer, ok := byIndex[netDeviceIntAttr(devPath, "iflink", 0)]
+			if ok && peer != nic && peer.Kind == NIC_KIND_VETH && peer.Link != nil &&
+				netDeviceIntAttr(filepath.Join(paths.SysClassNet, peer.Name), "iflink", 0) == nic.Link.Index {
+				nic.Peer = peer
+			}
+		}
+		if nic.Representor != nil {
+			nic.Representor.VF = netRepresentedVF(paths, nic)
+		}
+	}
+	for _, nic := range nics {
+		sortNICs(nic.Lower)
+		sortNICs(nic.Upper)
+	}
+}
+
+// netRepresentedVF returns the PCI device of the virtual function the
+// representor stands for. The representors are attached to the device of the
+// physical function, whose virtfnN links point to its virtual functions. The
+// virtual functions of the other hosts of multi-host devices, and those of
+// the other physical functions, are not found this way: nil is returned.
+func netRepresentedVF(paths *linuxpath.Paths, nic *NIC) *pci.Device {
+	repr := nic.Representor
+	if repr.Controller >= 0 || nic.PCI == nil || nic.PCI.SRIOV == nil {
+		return nil
+	}
+	addr := pciaddr.FromString(nic.PCI.Address)
+	if addr == nil {
+		return nil
+	}
+	if function, err := strconv.ParseInt(addr.Function, 16, 0); err != nil || int(function) != repr.PFIndex {
+		return nil
+	}
+	virtFn := filepath.Join(paths.SysBusPciDevices, nic.PCI.Address, "virtfn"+strconv.Itoa(repr.VFIndex))
+	dest, err := os.Readlink(virtFn)
+	if err != nil {
+		return nil
+	}
+	for _, vf := range nic.PCI.SRIOV.VirtualFunctions {
+		if vf.Address == filepath.Base(dest) {
+			return vf
+		}
+	}
+	return nil
+}
+
+// netDeviceLowerNames returns the names of the NICs the network device is
+// stacked on: the slaves of a bond, the ports of a bridge, the parent of a
+// VLAN, MACVLAN or IPVLAN interface, and otherwise the NICs the kernel lists
+// as lower devices. Lower devices in other network namespaces are not listed.
+func netDeviceLowerNames(devPath string, nic *NIC, vlans map[string]vlanEntry, byIndex map[int]*NIC) []string {
+	switch nic.Kind {
+	case NIC_KIND_BOND:
+		return strings.Fields(netDeviceStringAttr(devPath, "bonding/slaves"))
+	case NIC_KIND_BRIDGE:
+		if entries, err := ioutil.ReadDir(filepath.Join(devPath, "brif")); err == nil {
+			names := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			return names
+		}
+	case NIC_KIND_VLAN:
+		if vlan, ok := vlans[nic.Name]; ok {
+			return []string{vlan.parent}
+		}
+	}
+	names := make([]string, 0)
+	matches, _ := filepath.Glob(filepath.Join(devPath, "lower_*"))
+	for _, match := range matches {
+		names = append(names, strings.TrimPrefix(filepath.Base(match), "lower_"))
+	}
+	if len(names) == 0 && (nic.Kind == NIC_KIND_MACVLAN || nic.Kind == NIC_KIND_IPVLAN) && nic.Link != nil {
+		// kernels older than 3.14 have no lower_* links, but iflink is the
+		// index of the parent
+		iflink := netDeviceIntAttr(devPath, "iflink", 0)
+		if parent, ok := byIndex[iflink]; ok && iflink != nic.Link.Index {
+			names = append(names, parent.Name)
+		}
+	}
+	return names
+}
+
+func sortNICs(nics []*NIC) {
+	sort.Slice(nics, func(x, y int) bool {
+		return nics[x].Name < nics[y].Name
+	})
+}
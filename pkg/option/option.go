@@ -133,6 +133,15 @@ type Option struct {
 	// PathOverrides optionally allows to override the default paths ghw uses internally
 	// to learn about the system resources.
 	PathOverrides PathOverrides
+
+	// Block contains options for the discovery of block storage
+	Block *BlockOptions
+
+	// Net contains options for the discovery of network interfaces
+	Net *NetOptions
+
+	// PCI contains options for the discovery of PCI devices
+	PCI *PCIOptions
 }
 
 // SnapshotOptions contains options for handling of ghw snapshots
@@ -156,6 +165,51 @@ type SnapshotOptions struct {
 	Exclusive bool
 }
 
+// BlockOptions contains options for the discovery of block storage
+type BlockOptions struct {
+	// IncludePseudoDevices tells ghw to report the pseudo block devices,
+	// which are not backed by storage hardware: the loop devices, the RAM
+	// disks and the compressed RAM disks (zram). Loop devices are skipped
+	// and the others are reported as regular disks by default.
+	IncludePseudoDevices bool
+	// ReadPartitionTables tells ghw to read the partition identifiers from
+	// the partition tables of the disks when udev doesn't know about some
+	// partitions. Reading the device files of the disks usually requires
+	// root privileges, so it is not done by default.
+	ReadPartitionTables bool
+	// ReadSMARTData tells ghw to read the SMART data of the disks with
+	// smartctl, when tools are enabled and ghw runs as root. Running
+	// smartctl on every disk is slow, so it is not done by default.
+	ReadSMARTData bool
+	// IncludeFilesystemUsage tells ghw to report the capacity, used and free
+	// bytes of the filesystems mounted from partitions. The usage is read
+	// with statfs, which may block on unresponsive network or FUSE mounts,
+	// so it is not read by default. Only available for the running system.
+	IncludeFilesystemUsage bool
+}
+
+// NetOptions contains options for the discovery of network interfaces
+type NetOptions struct {
+	// IncludeAddresses tells ghw to report the IP addresses and the routes
+	// of the network interfaces. They identify the host and its network, so
+	// they are not reported by default.
+	IncludeAddresses bool
+}
+
+// PCIOptions contains options for the discovery of PCI devices
+type PCIOptions struct {
+	// IncludeCandidateDrivers tells ghw to report the kernel modules able to
+	// drive each PCI device. Finding them means matching every device
+	// against all the module aliases of the kernel, so they are not reported
+	// by default.
+	IncludeCandidateDrivers bool
+	// IncludeCapabilities tells ghw to report the capabilities listed in the
+	// configuration space of each PCI device. Reading and walking the
+	// configuration space of every device is costly, and requires root
+	// privileges, so they are not reported by default.
+	IncludeCapabilities bool
+}
+
 // WithChroot allows to override the root directory ghw uses.
 func WithChroot(dir string) *Option {
 	return &Option{Chroot: &dir}
@@ -188,6 +242,27 @@ func WithDisableTools() *Option {
 	return &Option{EnableTools: &false_}
 }
 
+// WithBlockOptions sets options for the discovery of block storage
+func WithBlockOptions(opts BlockOptions) *Option {
+	return &Option{
+		Block: &opts,
+	}
+}
+
+// WithNetOptions sets options for the discovery of network interfaces
+func WithNetOptions(opts NetOptions) *Option {
+	return &Option{
+		Net: &opts,
+	}
+}
+
+// WithPCIOptions sets options for the discovery of PCI devices
+func WithPCIOptions(opts PCIOptions) *Option {
+	return &Option{
+		PCI: &opts,
+	}
+}
+
 // PathOverrides is a map, keyed by the string name of a mount path, of override paths
 type PathOverrides map[string]string
 
@@ -221,6 +296,15 @@ func Merge(opts ...*Option) *Option {
 		if opt.PathOverrides != nil {
 			merged.PathOverrides = opt.PathOverrides
 		}
+		if opt.Block != nil {
+			merged.Block = opt.Block
+		}
+		if opt.Net != nil {
+			merged.Net = opt.Net
+		}
+		if opt.PCI != nil {
+			merged.PCI = opt.PCI
+		}
 	}
 	// Set the default value if missing from mergeOpts
 	if merged.Chroot == nil {
@@ -242,5 +326,14 @@ func Merge(opts ...*Option) *Option {
 		enabled := EnvOrDefaultTools()
 		merged.EnableTools = &enabled
 	}
+	if merged.Block == nil {
+		merged.Block = &BlockOptions{}
+	}
+	if merged.Net == nil {
+		merged.Net = &NetOptions{}
+	}
+	if merged.PCI == nil {
+		merged.PCI = &PCIOptions{}
+	}
 	return merged
 }
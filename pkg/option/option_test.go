@@ -150,6 +150,84 @@ func TestOption(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "chroot and block pseudo devices",
+			opts: []*option.Option{
+				option.WithChroot("/my/chroot/dir"),
+				option.WithBlockOptions(option.BlockOptions{
+					IncludePseudoDevices:   true,
+					ReadPartitionTables:    true,
+					ReadSMARTData:          true,
+					IncludeFilesystemUsage: true,
+				}),
+			},
+			merged: &option.Option{
+				Chroot: stringPtr("/my/chroot/dir"),
+				Block: &option.BlockOptions{
+					IncludePseudoDevices:   true,
+					ReadPartitionTables:    true,
+					ReadSMARTData:          true,
+					IncludeFilesystemUsage: true,
+				},
+			},
+		},
+		{
+			name: "block defaults",
+			opts: []*option.Option{
+				option.WithChroot("/my/chroot/dir"),
+			},
+			merged: &option.Option{
+				Chroot: stringPtr("/my/chroot/dir"),
+				Block:  &option.BlockOptions{},
+				Net:    &option.NetOptions{},
+				PCI:    &option.PCIOptions{},
+			},
+		},
+		{
+			name: "chroot and net addresses",
+			opts: []*option.Option{
+				option.WithChroot("/my/chroot/dir"),
+				option.WithNetOptions(option.NetOptions{
+					IncludeAddresses: true,
+				}),
+			},
+			merged: &option.Option{
+				Chroot: stringPtr("/my/chroot/dir"),
+				Net: &option.NetOptions{
+					IncludeAddresses: true,
+				},
+			},
+		},
+		{
+			name: "chroot and PCI candidate drivers",
+			opts: []*option.Option{
+				option.WithChroot("/my/chroot/dir"),
+				option.WithPCIOptions(option.PCIOptions{
+					IncludeCandidateDrivers: true,
+				}),
+			},
+			merged: &option.Option{
+				Chroot: stringPtr("/my/chroot/dir"),
+				PCI: &option.PCIOptions{
+					IncludeCandidateDrivers: true,
+				},
+			},
+		},
+		{
+			name: "chroot and PCI capabilities",
+			opts: []*option.Option{
+				option.WithChroot("/my/chroot/dir"),
+				option.WithPCIOptions(option.PCIOptions{
+					IncludeCapabilities: true,
+				}),
+			},
+			merged: &option.Option{
+				Chroot: stringPtr("/my/chroot/dir"),
+				PCI: &option.PCIOptions{
+					IncludeCapabilities: true,
+				},
+			},
+		},
 	}
 	for _, optTCase := range optTCases {
 		t.Run(optTCase.name, func(t *testing.T) {
@@ -181,6 +259,42 @@ func optionEqual(a, b *option.Option) (string, bool) {
 			return "chroot value", false
 		}
 	}
+	if a.Block != nil {
+		if b.Block == nil {
+			return "block ptr", false
+		}
+		if a.Block.IncludePseudoDevices != b.Block.IncludePseudoDevices {
+			return "block pseudo devices flag", false
+		}
+		if a.Block.ReadPartitionTables != b.Block.ReadPartitionTables {
+			return "block partition tables flag", false
+		}
+		if a.Block.ReadSMARTData != b.Block.ReadSMARTData {
+			return "block SMART data flag", false
+		}
+		if a.Block.IncludeFilesystemUsage != b.Block.IncludeFilesystemUsage {
+			return "block filesystem usage flag", false
+		}
+	}
+	if a.Net != nil {
+		if b.Net == nil {
+			return "net ptr", false
+		}
+		if a.Net.IncludeAddresses != b.Net.IncludeAddresses {
+			return "net addresses flag", false
+		}
+	}
+	if a.PCI != nil {
+		if b.PCI == nil {
+			return "pci ptr", false
+		}
+		if a.PCI.IncludeCandidateDrivers != b.PCI.IncludeCandidateDrivers {
+			return "pci candidate drivers flag", false
+		}
+		if a.PCI.IncludeCapabilities != b.PCI.IncludeCapabilities {
+			return "pci capabilities flag", false
+		}
+	}
 	if a.Snapshot != nil {
 		if b.Snapshot == nil {
 			return "snapshot ptr", false
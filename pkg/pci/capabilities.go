@@ -0,0 +1,194 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package pci
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	// offsets and sizes in the configuration space
+	configStatus           = 0x06
+	configHeaderType       = 0x0E
+	configCapabilitiesPtr  = 0x34
+	configCardbusCapsPtr   = 0x14
+	configStandardSize     = 0x100
+	configExtendedCapsBase = 0x100
+
+	statusCapabilitiesList = 0x10
+	headerTypeCardbus      = 0x02
+
+	// upper bounds to the capabilities found in a well-formed configuration
+	// space, so we don't loop forever on malformed ones
+	maxStandardCapabilities = (configStandardSize - 0x40) / 2
+	maxExtendedCapabilities = (4096 - configExtendedCapsBase) / 8
+)
+
+var (
+	capabilityName = map[uint16]string{
+		0x01: "Power Management",
+		0x02: "AGP",
+		0x03: "VPD",
+		0x04: "Slot Identification",
+		0x05: "MSI",
+		0x06: "CompactPCI Hot Swap",
+		0x07: "PCI-X",
+		0x08: "HyperTransport",
+		0x09: "Vendor Specific",
+		0x0A: "Debug Port",
+		0x0B: "CompactPCI Central Resource Control",
+		0x0C: "PCI Hot-Plug",
+		0x0D: "Subsystem Vendor ID",
+		0x0E: "AGP 8x",
+		0x0F: "Secure Device",
+		0x10: "PCI Express",
+		0x11: "MSI-X",
+		0x12: "SATA",
+		0x13: "Advanced Features",
+		0x14: "Enhanced Allocation",
+		0x15: "Flattening Portal Bridge",
+	}
+
+	extendedCapabilityName = map[uint16]string{
+		0x0001: "AER",
+		0x0002: "Virtual Channel",
+		0x0003: "Device Serial Number",
+		0x0004: "Power Budgeting",
+		0x0005: "Root Complex Link Declaration",
+		0x0006: "Root Complex Internal Link Control",
+		0x0007: "Root Complex Event Collector Endpoint Association",
+		0x0008: "MFVC",
+		0x0009: "Virtual Channel",
+		0x000A: "RCRB Header",
+		0x000B: "Vendor Specific",
+		0x000C: "Configuration Access Correlation",
+		0x000D: "ACS",
+		0x000E: "ARI",
+		0x000F: "ATS",
+		0x0010: "SR-IOV",
+		0x0011: "MR-IOV",
+		0x0012: "Multicast",
+		0x0013: "PRI",
+		0x0015: "Resizable BAR",
+		0x0016: "DPA",
+		0x0017: "TPH",
+		0x0018: "LTR",
+		0x0019: "Secondary PCI Express",
+		0x001A: "PMUX",
+		0x001B: "PASID",
+		0x001C: "LNR",
+		0x001D: "DPC",
+		0x001E: "L1 PM Substates",
+		0x001F: "PTM",
+		0x0020: "M-PCIe",
+		0x0021: "FRS Queueing",
+		0x0022: "Readiness Time Reporting",
+		0x0023: "Designated Vendor Specific",
+		0x0024: "VF Resizable BAR",
+		0x0025: "Data Link Feature",
+		0x0026: "Physical Layer 16.0 GT/s",
+		0x0027: "Lane Margining at the Receiver",
+		0x0028: "Hierarchy ID",
+		0x0029: "NPEM",
+		0x002A: "Physical Layer 32.0 GT/s",
+		0x002B: "Alternate Protocol",
+		0x002C: "SFI",
+	}
+)
+
+// Capability describes a capability found in the configuration space of a
+// device
+type Capability struct {
+	ID uint16 `json:"id"`
+	// Name is the name of the capability, e.g. "MSI-X" or "ACS"
+	Name string `json:"name"`
+	// Offset of the capability in the configuration space
+	Offset int `json:"offset"`
+	// IsExtended is true for the PCI Express extended capabilities, found
+	// after the first 256 bytes of the configuration space
+	IsExtended bool `json:"is_extended"`
+}
+
+func (c *Capability) String() string {
+	return fmt.Sprintf("[%x] %s", c.Offset, c.Name)
+}
+
+// HasCapability returns true if the configuration space of the device lists
+// the capability with the given name (e.g. "ACS" or "AER"). Check that the
+// Capabilities are not nil to tell a missing capability from an unreadable
+// configuration space.
+func (d *Device) HasCapability(name string) bool {
+	for _, capability := range d.Capabilities {
+		if capability.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCapabilities walks the standard and the extended capability lists of
+// the given configuration space. Returns nil if the configuration space is
+// truncated: unprivileged users can read only its first 64 bytes, which
+// don't tell whether the device has capabilities.
+func parseCapabilities(config []byte) []*Capability {
+	if len(config) < configStandardSize {
+		return nil
+	}
+	caps := make([]*Capability, 0)
+	if binary.LittleEndian.Uint16(config[configStatus:])&statusCapabilitiesList == 0 {
+		return caps
+	}
+
+	ptrOffset := configCapabilitiesPtr
+	if config[configHeaderType]&0x7F == headerTypeCardbus {
+		ptrOffset = configCardbusCapsPtr
+	}
+	pos := int(config[ptrOffset] & 0xFC)
+	for count := 0; count < maxStandardCapabilities; count++ {
+		if pos < 0x40 || pos+1 >= len(config) {
+			break
+		}
+		id := uint16(config[pos])
+		caps = append(caps, &Capability{
+			ID:     id,
+			Name:   lookupCapabilityName(capabilityName, id),
+			Offset: pos,
+		})
+		pos = int(config[pos+1] & 0xFC)
+	}
+
+	// the extended capability list always starts right after the standard
+	// configuration space
+	pos = configExtendedCapsBase
+	for count := 0; count < maxExtendedCapabilities; count++ {
+		if pos < configExtendedCapsBase || pos+4 > len(config) {
+			break
+		}
+		header := binary.LittleEndian.Uint32(config[pos:])
+		if header == 0 || header == 0xFFFFFFFF {
+			// no extended capabilities, or conventional PCI device
+			break
+		}
+		id := uint16(header & 0xFFFF)
+		caps = append(caps, &Capability{
+			ID:         id,
+			Name:       lookupCapabilityName(extendedCapabilityName, id),
+			Offset:     pos,
+			IsExtended: true,
+		})
+		pos = int((header >> 20) & 0xFFC)
+	}
+	return caps
+}
+
+func lookupCapabilityName(names map[uint16]string, id uint16) string {
+	if name, ok := names[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown (0x%02x)", id)
+}
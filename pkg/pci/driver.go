@@ -0,0 +1,74 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package pci
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Driver describes a PCI driver registered in the linux kernel
+type Driver struct {
+	Name string `json:"name"`
+	// Module is the name of the kernel module providing the driver. Empty if
+	// the driver is built into the kernel.
+	Module string `json:"module"`
+	// The devices bound to the driver, sorted by address
+	Devices []*Device `json:"-"`
+}
+
+type driverMarshallable struct {
+	Name    string   `json:"name"`
+	Module  string   `json:"module"`
+	Devices []string `json:"devices"`
+}
+
+// MarshalJSON serializes the addresses of the devices bound to the driver,
+// instead of the devices themselves
+func (d *Driver) MarshalJSON() ([]byte, error) {
+	dm := driverMarshallable{
+		Name:    d.Name,
+		Module:  d.Module,
+		Devices: make([]string, 0, len(d.Devices)),
+	}
+	for _, dev := range d.Devices {
+		dm.Devices = append(dm.Devices, dev.Address)
+	}
+	return json.Marshal(dm)
+}
+
+func (d *Driver) String() string {
+	module := "built-in"
+	if d.Module != "" {
+		module = "module '" + d.Module + "'"
+	}
+	return fmt.Sprintf("driver '%s' (%s, %d devices bound)", d.Name, module, len(d.Devices))
+}
+
+// moduleAlias is an entry of the modules.alias file: the kernel module
+// handling the devices whose modalias matches the glob pattern
+type moduleAlias struct {
+	pattern string
+	module  string
+}
+
+// Drivers returns the PCI drivers registered in the kernel of the host
+// system, sorted by name, including the drivers bound to no device
+func (info *Info) Drivers() []*Driver {
+	return info.drivers
+}
+
+// GetDriver returns the PCI driver with the given name, or nil if no such
+// driver is registered
+func (info *Info) GetDriver(name string) *Driver {
+	for _, drv := range info.drivers {
+		if drv.Name == name {
+			return drv
+		}
+	}
+	return nil
+}
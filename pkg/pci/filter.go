@@ -0,0 +1,92 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package pci
+
+import (
+	"strings"
+)
+
+// Predicate returns true if the given device matches a condition. Use the
+// predicates with Info.Filter.
+type Predicate func(*Device) bool
+
+// Filter returns the devices on the host system matching all the given
+// predicates, in the same order as Info.Devices
+func (info *Info) Filter(preds ...Predicate) []*Device {
+	devs := make([]*Device, 0)
+	for _, dev := range info.Devices {
+		if matchesAll(dev, preds) {
+			devs = append(devs, dev)
+		}
+	}
+	return devs
+}
+
+func matchesAll(dev *Device, preds []Predicate) bool {
+	for _, pred := range preds {
+		if !pred(dev) {
+			return false
+		}
+	}
+	return true
+}
+
+// ByClass matches the devices of the given class ID (e.g. "02" for network
+// controllers)
+func ByClass(classID string) Predicate {
+	return func(dev *Device) bool {
+		return dev.Class != nil && strings.EqualFold(dev.Class.ID, classID)
+	}
+}
+
+// BySubclass matches the devices of the given class and subclass IDs (e.g.
+// "01" and "08" for NVMe controllers)
+func BySubclass(classID, subclassID string) Predicate {
+	return func(dev *Device) bool {
+		return ByClass(classID)(dev) &&
+			dev.Subclass != nil && strings.EqualFold(dev.Subclass.ID, subclassID)
+	}
+}
+
+// ByVendor matches the devices of the given vendor ID (e.g. "10de")
+func ByVendor(vendorID string) Predicate {
+	return func(dev *Device) bool {
+		return dev.Vendor != nil && strings.EqualFold(dev.Vendor.ID, vendorID)
+	}
+}
+
+// ByProduct matches the devices of the given vendor and product IDs
+func ByProduct(vendorID, productID string) Predicate {
+	return func(dev *Device) bool {
+		return ByVendor(vendorID)(dev) &&
+			dev.Product != nil && strings.EqualFold(dev.Product.ID, productID)
+	}
+}
+
+// ByDriver matches the devices bound to the given driver. Use an empty
+// driver name to match the devices bound to no driver.
+func ByDriver(driver string) Predicate {
+	return func(dev *Device) bool {
+		return dev.Driver == driver
+	}
+}
+
+// ByNUMANode matches the devices affined to the given NUMA node. Never
+// matches on non-NUMA systems.
+func ByNUMANode(nodeID int) Predicate {
+	return func(dev *Device) bool {
+		return dev.Node != nil && dev.Node.ID == nodeID
+	}
+}
+
+// ByAddressPrefix matches the devices whose address starts with the given
+// prefix (e.g. "0000:3b:" for all the devices on bus 3b)
+func ByAddressPrefix(prefix string) Predicate {
+	return func(dev *Device) bool {
+		return strings.HasPrefix(dev.Address, strings.ToLower(prefix))
+	}
+}
@@ -0,0 +1,143 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package pci
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// the driver devices must be bound to in order to be passed through
+	vfioDriver = "vfio-pci"
+)
+
+// IOMMUGroup describes the smallest set of devices the IOMMU can isolate from
+// the rest of the system. All the devices in a group must be passed through
+// together.
+type IOMMUGroup struct {
+	ID int `json:"id"`
+	// The devices in the group, sorted by address
+	Devices []*Device `json:"-"`
+}
+
+type iommuGroupMarshallable struct {
+	ID      int      `json:"id"`
+	Devices []string `json:"devices"`
+}
+
+// MarshalJSON serializes the addresses of the devices in the group, instead
+// of the devices themselves
+func (g *IOMMUGroup) MarshalJSON() ([]byte, error) {
+	gm := iommuGroupMarshallable{
+		ID:      g.ID,
+		Devices: make([]string, 0, len(g.Devices)),
+	}
+	for _, dev := range g.Devices {
+		gm.Devices = append(gm.Devices, dev.Address)
+	}
+	return json.Marshal(gm)
+}
+
+func (g *IOMMUGroup) String() string {
+	addrs := make([]string, 0, len(g.Devices))
+	for _, dev := range g.Devices {
+		addrs = append(addrs, dev.Address)
+	}
+	return fmt.Sprintf("IOMMU group %d (%s)", g.ID, strings.Join(addrs, ", "))
+}
+
+// IsBoundToVFIO returns true if all the devices in the group can be handed to
+// VFIO. Like the linux kernel does, besides the devices bound to the vfio-pci
+// driver, we accept the devices bound to no driver, to pci-stub and to
+// pcieport (PCI Express bridges).
+func (g *IOMMUGroup) IsBoundToVFIO() bool {
+	return len(g.blockingDevices(nil)) == 0
+}
+
+// blockingDevices returns the devices in the group, besides the given one,
+// which prevent the group from being handed to VFIO
+func (g *IOMMUGroup) blockingDevices(skip *Device) []*Device {
+	devs := make([]*Device, 0)
+	for _, dev := range g.Devices {
+		if dev == skip {
+			continue
+		}
+		switch dev.Driver {
+		case vfioDriver, "", "pci-stub", "pcieport":
+			continue
+		}
+		devs = append(devs, dev)
+	}
+	return devs
+}
+
+// VFIOReadiness describes whether a device can be passed through to a
+// virtual machine using VFIO, and if not, why
+type VFIOReadiness int
+
+const (
+	VFIO_READINESS_READY           VFIOReadiness = iota
+	VFIO_READINESS_IOMMU_DISABLED                // No IOMMU group: IOMMU disabled or not supported
+	VFIO_READINESS_NON_VFIO_DRIVER               // The device is not bound to vfio-pci
+	VFIO_READINESS_SHARED_GROUP                  // Other devices in the IOMMU group are not bound to vfio-pci
+)
+
+var (
+	vfioReadinessString = map[VFIOReadiness]string{
+		VFIO_READINESS_READY:           "ready",
+		VFIO_READINESS_IOMMU_DISABLED:  "IOMMU disabled",
+		VFIO_READINESS_NON_VFIO_DRIVER: "non-vfio driver",
+		VFIO_READINESS_SHARED_GROUP:    "shared IOMMU group",
+	}
+)
+
+func (r VFIOReadiness) String() string {
+	return vfioReadinessString[r]
+}
+
+// MarshalJSON lowercases the string output, like the other ghw enums do
+func (r VFIOReadiness) MarshalJSON() ([]byte, error) {
+	return []byte("\"" + strings.ToLower(r.String()) + "\""), nil
+}
+
+// VFIOReadiness returns whether the device can be passed through to a
+// virtual machine using VFIO. If more than one reason prevents the passthrough,
+// the most fundamental one is reported: a disabled IOMMU first, then the
+// driver of the device, then the other devices in its IOMMU group.
+func (d *Device) VFIOReadiness() VFIOReadiness {
+	if d.IOMMUGroup == nil {
+		return VFIO_READINESS_IOMMU_DISABLED
+	}
+	if d.Driver != vfioDriver {
+		return VFIO_READINESS_NON_VFIO_DRIVER
+	}
+	if len(d.IOMMUGroup.blockingDevices(d)) > 0 {
+		return VFIO_READINESS_SHARED_GROUP
+	}
+	return VFIO_READINESS_READY
+}
+
+// IOMMUGroups returns the IOMMU groups of the devices on the host system,
+// sorted by ID. Returns an empty slice if the IOMMU is disabled.
+func (info *Info) IOMMUGroups() []*IOMMUGroup {
+	groups := make([]*IOMMUGroup, 0)
+	seen := make(map[*IOMMUGroup]bool)
+	for _, dev := range info.Devices {
+		if dev.IOMMUGroup == nil || seen[dev.IOMMUGroup] {
+			continue
+		}
+		seen[dev.IOMMUGroup] = true
+		groups = append(groups, dev.IOMMUGroup)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].ID < groups[j].ID
+	})
+	return groups
+}
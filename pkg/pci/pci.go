@@ -33,6 +33,70 @@ var (
 	)
 )
 
+// Link describes the PCI Express link of a device
+type Link struct {
+	// Link speeds as reported by the kernel (e.g. "8.0 GT/s PCIe") and
+	// the same speeds in gigatransfers per second. Zero if unknown.
+	CurrentSpeed    string  `json:"current_speed"`
+	CurrentSpeedGTs float64 `json:"current_speed_gts"`
+	MaxSpeed        string  `json:"max_speed"`
+	MaxSpeedGTs     float64 `json:"max_speed_gts"`
+	// Link widths, in lanes. Zero if unknown.
+	CurrentWidth int `json:"current_width"`
+	MaxWidth     int `json:"max_width"`
+	// IsDegraded is true if the link is running below its maximum speed or
+	// width
+	IsDegraded bool `json:"is_degraded"`
+}
+
+func (l *Link) String() string {
+	degraded := ""
+	if l.IsDegraded {
+		degraded = " (degraded)"
+	}
+	return fmt.Sprintf(
+		"%s x%d (max %s x%d)%s",
+		l.CurrentSpeed,
+		l.CurrentWidth,
+		l.MaxSpeed,
+		l.MaxWidth,
+		degraded,
+	)
+}
+
+// BAR describes a base address register of a device: a memory or I/O
+// address range the device is mapped at
+type BAR struct {
+	// Index is the number of the BAR, from 0 to 5
+	Index     int    `json:"index"`
+	Address   uint64 `json:"address"`
+	SizeBytes uint64 `json:"size_bytes"`
+	// Flags are the raw resource flags reported by the linux kernel
+	Flags          uint64 `json:"flags"`
+	IsIO           bool   `json:"is_io"`
+	IsMemory       bool   `json:"is_memory"`
+	IsPrefetchable bool   `json:"is_prefetchable"`
+	Is64Bit        bool   `json:"is_64bit"`
+}
+
+func (b *BAR) String() string {
+	kind := "memory"
+	if b.IsIO {
+		kind = "I/O"
+	}
+	return fmt.Sprintf("BAR %d: %s at 0x%x (%d bytes)", b.Index, kind, b.Address, b.SizeBytes)
+}
+
+// SRIOV describes the SR-IOV capabilities of a physical function
+type SRIOV struct {
+	// TotalVFs is the number of virtual functions the device supports
+	TotalVFs int `json:"total_vfs"`
+	// NumVFs is the number of virtual functions currently enabled
+	NumVFs int `json:"num_vfs"`
+	// The enabled virtual functions, in virtfnN order
+	VirtualFunctions []*Device `json:"-"`
+}
+
 type Device struct {
 	// The PCI address of the device
 	Address   string         `json:"address"`
@@ -50,6 +114,63 @@ type Device struct {
 	// architecture is not NUMA.
 	Node   *topology.Node `json:"node,omitempty"`
 	Driver string         `json:"driver"`
+	// DriverOverride is the name of the only driver the device may be bound
+	// to, as set through the driver_override sysfs attribute. Empty if not
+	// set.
+	DriverOverride string `json:"driver_override,omitempty"`
+	// CandidateDrivers are the names of the kernel modules whose aliases
+	// match the modalias of the device, sorted. Only reported when requested
+	// with the PCIOptions, and empty if the module aliases of the running
+	// kernel can't be read.
+	CandidateDrivers []string `json:"candidate_drivers,omitempty"`
+	// PCI Express link information. Will be nil if the device is not a
+	// PCI Express device.
+	Link *Link `json:"link,omitempty"`
+	// SR-IOV information. Will be nil if the device is not a SR-IOV physical
+	// function.
+	SRIOV *SRIOV `json:"sriov,omitempty"`
+	// PhysicalFunction is the device this device is a virtual function of.
+	// Will be nil if the device is not a SR-IOV virtual function.
+	PhysicalFunction *Device `json:"-"`
+	// IOMMU group the device belongs to. Will be nil if the IOMMU is
+	// disabled.
+	IOMMUGroup *IOMMUGroup `json:"-"`
+	// The BARs the device is mapped at. BARs which are not implemented or
+	// not assigned are omitted.
+	BARs []*BAR `json:"bars"`
+	// Legacy INTx interrupt line. Zero if the device doesn't use one.
+	IRQ int `json:"irq"`
+	// MSIMode is "msi" or "msix" if the device uses message signaled
+	// interrupts, empty otherwise
+	MSIMode string `json:"msi_mode,omitempty"`
+	// The interrupts allocated for the MSI or MSI-X vectors, sorted
+	MSIIRQs []int `json:"msi_irqs,omitempty"`
+	// LocalCPUs are the IDs of the logical processors local to the device
+	LocalCPUs []int `json:"local_cpus,omitempty"`
+	// IsEnabled is true if the device is enabled
+	IsEnabled bool `json:"is_enabled"`
+	// The capabilities listed in the configuration space of the device. Only
+	// reported when requested with the PCIOptions. Reading them requires
+	// root privileges: nil means the configuration space is not readable,
+	// while an empty list means the device has no capabilities.
+	Capabilities []*Capability `json:"capabilities,omitempty"`
+	// Parent is the bridge (e.g. PCI Express root port or switch port) the
+	// device is connected to. Will be nil if the device is connected
+	// directly to the root bus of a root complex.
+	Parent *Device `json:"-"`
+	// Children are the devices connected to the device, if it is a bridge,
+	// sorted by address
+	Children []*Device `json:"-"`
+}
+
+// IsPhysicalFunction returns true if the device is a SR-IOV physical function
+func (d *Device) IsPhysicalFunction() bool {
+	return d.SRIOV != nil
+}
+
+// IsVirtualFunction returns true if the device is a SR-IOV virtual function
+func (d *Device) IsVirtualFunction() bool {
+	return d.PhysicalFunction != nil
 }
 
 type devIdent struct {
@@ -57,16 +178,41 @@ type devIdent struct {
 	Name string `json:"name"`
 }
 
+type sriovMarshallable struct {
+	TotalVFs         int      `json:"total_vfs"`
+	NumVFs           int      `json:"num_vfs"`
+	VirtualFunctions []string `json:"virtual_functions"`
+}
+
 type devMarshallable struct {
-	Driver    string   `json:"driver"`
-	Address   string   `json:"address"`
-	Vendor    devIdent `json:"vendor"`
-	Product   devIdent `json:"product"`
-	Revision  string   `json:"revision"`
-	Subsystem devIdent `json:"subsystem"`
-	Class     devIdent `json:"class"`
-	Subclass  devIdent `json:"subclass"`
-	Interface devIdent `json:"programming_interface"`
+	Driver       string             `json:"driver"`
+	Address      string             `json:"address"`
+	Vendor       devIdent           `json:"vendor"`
+	Product      devIdent           `json:"product"`
+	Revision     string             `json:"revision"`
+	Subsystem    devIdent           `json:"subsystem"`
+	Class        devIdent           `json:"class"`
+	Subclass     devIdent           `json:"subclass"`
+	Interface    devIdent           `json:"programming_interface"`
+	Link         *Link              `json:"link,omitempty"`
+	BARs         []*BAR             `json:"bars"`
+	IRQ          int                `json:"irq"`
+	MSIMode      string             `json:"msi_mode,omitempty"`
+	MSIIRQs      []int              `json:"msi_irqs,omitempty"`
+	LocalCPUs    []int              `json:"local_cpus,omitempty"`
+	IsEnabled    bool               `json:"is_enabled"`
+	Capabilities []*Capability      `json:"capabilities,omitempty"`
+	SRIOV        *sriovMarshallable `json:"sriov,omitempty"`
+	// address of the physical function
+	PhysicalFunction string `json:"physical_function,omitempty"`
+	// ID of the IOMMU group
+	IOMMUGroup *int `json:"iommu_group,omitempty"`
+	// addresses of the parent and children devices
+	Parent   string   `json:"parent,omitempty"`
+	Children []string `json:"children,omitempty"`
+
+	DriverOverride   string   `json:"driver_override,omitempty"`
+	CandidateDrivers []string `json:"candidate_drivers,omitempty"`
 }
 
 // NOTE(jaypipes) Device has a custom JSON marshaller because we don't want
@@ -102,6 +248,39 @@ func (d *Device) MarshalJSON() ([]byte, error) {
 			ID:   d.ProgrammingInterface.ID,
 			Name: d.ProgrammingInterface.Name,
 		},
+		Link:         d.Link,
+		BARs:         d.BARs,
+		IRQ:          d.IRQ,
+		MSIMode:      d.MSIMode,
+		MSIIRQs:      d.MSIIRQs,
+		LocalCPUs:    d.LocalCPUs,
+		IsEnabled:    d.IsEnabled,
+		Capabilities: d.Capabilities,
+
+		DriverOverride:   d.DriverOverride,
+		CandidateDrivers: d.CandidateDrivers,
+	}
+	if d.SRIOV != nil {
+		dm.SRIOV = &sriovMarshallable{
+			TotalVFs:         d.SRIOV.TotalVFs,
+			NumVFs:           d.SRIOV.NumVFs,
+			VirtualFunctions: make([]string, 0, len(d.SRIOV.VirtualFunctions)),
+		}
+		for _, vf := range d.SRIOV.VirtualFunctions {
+			dm.SRIOV.VirtualFunctions = append(dm.SRIOV.VirtualFunctions, vf.Address)
+		}
+	}
+	if d.PhysicalFunction != nil {
+		dm.PhysicalFunction = d.PhysicalFunction.Address
+	}
+	if d.IOMMUGroup != nil {
+		dm.IOMMUGroup = &d.IOMMUGroup.ID
+	}
+	if d.Parent != nil {
+		dm.Parent = d.Parent.Address
+	}
+	for _, child := range d.Children {
+		dm.Children = append(dm.Children, child.Address)
 	}
 	return json.Marshal(dm)
 }
@@ -129,9 +308,31 @@ func (d *Device) String() string {
 	)
 }
 
+// RootComplex describes a PCI host bridge, the root of a hierarchy of PCI
+// devices
+type RootComplex struct {
+	// Name of the root complex, as in sysfs (e.g. "pci0000:00")
+	Name   string `json:"name"`
+	Domain string `json:"domain"`
+	Bus    string `json:"bus"`
+	// Devices connected directly to the root bus, sorted by address
+	Devices []*Device `json:"-"`
+}
+
+func (rc *RootComplex) String() string {
+	return fmt.Sprintf("%s (%d devices on the root bus)", rc.Name, len(rc.Devices))
+}
+
 type Info struct {
 	arch topology.Architecture
 	ctx  *context.Context
+	// root complexes the Devices hang off
+	rootComplexes []*RootComplex
+	// drivers registered in the kernel, bound to the Devices or not
+	drivers []*Driver
+	// aliases of the kernel modules, loaded on first use. nil if not loaded
+	// yet.
+	moduleAliases []moduleAlias
 	// All PCI devices on the host system
 	Devices []*Device
 	// hash of class ID -> class information
@@ -181,6 +382,36 @@ func NewWithContext(ctx *context.Context) (*Info, error) {
 	return info, nil
 }
 
+// RootComplexes returns the root complexes of the host system, sorted by name.
+// Walk the Children of their Devices to explore the whole PCI hierarchy.
+func (info *Info) RootComplexes() []*RootComplex {
+	return info.rootComplexes
+}
+
+// PhysicalFunctions returns the SR-IOV physical functions found on the host
+// system
+func (info *Info) PhysicalFunctions() []*Device {
+	pfs := make([]*Device, 0)
+	for _, dev := range info.Devices {
+		if dev.IsPhysicalFunction() {
+			pfs = append(pfs, dev)
+		}
+	}
+	return pfs
+}
+
+// VirtualFunctions returns the SR-IOV virtual functions found on the host
+// system
+func (info *Info) VirtualFunctions() []*Device {
+	vfs := make([]*Device, 0)
+	for _, dev := range info.Devices {
+		if dev.IsVirtualFunction() {
+			vfs = append(vfs, dev)
+		}
+	}
+	return vfs
+}
+
 // lookupDevice gets a device from cached data
 func (info *Info) lookupDevice(address string) *Device {
 	for _, dev := range info.Devices {
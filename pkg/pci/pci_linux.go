@@ -8,7 +8,10 @@ package pci
 import (
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/jaypipes/pcidb"
@@ -23,6 +26,15 @@ import (
 const (
 	// found running `wc` against real linux systems
 	modAliasExpectedLength = 54
+
+	// the first 6 lines of the resource file describe the BARs
+	numBARs = 6
+
+	// resource flags, from include/linux/ioport.h
+	ioResourceIO       = 0x00000100
+	ioResourceMem      = 0x00000200
+	ioResourcePrefetch = 0x00002000
+	ioResourceMem64    = 0x00100000
 )
 
 func (i *Info) load() error {
@@ -34,6 +46,10 @@ func (i *Info) load() error {
 	i.Vendors = db.Vendors
 	i.Products = db.Products
 	i.Devices = i.ListDevices()
+	i.linkSRIOVDevices(i.Devices)
+	linkIOMMUGroups(i.Devices)
+	i.rootComplexes = i.linkHierarchy(i.Devices)
+	i.drivers = i.listDrivers(i.Devices)
 	return nil
 }
 
@@ -97,6 +113,508 @@ func getDeviceDriver(ctx *context.Context, pciAddr *pciaddr.Address) string {
 	return filepath.Base(dest)
 }
 
+func getDeviceDriverOverride(ctx *context.Context, pciAddr *pciaddr.Address) string {
+	paths := linuxpath.New(ctx)
+	devPath := filepath.Join(paths.SysBusPciDevices, pciAddr.String())
+	override, err := readDeviceAttr(devPath, "driver_override")
+	// the kernel reports "(null)" if no override is set
+	if err != nil || override == "(null)" {
+		return ""
+	}
+	return override
+}
+
+// getDeviceCandidateDrivers returns the kernel modules whose aliases match
+// the modalias of the device. The aliases are loaded once per Info.
+func (info *Info) getDeviceCandidateDrivers(pciAddr *pciaddr.Address) []string {
+	modalias, err := ioutil.ReadFile(getDeviceModaliasPath(info.ctx, pciAddr))
+	if err != nil {
+		return nil
+	}
+	if info.moduleAliases == nil {
+		info.moduleAliases = loadModuleAliases(info.ctx)
+	}
+	return matchModuleAliases(info.moduleAliases, strings.TrimSpace(string(modalias)))
+}
+
+// loadModuleAliases reads the PCI aliases of the loadable and of the
+// built-in kernel modules of the running kernel. Returns an empty slice if
+// they can't be read.
+func loadModuleAliases(ctx *context.Context) []moduleAlias {
+	paths := linuxpath.New(ctx)
+	aliases := make([]moduleAlias, 0)
+	release, err := ioutil.ReadFile(paths.ProcSysKernelOSRelease)
+	if err != nil {
+		return aliases
+	}
+	modulesDir := filepath.Join(paths.LibModules, strings.TrimSpace(string(release)))
+	for _, name := range []string{"modules.alias", "modules.builtin.alias"} {
+		data, err := ioutil.ReadFile(filepath.Join(modulesDir, name))
+		if err != nil {
+			continue
+		}
+		aliases = append(aliases, parseModuleAliases(string(data))...)
+	}
+	return aliases
+}
+
+// parseModuleAliases parses the PCI entries of a modules.alias file, which
+// look like:
+//
+// alias pci:v00008086d00001520sv*sd*bc*sc*i* igbvf
+func parseModuleAliases(data string) []moduleAlias {
+	aliases := make([]moduleAlias, 0)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != "alias" || !strings.HasPrefix(fields[1], "pci:") {
+			continue
+		}
+		aliases = append(aliases, moduleAlias{
+			pattern: fields[1],
+			module:  fields[2],
+		})
+	}
+	return aliases
+}
+
+func matchModuleAliases(aliases []moduleAlias, modalias string) []string {
+	modules := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, alias := range aliases {
+		if seen[alias.module] {
+			continue
+		}
+		// modaliases contain no path separators, so the shell-like matching
+		// of path.Match is what we need
+		if matched, _ := path.Match(alias.pattern, modalias); matched {
+			seen[alias.module] = true
+			modules = append(modules, alias.module)
+		}
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// listDrivers returns the drivers registered in /sys/bus/pci/drivers, with
+// the given devices bound to them
+func (info *Info) listDrivers(devs []*Device) []*Driver {
+	paths := linuxpath.New(info.ctx)
+	drivers := make([]*Driver, 0)
+	entries, err := ioutil.ReadDir(paths.SysBusPciDrivers)
+	if err != nil {
+		info.ctx.Warn("failed to read /sys/bus/pci/drivers")
+		return drivers
+	}
+	byName := make(map[string]*Driver, len(entries))
+	for _, entry := range entries {
+		drv := &Driver{
+			Name:    entry.Name(),
+			Module:  getDriverModule(filepath.Join(paths.SysBusPciDrivers, entry.Name())),
+			Devices: make([]*Device, 0),
+		}
+		drivers = append(drivers, drv)
+		byName[drv.Name] = drv
+	}
+	for _, dev := range devs {
+		if drv, ok := byName[dev.Driver]; ok {
+			drv.Devices = append(drv.Devices, dev)
+		}
+	}
+	return drivers
+}
+
+// getDriverModule returns the name of the kernel module providing the
+// driver, which the module symlink points to. Built-in drivers have no
+// module symlink.
+func getDriverModule(drvPath string) string {
+	dest, err := os.Readlink(filepath.Join(drvPath, "module"))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(dest)
+}
+
+func getDeviceLink(ctx *context.Context, pciAddr *pciaddr.Address) *Link {
+	paths := linuxpath.New(ctx)
+	devPath := filepath.Join(paths.SysBusPciDevices, pciAddr.String())
+
+	// the link attributes are exposed only for PCI Express devices
+	curSpeed, err := readDeviceAttr(devPath, "current_link_speed")
+	if err != nil {
+		return nil
+	}
+	maxSpeed, _ := readDeviceAttr(devPath, "max_link_speed")
+	curWidth, _ := readDeviceAttr(devPath, "current_link_width")
+	maxWidth, _ := readDeviceAttr(devPath, "max_link_width")
+
+	link := &Link{
+		CurrentSpeed:    curSpeed,
+		CurrentSpeedGTs: parseLinkSpeed(curSpeed),
+		MaxSpeed:        maxSpeed,
+		MaxSpeedGTs:     parseLinkSpeed(maxSpeed),
+		CurrentWidth:    parseCount(curWidth),
+		MaxWidth:        parseCount(maxWidth),
+	}
+	link.IsDegraded = isLinkDegraded(link)
+	return link
+}
+
+func readDeviceAttr(devPath, attr string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(devPath, attr))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// parseLinkSpeed returns the link speed in GT/s. The kernel reports values
+// like "8.0 GT/s PCIe", "2.5 GT/s" or "Unknown speed"; we return 0 for the
+// latter.
+func parseLinkSpeed(speed string) float64 {
+	fields := strings.Fields(speed)
+	if len(fields) < 2 || fields[1] != "GT/s" {
+		return 0
+	}
+	val, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return val
+}
+
+// parseCount returns 0 if the value is not a valid count
+func parseCount(count string) int {
+	val, err := strconv.Atoi(count)
+	if err != nil || val < 0 {
+		return 0
+	}
+	return val
+}
+
+// isLinkDegraded compares only the values we know: an unknown speed or
+// width is never considered degraded
+func isLinkDegraded(link *Link) bool {
+	if link.CurrentSpeedGTs > 0 && link.CurrentSpeedGTs < link.MaxSpeedGTs {
+		return true
+	}
+	if link.CurrentWidth > 0 && link.CurrentWidth < link.MaxWidth {
+		return true
+	}
+	return false
+}
+
+func getDeviceBARs(ctx *context.Context, pciAddr *pciaddr.Address) []*BAR {
+	paths := linuxpath.New(ctx)
+	devPath := filepath.Join(paths.SysBusPciDevices, pciAddr.String())
+
+	bars := make([]*BAR, 0)
+	data, err := readDeviceAttr(devPath, "resource")
+	if err != nil {
+		return bars
+	}
+	// each line looks like "0x00000000f7e00000 0x00000000f7e1ffff 0x0000000000040200":
+	// start address, end address, flags
+	for idx, line := range strings.Split(data, "\n") {
+		if idx >= numBARs {
+			break
+		}
+		bar := parseBAR(idx, line)
+		if bar == nil {
+			continue
+		}
+		bars = append(bars, bar)
+	}
+	return bars
+}
+
+// parseBAR returns nil if the BAR is not implemented or not assigned
+func parseBAR(idx int, line string) *BAR {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return nil
+	}
+	vals := make([]uint64, 0, len(fields))
+	for _, field := range fields {
+		val, err := strconv.ParseUint(field, 0, 64)
+		if err != nil {
+			return nil
+		}
+		vals = append(vals, val)
+	}
+	start, end, flags := vals[0], vals[1], vals[2]
+	if start == 0 && end == 0 {
+		return nil
+	}
+	return &BAR{
+		Index:          idx,
+		Address:        start,
+		SizeBytes:      end - start + 1,
+		Flags:          flags,
+		IsIO:           flags&ioResourceIO != 0,
+		IsMemory:       flags&ioResourceMem != 0,
+		IsPrefetchable: flags&ioResourcePrefetch != 0,
+		Is64Bit:        flags&ioResourceMem64 != 0,
+	}
+}
+
+func getDeviceIRQ(ctx *context.Context, pciAddr *pciaddr.Address) int {
+	paths := linuxpath.New(ctx)
+	devPath := filepath.Join(paths.SysBusPciDevices, pciAddr.String())
+
+	irq, err := readDeviceAttr(devPath, "irq")
+	if err != nil {
+		return 0
+	}
+	return parseCount(irq)
+}
+
+// getDeviceMSIIRQs returns the MSI mode ("msi" or "msix") and the interrupts
+// allocated for the MSI vectors of the device
+func getDeviceMSIIRQs(ctx *context.Context, pciAddr *pciaddr.Address) (string, []int) {
+	paths := linuxpath.New(ctx)
+	msiPath := filepath.Join(paths.SysBusPciDevices, pciAddr.String(), "msi_irqs")
+
+	// the directory exists only if the driver enabled MSI or MSI-X
+	entries, err := ioutil.ReadDir(msiPath)
+	if err != nil || len(entries) == 0 {
+		return "", nil
+	}
+	mode := ""
+	irqs := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		irq, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		irqs = append(irqs, irq)
+		if mode == "" {
+			// each file contains the mode, which is the same for all the
+			// vectors of the device
+			mode, _ = readDeviceAttr(msiPath, entry.Name())
+		}
+	}
+	sort.Ints(irqs)
+	return mode, irqs
+}
+
+func getDeviceLocalCPUs(ctx *context.Context, pciAddr *pciaddr.Address) []int {
+	paths := linuxpath.New(ctx)
+	devPath := filepath.Join(paths.SysBusPciDevices, pciAddr.String())
+
+	cpuList, err := readDeviceAttr(devPath, "local_cpulist")
+	if err != nil {
+		return nil
+	}
+	cpus, err := parseCPUList(cpuList)
+	if err != nil {
+		ctx.Warn("error parsing the local CPUs of PCI device %s: %v", pciAddr.String(), err)
+		return nil
+	}
+	return cpus
+}
+
+// parseCPUList parses the kernel CPU list format, e.g. "0-5,12-17"
+func parseCPUList(cpuList string) ([]int, error) {
+	cpus := make([]int, 0)
+	if cpuList == "" {
+		return cpus, nil
+	}
+	for _, item := range strings.Split(cpuList, ",") {
+		bounds := strings.SplitN(item, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, err
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+func getDeviceEnabled(ctx *context.Context, pciAddr *pciaddr.Address) bool {
+	paths := linuxpath.New(ctx)
+	devPath := filepath.Join(paths.SysBusPciDevices, pciAddr.String())
+
+	// the file holds the number of users which enabled the device
+	enable, err := readDeviceAttr(devPath, "enable")
+	if err != nil {
+		return false
+	}
+	return parseCount(enable) > 0
+}
+
+func getDeviceCapabilities(ctx *context.Context, pciAddr *pciaddr.Address) []*Capability {
+	paths := linuxpath.New(ctx)
+	configPath := filepath.Join(paths.SysBusPciDevices, pciAddr.String(), "config")
+
+	config, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil
+	}
+	return parseCapabilities(config)
+}
+
+func getDeviceSRIOV(ctx *context.Context, pciAddr *pciaddr.Address) *SRIOV {
+	paths := linuxpath.New(ctx)
+	devPath := filepath.Join(paths.SysBusPciDevices, pciAddr.String())
+
+	// the sriov_* attributes are exposed only for physical functions
+	totalVFs, err := readDeviceAttr(devPath, "sriov_totalvfs")
+	if err != nil {
+		return nil
+	}
+	numVFs, _ := readDeviceAttr(devPath, "sriov_numvfs")
+	return &SRIOV{
+		TotalVFs:         parseCount(totalVFs),
+		NumVFs:           parseCount(numVFs),
+		VirtualFunctions: []*Device{},
+	}
+}
+
+// linkSRIOVDevices connects the physical functions found in the given devices
+// to their virtual functions, and vice versa
+func (info *Info) linkSRIOVDevices(devs []*Device) {
+	paths := linuxpath.New(info.ctx)
+	devsByAddr := make(map[string]*Device, len(devs))
+	for _, dev := range devs {
+		devsByAddr[dev.Address] = dev
+	}
+	for _, pf := range devs {
+		if pf.SRIOV == nil {
+			continue
+		}
+		// the kernel creates the virtfnN links with N from 0 to sriov_numvfs-1
+		for idx := 0; ; idx++ {
+			virtFn := filepath.Join(paths.SysBusPciDevices, pf.Address, "virtfn"+strconv.Itoa(idx))
+			dest, err := os.Readlink(virtFn)
+			if err != nil {
+				break
+			}
+			vf, ok := devsByAddr[filepath.Base(dest)]
+			if !ok {
+				info.ctx.Warn("unknown virtual function %q for PCI device %s", filepath.Base(dest), pf.Address)
+				continue
+			}
+			vf.PhysicalFunction = pf
+			pf.SRIOV.VirtualFunctions = append(pf.SRIOV.VirtualFunctions, vf)
+		}
+	}
+}
+
+// getDeviceIOMMUGroup returns an IOMMU group containing only the given
+// device: the groups are shared among devices by linkIOMMUGroups
+func getDeviceIOMMUGroup(ctx *context.Context, device *Device, pciAddr *pciaddr.Address) *IOMMUGroup {
+	paths := linuxpath.New(ctx)
+	groupPath := filepath.Join(paths.SysBusPciDevices, pciAddr.String(), "iommu_group")
+
+	// the link is missing if the IOMMU is disabled
+	dest, err := os.Readlink(groupPath)
+	if err != nil {
+		return nil
+	}
+	groupID, err := strconv.Atoi(filepath.Base(dest))
+	if err != nil {
+		ctx.Warn("unexpected IOMMU group %q for PCI device %s", dest, device.Address)
+		return nil
+	}
+	return &IOMMUGroup{
+		ID:      groupID,
+		Devices: []*Device{device},
+	}
+}
+
+// linkIOMMUGroups makes all the given devices belonging to the same IOMMU
+// group share the same IOMMUGroup
+func linkIOMMUGroups(devs []*Device) {
+	groups := make(map[int]*IOMMUGroup)
+	for _, dev := range devs {
+		if dev.IOMMUGroup == nil {
+			continue
+		}
+		group, ok := groups[dev.IOMMUGroup.ID]
+		if !ok {
+			group = &IOMMUGroup{ID: dev.IOMMUGroup.ID}
+			groups[group.ID] = group
+		}
+		group.Devices = append(group.Devices, dev)
+		dev.IOMMUGroup = group
+	}
+	for _, group := range groups {
+		sort.Slice(group.Devices, func(i, j int) bool {
+			return group.Devices[i].Address < group.Devices[j].Address
+		})
+	}
+}
+
+// linkHierarchy connects the given devices to their parents and children,
+// and returns the root complexes they hang off
+func (info *Info) linkHierarchy(devs []*Device) []*RootComplex {
+	paths := linuxpath.New(info.ctx)
+	devsByAddr := make(map[string]*Device, len(devs))
+	for _, dev := range devs {
+		devsByAddr[dev.Address] = dev
+	}
+	roots := make(map[string]*RootComplex)
+	for _, dev := range devs {
+		// the entries in /sys/bus/pci/devices link to the device entries in
+		// /sys/devices, which are nested like the PCI hierarchy:
+		// ../../../devices/pci0000:00/0000:00:09.0/0000:05:00.0
+		dest, err := os.Readlink(filepath.Join(paths.SysBusPciDevices, dev.Address))
+		if err != nil {
+			continue
+		}
+		parentName := filepath.Base(filepath.Dir(dest))
+		if parent, ok := devsByAddr[parentName]; ok {
+			dev.Parent = parent
+			parent.Children = append(parent.Children, dev)
+			continue
+		}
+		rc := parseRootComplexName(parentName)
+		if rc == nil {
+			info.ctx.Warn("unexpected parent %q for PCI device %s", parentName, dev.Address)
+			continue
+		}
+		if _, ok := roots[rc.Name]; !ok {
+			roots[rc.Name] = rc
+		}
+		roots[rc.Name].Devices = append(roots[rc.Name].Devices, dev)
+	}
+
+	rootComplexes := make([]*RootComplex, 0, len(roots))
+	for _, rc := range roots {
+		rootComplexes = append(rootComplexes, rc)
+	}
+	sort.Slice(rootComplexes, func(i, j int) bool {
+		return rootComplexes[i].Name < rootComplexes[j].Name
+	})
+	return rootComplexes
+}
+
+// parseRootComplexName parses the names of the root complexes in sysfs, which
+// look like "pci$DOMAIN:$BUS"
+func parseRootComplexName(name string) *RootComplex {
+	if !strings.HasPrefix(name, "pci") {
+		return nil
+	}
+	parts := strings.Split(strings.TrimPrefix(name, "pci"), ":")
+	if len(parts) != 2 {
+		return nil
+	}
+	return &RootComplex{
+		Name:    name,
+		Domain:  parts[0],
+		Bus:     parts[1],
+		Devices: []*Device{},
+	}
+}
+
 type deviceModaliasInfo struct {
 	vendorID     string
 	productID    string
@@ -318,6 +836,21 @@ func (info *Info) GetDevice(address string) *Device {
 		device.Node = getDeviceNUMANode(info.ctx, pciAddr)
 	}
 	device.Driver = getDeviceDriver(info.ctx, pciAddr)
+	device.DriverOverride = getDeviceDriverOverride(info.ctx, pciAddr)
+	if info.ctx.PCICandidateDrivers {
+		device.CandidateDrivers = info.getDeviceCandidateDrivers(pciAddr)
+	}
+	device.Link = getDeviceLink(info.ctx, pciAddr)
+	device.BARs = getDeviceBARs(info.ctx, pciAddr)
+	device.IRQ = getDeviceIRQ(info.ctx, pciAddr)
+	device.MSIMode, device.MSIIRQs = getDeviceMSIIRQs(info.ctx, pciAddr)
+	device.LocalCPUs = getDeviceLocalCPUs(info.ctx, pciAddr)
+	device.IsEnabled = getDeviceEnabled(info.ctx, pciAddr)
+	if info.ctx.PCICapabilities {
+		device.Capabilities = getDeviceCapabilities(info.ctx, pciAddr)
+	}
+	device.SRIOV = getDeviceSRIOV(info.ctx, pciAddr)
+	device.IOMMUGroup = getDeviceIOMMUGroup(info.ctx, device, pciAddr)
 	return device
 }
 
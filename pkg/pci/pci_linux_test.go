@@ -7,15 +7,19 @@
 package pci_test
 
 import (
+	"encoding/binary"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/jaypipes/ghw/pkg/context"
 	"github.com/jaypipes/ghw/pkg/marshal"
 	"github.com/jaypipes/ghw/pkg/option"
 	"github.com/jaypipes/ghw/pkg/pci"
+	"github.com/jaypipes/ghw/pkg/snapshot"
 
 	"github.com/jaypipes/ghw/testdata"
 )
@@ -161,6 +165,709 @@ func TestPCIMalformedModalias(t *testing.T) {
 	}
 }
 
+func TestPCISRIOV(t *testing.T) {
+	info := pciTestSetup(t)
+
+	pfs := info.PhysicalFunctions()
+	if len(pfs) != 2 {
+		t.Fatalf("Expected 2 physical functions, got %d", len(pfs))
+	}
+	vfs := info.VirtualFunctions()
+	if len(vfs) != 8 {
+		t.Fatalf("Expected 8 virtual functions, got %d", len(vfs))
+	}
+
+	pf := info.GetDevice("0000:05:00.0")
+	if pf == nil || !pf.IsPhysicalFunction() {
+		t.Fatalf("Expected 0000:05:00.0 to be a physical function, got %v", pf)
+	}
+	if pf.IsVirtualFunction() {
+		t.Errorf("Expected 0000:05:00.0 not to be a virtual function")
+	}
+	if pf.SRIOV.TotalVFs != 7 || pf.SRIOV.NumVFs != 4 {
+		t.Errorf("Unexpected SR-IOV information %+v", pf.SRIOV)
+	}
+	expectedVFs := []string{"0000:05:10.0", "0000:05:10.4", "0000:05:11.0", "0000:05:11.4"}
+	if len(pf.SRIOV.VirtualFunctions) != len(expectedVFs) {
+		t.Fatalf("Expected %d virtual functions, got %d", len(expectedVFs), len(pf.SRIOV.VirtualFunctions))
+	}
+	for idx, vf := range pf.SRIOV.VirtualFunctions {
+		if vf.Address != expectedVFs[idx] {
+			t.Errorf("Expected virtual function %d to be %q, got %q", idx, expectedVFs[idx], vf.Address)
+		}
+		if vf.PhysicalFunction != pf {
+			t.Errorf("Expected virtual function %q to point back to its physical function", vf.Address)
+		}
+	}
+
+	vf := info.GetDevice("0000:05:11.0")
+	if vf == nil || !vf.IsVirtualFunction() || vf.IsPhysicalFunction() {
+		t.Fatalf("Expected 0000:05:11.0 to be a virtual function, got %v", vf)
+	}
+
+	dev := info.GetDevice("0000:07:03.0")
+	if dev == nil || dev.IsVirtualFunction() || dev.IsPhysicalFunction() {
+		t.Fatalf("Expected 0000:07:03.0 not to be a SR-IOV device, got %v", dev)
+	}
+
+	// the back-references must not make the marshalling loop
+	if s := info.JSONString(false); s == "" {
+		t.Fatalf("Error marshalling the PCI info")
+	}
+}
+
+func TestPCIListDevicesTwice(t *testing.T) {
+	tmpRoot := pciTestUnpack(t)
+	defer snapshot.Cleanup(tmpRoot)
+
+	info, err := pci.New(option.WithChroot(tmpRoot))
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+
+	// the devices are linked when loaded, so listing them again must neither
+	// link them twice nor replace the root complexes and the drivers
+	roots := info.RootComplexes()
+	drivers := info.Drivers()
+	devs := info.ListDevices()
+	if len(devs) != len(info.Devices) {
+		t.Fatalf("Expected %d devices, got %d", len(info.Devices), len(devs))
+	}
+	if len(roots) == 0 || &info.RootComplexes()[0] != &roots[0] {
+		t.Errorf("Expected the root complexes to be left unchanged")
+	}
+	if len(drivers) == 0 || &info.Drivers()[0] != &drivers[0] {
+		t.Errorf("Expected the drivers to be left unchanged")
+	}
+	pf := info.GetDevice("0000:05:00.0")
+	if pf == nil || !pf.IsPhysicalFunction() {
+		t.Fatalf("Expected 0000:05:00.0 to be a physical function, got %v", pf)
+	}
+	if len(pf.SRIOV.VirtualFunctions) != 4 {
+		t.Errorf("Expected 4 virtual functions, got %d", len(pf.SRIOV.VirtualFunctions))
+	}
+	if vfs := info.VirtualFunctions(); len(vfs) != 8 {
+		t.Errorf("Expected 8 virtual functions, got %d", len(vfs))
+	}
+
+	rootPort := info.GetDevice("0000:00:09.0")
+	if rootPort == nil || len(rootPort.Children) != 10 {
+		t.Fatalf("Expected 10 children for the root port, got %v", rootPort)
+	}
+	roots = info.RootComplexes()
+	if len(roots) != 3 {
+		t.Fatalf("Expected 3 root complexes, got %d", len(roots))
+	}
+	seen := make(map[*pci.Device]bool)
+	for _, dev := range roots[0].Devices {
+		if seen[dev] {
+			t.Errorf("Expected %q once on the root bus of %v", dev.Address, roots[0])
+		}
+		seen[dev] = true
+	}
+}
+
+func TestPCIFilter(t *testing.T) {
+	info := pciTestSetup(t)
+
+	tCases := []struct {
+		name  string
+		preds []pci.Predicate
+		count int
+		first string
+	}{
+		{
+			name:  "no predicates",
+			preds: nil,
+			count: len(info.Devices),
+			first: "0000:00:00.0",
+		},
+		{
+			name:  "network controllers",
+			preds: []pci.Predicate{pci.ByClass("02")},
+			count: 14,
+			first: "0000:01:00.0",
+		},
+		{
+			name:  "Intel network controllers",
+			preds: []pci.Predicate{pci.ByClass("02"), pci.ByVendor("8086")},
+			count: 10,
+			first: "0000:05:00.0",
+		},
+		{
+			name:  "USB controllers, lowercase IDs",
+			preds: []pci.Predicate{pci.BySubclass("0c", "03")},
+			count: 6,
+			first: "0000:00:1a.0",
+		},
+		{
+			name:  "product",
+			preds: []pci.Predicate{pci.ByProduct("14e4", "1639")},
+			count: 4,
+			first: "0000:01:00.0",
+		},
+		{
+			name:  "driver",
+			preds: []pci.Predicate{pci.ByDriver("igbvf")},
+			count: 8,
+			first: "0000:05:10.0",
+		},
+		{
+			name:  "NUMA node",
+			preds: []pci.Predicate{pci.ByNUMANode(1)},
+			count: 5,
+			first: "0000:05:00.1",
+		},
+		{
+			name:  "address prefix",
+			preds: []pci.Predicate{pci.ByAddressPrefix("0000:05:1")},
+			count: 8,
+			first: "0000:05:10.0",
+		},
+		{
+			name:  "no match",
+			preds: []pci.Predicate{pci.ByVendor("10de")},
+			count: 0,
+		},
+	}
+	for _, tCase := range tCases {
+		t.Run(tCase.name, func(t *testing.T) {
+			devs := info.Filter(tCase.preds...)
+			if len(devs) != tCase.count {
+				t.Fatalf("Expected %d devices, got %d", tCase.count, len(devs))
+			}
+			if tCase.count > 0 && devs[0].Address != tCase.first {
+				t.Errorf("Expected %q as first device, got %q", tCase.first, devs[0].Address)
+			}
+		})
+	}
+}
+
+func TestPCIHierarchy(t *testing.T) {
+	info := pciTestSetup(t)
+
+	roots := info.RootComplexes()
+	if len(roots) != 3 {
+		t.Fatalf("Expected 3 root complexes, got %d", len(roots))
+	}
+	rc := roots[0]
+	if rc.Name != "pci0000:00" || rc.Domain != "0000" || rc.Bus != "00" {
+		t.Fatalf("Unexpected root complex %+v", rc)
+	}
+
+	rootPort := info.GetDevice("0000:00:09.0")
+	if rootPort == nil {
+		t.Fatalf("got nil device for address %q", "0000:00:09.0")
+	}
+	if rootPort.Parent != nil {
+		t.Errorf("Expected no parent for the root port, got %v", rootPort.Parent)
+	}
+	found := false
+	for _, dev := range rc.Devices {
+		found = found || dev == rootPort
+	}
+	if !found {
+		t.Errorf("Expected the root port on the root bus of %v", rc)
+	}
+	if len(rootPort.Children) != 10 {
+		t.Fatalf("Expected 10 children for the root port, got %d", len(rootPort.Children))
+	}
+	if rootPort.Children[0].Address != "0000:05:00.0" {
+		t.Errorf("Expected children sorted by address, got %q first", rootPort.Children[0].Address)
+	}
+	for _, child := range rootPort.Children {
+		if child.Parent != rootPort {
+			t.Errorf("Expected %q to point back to the root port", child.Address)
+		}
+	}
+
+	dev := info.GetDevice("0000:07:03.0")
+	if dev == nil || dev.Parent == nil || dev.Parent.Address != "0000:00:1e.0" {
+		t.Fatalf("Expected 0000:07:03.0 behind the 0000:00:1e.0 bridge, got %v", dev)
+	}
+}
+
+func TestPCIDeviceLink(t *testing.T) {
+	tmpRoot := pciTestUnpack(t)
+	defer snapshot.Cleanup(tmpRoot)
+
+	// the snapshot predates the link attributes, so we add them here
+	links := map[string]map[string]string{
+		"0000:05:00.0": {
+			"current_link_speed": "2.5 GT/s PCIe",
+			"current_link_width": "4",
+			"max_link_speed":     "5.0 GT/s PCIe",
+			"max_link_width":     "4",
+		},
+		"0000:05:00.1": {
+			"current_link_speed": "5.0 GT/s PCIe",
+			"current_link_width": "4",
+			"max_link_speed":     "5.0 GT/s PCIe",
+			"max_link_width":     "4",
+		},
+	}
+	for addr, attrs := range links {
+		for attr, val := range attrs {
+			attrPath := filepath.Join(tmpRoot, "sys", "bus", "pci", "devices", addr, attr)
+			if err := ioutil.WriteFile(attrPath, []byte(val+"\n"), 0644); err != nil {
+				t.Fatalf("Unable to write %q: %v", attrPath, err)
+			}
+		}
+	}
+
+	info, err := pci.New(option.WithChroot(tmpRoot))
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+
+	dev := info.GetDevice("0000:05:00.0")
+	if dev == nil || dev.Link == nil {
+		t.Fatalf("Expected link information for 0000:05:00.0, got %v", dev)
+	}
+	if dev.Link.CurrentSpeedGTs != 2.5 || dev.Link.MaxSpeedGTs != 5.0 {
+		t.Errorf("Unexpected link speeds %v", dev.Link)
+	}
+	if dev.Link.CurrentWidth != 4 || dev.Link.MaxWidth != 4 {
+		t.Errorf("Unexpected link widths %v", dev.Link)
+	}
+	if !dev.Link.IsDegraded {
+		t.Errorf("Expected degraded link, got %v", dev.Link)
+	}
+
+	dev = info.GetDevice("0000:05:00.1")
+	if dev == nil || dev.Link == nil {
+		t.Fatalf("Expected link information for 0000:05:00.1, got %v", dev)
+	}
+	if dev.Link.IsDegraded {
+		t.Errorf("Expected healthy link, got %v", dev.Link)
+	}
+
+	// conventional PCI device
+	dev = info.GetDevice("0000:07:03.0")
+	if dev == nil {
+		t.Fatalf("got nil device for address %q", "0000:07:03.0")
+	}
+	if dev.Link != nil {
+		t.Errorf("Expected no link information, got %v", dev.Link)
+	}
+}
+
+func TestPCIDeviceResources(t *testing.T) {
+	tmpRoot := pciTestUnpack(t)
+	defer snapshot.Cleanup(tmpRoot)
+
+	// the snapshot predates the resource, enable and msi_irqs attributes,
+	// so we add them here
+	devPath := filepath.Join(tmpRoot, "sys", "bus", "pci", "devices", "0000:05:00.0")
+	resource := `0x00000000fbe20000 0x00000000fbe3ffff 0x0000000000040200
+0x0000000000000000 0x0000000000000000 0x0000000000000000
+0x000000000000dc00 0x000000000000dc1f 0x0000000000040101
+0x00000000fbe44000 0x00000000fbe47fff 0x0000000000140204
+0x0000000000000000 0x0000000000000000 0x0000000000000000
+0x0000000000000000 0x0000000000000000 0x0000000000000000
+0x00000000fbe00000 0x00000000fbe1ffff 0x0000000000046200
+`
+	attrs := map[string]string{
+		"resource":      resource,
+		"enable":        "1\n",
+		"msi_irqs/89":   "msix\n",
+		"msi_irqs/90":   "msix\n",
+		"msi_irqs/100":  "msix\n",
+		"local_cpulist": "0-2,6,8-9\n",
+	}
+	if err := os.MkdirAll(filepath.Join(devPath, "msi_irqs"), os.ModePerm); err != nil {
+		t.Fatalf("Unable to create the msi_irqs directory: %v", err)
+	}
+	for attr, val := range attrs {
+		if err := ioutil.WriteFile(filepath.Join(devPath, attr), []byte(val), 0644); err != nil {
+			t.Fatalf("Unable to write %q: %v", attr, err)
+		}
+	}
+
+	info, err := pci.New(option.WithChroot(tmpRoot))
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+
+	dev := info.GetDevice("0000:05:00.0")
+	if dev == nil {
+		t.Fatalf("got nil device for address %q", "0000:05:00.0")
+	}
+	// the expansion ROM (last line) is not a BAR
+	if len(dev.BARs) != 3 {
+		t.Fatalf("Expected 3 BARs, got %d", len(dev.BARs))
+	}
+	bar := dev.BARs[0]
+	if bar.Index != 0 || bar.Address != 0xfbe20000 || bar.SizeBytes != 128*1024 || !bar.IsMemory || bar.IsIO {
+		t.Errorf("Unexpected BAR %+v", bar)
+	}
+	bar = dev.BARs[1]
+	if bar.Index != 2 || bar.SizeBytes != 32 || !bar.IsIO {
+		t.Errorf("Unexpected BAR %+v", bar)
+	}
+	bar = dev.BARs[2]
+	if bar.Index != 3 || !bar.Is64Bit || bar.IsPrefetchable {
+		t.Errorf("Unexpected BAR %+v", bar)
+	}
+	if dev.IRQ != 35 {
+		t.Errorf("Expected IRQ 35, got %d", dev.IRQ)
+	}
+	if dev.MSIMode != "msix" || !reflect.DeepEqual(dev.MSIIRQs, []int{89, 90, 100}) {
+		t.Errorf("Unexpected MSI information %q %v", dev.MSIMode, dev.MSIIRQs)
+	}
+	if !reflect.DeepEqual(dev.LocalCPUs, []int{0, 1, 2, 6, 8, 9}) {
+		t.Errorf("Unexpected local CPUs %v", dev.LocalCPUs)
+	}
+	if !dev.IsEnabled {
+		t.Errorf("Expected enabled device")
+	}
+
+	// untouched devices
+	dev = info.GetDevice("0000:05:00.1")
+	if dev == nil {
+		t.Fatalf("got nil device for address %q", "0000:05:00.1")
+	}
+	if len(dev.LocalCPUs) != 12 || dev.LocalCPUs[1] != 3 {
+		t.Errorf("Unexpected local CPUs %v", dev.LocalCPUs)
+	}
+	if dev.IsEnabled || len(dev.BARs) != 0 || dev.MSIMode != "" {
+		t.Errorf("Expected no resource information, got %+v", dev)
+	}
+}
+
+func TestPCIDeviceCapabilities(t *testing.T) {
+	tmpRoot := pciTestUnpack(t)
+	defer snapshot.Cleanup(tmpRoot)
+
+	config := make([]byte, 4096)
+	binary.LittleEndian.PutUint16(config[0x06:], 0x0010) // capabilities list
+	config[0x34] = 0x40
+	// standard capabilities: ID, next pointer
+	for _, c := range [][3]int{
+		{0x40, 0x01, 0x50}, // Power Management
+		{0x50, 0x05, 0x70}, // MSI
+		{0x70, 0x10, 0xB0}, // PCI Express
+		{0xB0, 0x11, 0x00}, // MSI-X
+	} {
+		config[c[0]] = uint8(c[1])
+		config[c[0]+1] = uint8(c[2])
+	}
+	// extended capabilities: ID, next pointer
+	for _, c := range [][3]int{
+		{0x100, 0x0001, 0x140}, // AER
+		{0x140, 0x000D, 0x160}, // ACS
+		{0x160, 0x0010, 0x1A0}, // SR-IOV
+		{0x1A0, 0x000F, 0x1C0}, // ATS
+		{0x1C0, 0x001B, 0x000}, // PASID
+	} {
+		header := uint32(c[1]) | 1<<16 | uint32(c[2])<<20
+		binary.LittleEndian.PutUint32(config[c[0]:], header)
+	}
+
+	devicesPath := filepath.Join(tmpRoot, "sys", "bus", "pci", "devices")
+	if err := ioutil.WriteFile(filepath.Join(devicesPath, "0000:05:00.0", "config"), config, 0644); err != nil {
+		t.Fatalf("Unable to write the configuration space: %v", err)
+	}
+	// what unprivileged users can read
+	if err := ioutil.WriteFile(filepath.Join(devicesPath, "0000:05:00.1", "config"), config[:64], 0644); err != nil {
+		t.Fatalf("Unable to write the configuration space: %v", err)
+	}
+
+	info, err := pci.New(
+		option.WithChroot(tmpRoot),
+		option.WithPCIOptions(option.PCIOptions{IncludeCapabilities: true}),
+	)
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+
+	dev := info.GetDevice("0000:05:00.0")
+	if dev == nil {
+		t.Fatalf("got nil device for address %q", "0000:05:00.0")
+	}
+	expected := []struct {
+		name     string
+		offset   int
+		extended bool
+	}{
+		{"Power Management", 0x40, false},
+		{"MSI", 0x50, false},
+		{"PCI Express", 0x70, false},
+		{"MSI-X", 0xB0, false},
+		{"AER", 0x100, true},
+		{"ACS", 0x140, true},
+		{"SR-IOV", 0x160, true},
+		{"ATS", 0x1A0, true},
+		{"PASID", 0x1C0, true},
+	}
+	if len(dev.Capabilities) != len(expected) {
+		t.Fatalf("Expected %d capabilities, got %v", len(expected), dev.Capabilities)
+	}
+	for idx, exp := range expected {
+		capability := dev.Capabilities[idx]
+		if capability.Name != exp.name || capability.Offset != exp.offset || capability.IsExtended != exp.extended {
+			t.Errorf("Expected capability %d to be %+v, got %+v", idx, exp, capability)
+		}
+	}
+	if !dev.HasCapability("ACS") || dev.HasCapability("DPC") {
+		t.Errorf("Unexpected HasCapability results for %v", dev.Capabilities)
+	}
+
+	dev = info.GetDevice("0000:05:00.1")
+	if dev == nil {
+		t.Fatalf("got nil device for address %q", "0000:05:00.1")
+	}
+	if dev.Capabilities != nil {
+		t.Errorf("Expected unknown capabilities from a truncated configuration space, got %v", dev.Capabilities)
+	}
+
+	// a device without capabilities
+	noCaps := make([]byte, 256)
+	if err := ioutil.WriteFile(filepath.Join(devicesPath, "0000:05:00.1", "config"), noCaps, 0644); err != nil {
+		t.Fatalf("Unable to write the configuration space: %v", err)
+	}
+	info, err = pci.New(
+		option.WithChroot(tmpRoot),
+		option.WithPCIOptions(option.PCIOptions{IncludeCapabilities: true}),
+	)
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if dev = info.GetDevice("0000:05:00.1"); dev.Capabilities == nil || len(dev.Capabilities) != 0 {
+		t.Errorf("Expected no capabilities, got %v", dev.Capabilities)
+	}
+
+	// the capabilities are reported only on request
+	info, err = pci.New(option.WithChroot(tmpRoot))
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if dev = info.GetDevice("0000:05:00.0"); dev.Capabilities != nil {
+		t.Errorf("Expected no capabilities by default, got %v", dev.Capabilities)
+	}
+}
+
+func TestPCIIOMMUGroups(t *testing.T) {
+	tmpRoot := pciTestUnpack(t)
+	defer snapshot.Cleanup(tmpRoot)
+
+	// the snapshot was taken with the IOMMU disabled, so we add the groups
+	// here. Device 0000:07:03.0 is left out of any group.
+	groups := map[string]int{
+		"0000:05:00.0": 10,
+		"0000:05:10.0": 11,
+		"0000:05:10.4": 11,
+		"0000:05:11.0": 12,
+	}
+	for addr, groupID := range groups {
+		groupDir := filepath.Join(tmpRoot, "sys", "kernel", "iommu_groups", fmt.Sprintf("%d", groupID), "devices")
+		if err := os.MkdirAll(groupDir, os.ModePerm); err != nil {
+			t.Fatalf("Unable to create %q: %v", groupDir, err)
+		}
+		devPath := filepath.Join(tmpRoot, "sys", "bus", "pci", "devices", addr)
+		groupLink := fmt.Sprintf("../../../../kernel/iommu_groups/%d", groupID)
+		if err := os.Symlink(groupLink, filepath.Join(devPath, "iommu_group")); err != nil {
+			t.Fatalf("Unable to link %q to its IOMMU group: %v", addr, err)
+		}
+	}
+	vfioDir := filepath.Join(tmpRoot, "sys", "bus", "pci", "drivers", "vfio-pci")
+	if err := os.MkdirAll(vfioDir, os.ModePerm); err != nil {
+		t.Fatalf("Unable to create %q: %v", vfioDir, err)
+	}
+	for _, addr := range []string{"0000:05:10.0", "0000:05:11.0"} {
+		driverPath := filepath.Join(tmpRoot, "sys", "bus", "pci", "devices", addr, "driver")
+		if err := os.Remove(driverPath); err != nil {
+			t.Fatalf("Unable to remove %q: %v", driverPath, err)
+		}
+		if err := os.Symlink("../../../../bus/pci/drivers/vfio-pci", driverPath); err != nil {
+			t.Fatalf("Unable to bind %q to vfio-pci: %v", addr, err)
+		}
+	}
+
+	info, err := pci.New(option.WithChroot(tmpRoot))
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+
+	iommuGroups := info.IOMMUGroups()
+	if len(iommuGroups) != 3 {
+		t.Fatalf("Expected 3 IOMMU groups, got %d", len(iommuGroups))
+	}
+	group := iommuGroups[1]
+	if group.ID != 11 || len(group.Devices) != 2 {
+		t.Fatalf("Unexpected IOMMU group %v", group)
+	}
+	if group.Devices[0].Address != "0000:05:10.0" || group.Devices[1].Address != "0000:05:10.4" {
+		t.Errorf("Unexpected devices in IOMMU group %v", group)
+	}
+	if group.IsBoundToVFIO() {
+		t.Errorf("Expected IOMMU group %v not to be bound to VFIO", group)
+	}
+	if !iommuGroups[2].IsBoundToVFIO() {
+		t.Errorf("Expected IOMMU group %v to be bound to VFIO", iommuGroups[2])
+	}
+
+	// the devices are cached, so listing them again must not add them to
+	// their groups twice
+	info.ListDevices()
+	if group := info.GetDevice("0000:05:10.0").IOMMUGroup; len(group.Devices) != 2 {
+		t.Errorf("Expected 2 devices in IOMMU group %v after listing the devices again", group)
+	}
+
+	tCases := map[string]pci.VFIOReadiness{
+		"0000:05:00.0": pci.VFIO_READINESS_NON_VFIO_DRIVER,
+		"0000:05:10.0": pci.VFIO_READINESS_SHARED_GROUP,
+		"0000:05:11.0": pci.VFIO_READINESS_READY,
+		"0000:07:03.0": pci.VFIO_READINESS_IOMMU_DISABLED,
+	}
+	for addr, readiness := range tCases {
+		t.Run(fmt.Sprintf("%s (%s)", addr, readiness), func(t *testing.T) {
+			dev := info.GetDevice(addr)
+			if dev == nil {
+				t.Fatalf("got nil device for address %q", addr)
+			}
+			if got := dev.VFIOReadiness(); got != readiness {
+				t.Errorf("got VFIO readiness %q expected %q", got, readiness)
+			}
+		})
+	}
+
+	if s := info.JSONString(false); s == "" {
+		t.Fatalf("Error marshalling the PCI info")
+	}
+}
+
+func TestPCIDrivers(t *testing.T) {
+	tmpRoot := pciTestUnpack(t)
+	defer snapshot.Cleanup(tmpRoot)
+
+	// the snapshot carries neither the module links of the drivers nor the
+	// module aliases of the kernel, so we add them here
+	driversPath := filepath.Join(tmpRoot, "sys", "bus", "pci", "drivers")
+	for _, drv := range []string{"igb", "igbvf"} {
+		if err := os.Symlink("../../../../module/"+drv, filepath.Join(driversPath, drv, "module")); err != nil {
+			t.Fatalf("Unable to link the %q driver to its module: %v", drv, err)
+		}
+	}
+	release := "5.10.0-test"
+	modulesDir := filepath.Join(tmpRoot, "lib", "modules", release)
+	if err := os.MkdirAll(modulesDir, os.ModePerm); err != nil {
+		t.Fatalf("Unable to create %q: %v", modulesDir, err)
+	}
+	aliases := `# Aliases extracted from modules themselves.
+alias pci:v00008086d00001520sv*sd*bc*sc*i* igbvf
+alias pci:v00008086d00001521sv*sd*bc*sc*i* igb
+alias pci:v*d*sv*sd*bc02sc00i* fake_nic
+alias usb:v0BDAp8153d*dc*dsc*dp*ic*isc*ip*in* r8152
+`
+	if err := ioutil.WriteFile(filepath.Join(modulesDir, "modules.alias"), []byte(aliases), 0644); err != nil {
+		t.Fatalf("Unable to write the module aliases: %v", err)
+	}
+	builtinAliases := "alias pci:v00008086d00001520sv*sd*bc*sc*i* igbvf\n"
+	if err := ioutil.WriteFile(filepath.Join(modulesDir, "modules.builtin.alias"), []byte(builtinAliases), 0644); err != nil {
+		t.Fatalf("Unable to write the builtin module aliases: %v", err)
+	}
+	osReleasePath := filepath.Join(tmpRoot, "proc", "sys", "kernel", "osrelease")
+	if err := os.MkdirAll(filepath.Dir(osReleasePath), os.ModePerm); err != nil {
+		t.Fatalf("Unable to create %q: %v", filepath.Dir(osReleasePath), err)
+	}
+	if err := ioutil.WriteFile(osReleasePath, []byte(release+"\n"), 0644); err != nil {
+		t.Fatalf("Unable to write %q: %v", osReleasePath, err)
+	}
+	overrides := map[string]string{
+		"0000:05:10.0": "vfio-pci",
+		"0000:05:10.1": "(null)",
+	}
+	for addr, override := range overrides {
+		overridePath := filepath.Join(tmpRoot, "sys", "bus", "pci", "devices", addr, "driver_override")
+		if err := ioutil.WriteFile(overridePath, []byte(override+"\n"), 0644); err != nil {
+			t.Fatalf("Unable to write %q: %v", overridePath, err)
+		}
+	}
+
+	info, err := pci.New(
+		option.WithChroot(tmpRoot),
+		option.WithPCIOptions(option.PCIOptions{IncludeCandidateDrivers: true}),
+	)
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+
+	if len(info.Drivers()) != 31 {
+		t.Fatalf("Expected 31 drivers, got %d", len(info.Drivers()))
+	}
+	drv := info.GetDriver("igbvf")
+	if drv == nil {
+		t.Fatalf("Expected to find the igbvf driver")
+	}
+	if drv.Module != "igbvf" {
+		t.Errorf("Expected module 'igbvf', got %q", drv.Module)
+	}
+	if len(drv.Devices) != 8 || drv.Devices[0].Address != "0000:05:10.0" {
+		t.Errorf("Unexpected devices bound to %v", drv)
+	}
+	drv = info.GetDriver("ahci")
+	if drv == nil || drv.Module != "" || len(drv.Devices) != 0 {
+		t.Errorf("Expected the ahci driver built in and bound to no device, got %v", drv)
+	}
+	if info.GetDriver("vfio-pci") != nil {
+		t.Errorf("Expected no vfio-pci driver")
+	}
+
+	dev := info.GetDevice("0000:05:10.0")
+	if dev.DriverOverride != "vfio-pci" {
+		t.Errorf("Expected driver override 'vfio-pci', got %q", dev.DriverOverride)
+	}
+	if !reflect.DeepEqual(dev.CandidateDrivers, []string{"fake_nic", "igbvf"}) {
+		t.Errorf("Unexpected candidate drivers %v", dev.CandidateDrivers)
+	}
+	dev = info.GetDevice("0000:05:10.1")
+	if dev.DriverOverride != "" {
+		t.Errorf("Expected no driver override, got %q", dev.DriverOverride)
+	}
+	dev = info.GetDevice("0000:05:00.0")
+	if !reflect.DeepEqual(dev.CandidateDrivers, []string{"fake_nic", "igb"}) {
+		t.Errorf("Unexpected candidate drivers %v", dev.CandidateDrivers)
+	}
+	dev = info.GetDevice("0000:07:03.0")
+	if len(dev.CandidateDrivers) != 0 {
+		t.Errorf("Expected no candidate drivers, got %v", dev.CandidateDrivers)
+	}
+
+	// the candidate drivers are reported only on request
+	info, err = pci.New(option.WithChroot(tmpRoot))
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if dev = info.GetDevice("0000:05:10.0"); dev.CandidateDrivers != nil {
+		t.Errorf("Expected no candidate drivers by default, got %v", dev.CandidateDrivers)
+	}
+}
+
+// pciTestUnpack unpacks the multi-NUMA snapshot into a temporary directory,
+// for the tests which need to alter it
+func pciTestUnpack(t *testing.T) string {
+	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_PCI"); ok {
+		t.Skip("Skipping PCI tests.")
+	}
+
+	testdataPath, err := testdata.SnapshotsDirectory()
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	multiNumaSnapshot := filepath.Join(testdataPath, "linux-amd64-intel-xeon-L5640.tar.gz")
+
+	tmpRoot, err := ioutil.TempDir("", "ghw-pci-testing-*")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	_, err = snapshot.UnpackInto(multiNumaSnapshot, tmpRoot, 0)
+	if err != nil {
+		t.Fatalf("Unable to unpack %q into %q: %v", multiNumaSnapshot, tmpRoot, err)
+	}
+	return tmpRoot
+}
+
 func pciTestSetup(t *testing.T) *pci.Info {
 	if _, ok := os.LookupEnv("GHW_TESTING_SKIP_PCI"); ok {
 		t.Skip("Skipping PCI tests.")
@@ -10,14 +10,15 @@ import (
 )
 
 func (i *Info) load() error {
+	dmi := linuxdmi.NewItems(i.ctx)
 
-	i.Family = linuxdmi.Item(i.ctx, "product_family")
-	i.Name = linuxdmi.Item(i.ctx, "product_name")
-	i.Vendor = linuxdmi.Item(i.ctx, "sys_vendor")
-	i.SerialNumber = linuxdmi.Item(i.ctx, "product_serial")
-	i.UUID = linuxdmi.Item(i.ctx, "product_uuid")
-	i.SKU = linuxdmi.Item(i.ctx, "product_sku")
-	i.Version = linuxdmi.Item(i.ctx, "product_version")
+	i.Family = dmi.Item("product_family")
+	i.Name = dmi.Item("product_name")
+	i.Vendor = dmi.Item("sys_vendor")
+	i.SerialNumber = dmi.Item("product_serial")
+	i.UUID = dmi.Item("product_uuid")
+	i.SKU = dmi.Item("product_sku")
+	i.Version = dmi.Item("product_version")
 
 	return nil
 }
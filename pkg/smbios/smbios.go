@@ -0,0 +1,143 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package smbios
+
+import (
+	"fmt"
+
+	"github.com/jaypipes/ghw/pkg/context"
+	"github.com/jaypipes/ghw/pkg/marshal"
+	"github.com/jaypipes/ghw/pkg/option"
+)
+
+// Info describes the SMBIOS structure table exposed by the system firmware
+type Info struct {
+	ctx        *context.Context
+	EntryPoint *EntryPoint `json:"entry_point"`
+	// All the raw structures found in the table, in table order. Use this
+	// field to access the structures (or the fields) ghw doesn't decode.
+	Structures []*Structure `json:"-"`
+	// BIOS and System are nil if the table doesn't contain the corresponding
+	// structure
+	BIOS           *BIOS                  `json:"bios"`
+	System         *System                `json:"system"`
+	Baseboards     []*Baseboard           `json:"baseboards"`
+	Chassis        []*Chassis             `json:"chassis"`
+	Processors     []*Processor           `json:"processors"`
+	Caches         []*Cache               `json:"caches"`
+	PortConnectors []*PortConnector       `json:"port_connectors"`
+	SystemSlots    []*SystemSlot          `json:"system_slots"`
+	OEMStrings     []string               `json:"oem_strings"`
+	MemoryArrays   []*PhysicalMemoryArray `json:"memory_arrays"`
+	MemoryDevices  []*MemoryDevice        `json:"memory_devices"`
+	PowerSupplies  []*PowerSupply         `json:"power_supplies"`
+}
+
+// New returns a pointer to an Info struct that contains the decoded SMBIOS
+// structure table of the host system
+func New(opts ...*option.Option) (*Info, error) {
+	return NewWithContext(context.New(opts...))
+}
+
+// NewWithContext returns a pointer to an Info struct that contains the
+// decoded SMBIOS structure table of the host system. Use this function when
+// you want to consume the smbios package from another package (e.g. memory)
+func NewWithContext(ctx *context.Context) (*Info, error) {
+	info := &Info{ctx: ctx}
+	if err := ctx.Do(info.load); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (i *Info) String() string {
+	version := "unknown version"
+	if i.EntryPoint != nil {
+		version = i.EntryPoint.Version()
+	}
+	return fmt.Sprintf(
+		"smbios %s (%d structures)",
+		version,
+		len(i.Structures),
+	)
+}
+
+// StructuresByType returns all the raw structures of the given type, in table
+// order
+func (i *Info) StructuresByType(structType uint8) []*Structure {
+	structs := make([]*Structure, 0)
+	for _, s := range i.Structures {
+		if s.Header.Type == structType {
+			structs = append(structs, s)
+		}
+	}
+	return structs
+}
+
+// decode fills the typed records from the raw structures
+func (i *Info) decode() {
+	i.Baseboards = make([]*Baseboard, 0)
+	i.Chassis = make([]*Chassis, 0)
+	i.Processors = make([]*Processor, 0)
+	i.Caches = make([]*Cache, 0)
+	i.PortConnectors = make([]*PortConnector, 0)
+	i.SystemSlots = make([]*SystemSlot, 0)
+	i.OEMStrings = make([]string, 0)
+	i.MemoryArrays = make([]*PhysicalMemoryArray, 0)
+	i.MemoryDevices = make([]*MemoryDevice, 0)
+	i.PowerSupplies = make([]*PowerSupply, 0)
+	for _, s := range i.Structures {
+		switch s.Header.Type {
+		case TypeBIOS:
+			if i.BIOS == nil {
+				i.BIOS = parseBIOS(s)
+			}
+		case TypeSystem:
+			if i.System == nil {
+				i.System = parseSystem(s, i.EntryPoint)
+			}
+		case TypeBaseboard:
+			i.Baseboards = append(i.Baseboards, parseBaseboard(s))
+		case TypeChassis:
+			i.Chassis = append(i.Chassis, parseChassis(s))
+		case TypeProcessor:
+			i.Processors = append(i.Processors, parseProcessor(s))
+		case TypeCache:
+			i.Caches = append(i.Caches, parseCache(s))
+		case TypePortConnector:
+			i.PortConnectors = append(i.PortConnectors, parsePortConnector(s))
+		case TypeSystemSlot:
+			i.SystemSlots = append(i.SystemSlots, parseSystemSlot(s))
+		case TypeOEMStrings:
+			i.OEMStrings = append(i.OEMStrings, parseOEMStrings(s)...)
+		case TypePhysicalMemoryArray:
+			i.MemoryArrays = append(i.MemoryArrays, parsePhysicalMemoryArray(s))
+		case TypeMemoryDevice:
+			i.MemoryDevices = append(i.MemoryDevices, parseMemoryDevice(s))
+		case TypePowerSupply:
+			i.PowerSupplies = append(i.PowerSupplies, parsePowerSupply(s))
+		}
+	}
+}
+
+// simple private struct used to encapsulate SMBIOS information in a top-level
+// "smbios" YAML/JSON map/object key
+type smbiosPrinter struct {
+	Info *Info `json:"smbios"`
+}
+
+// YAMLString returns a string with the SMBIOS information formatted as YAML
+// under a top-level "smbios:" key
+func (i *Info) YAMLString() string {
+	return marshal.SafeYAML(i.ctx, smbiosPrinter{i})
+}
+
+// JSONString returns a string with the SMBIOS information formatted as JSON
+// under a top-level "smbios:" key
+func (i *Info) JSONString(indent bool) string {
+	return marshal.SafeJSON(i.ctx, smbiosPrinter{i}, indent)
+}
@@ -0,0 +1,41 @@
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package smbios
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/jaypipes/ghw/pkg/linuxpath"
+)
+
+func (i *Info) load() error {
+	// The kernel exposes both the SMBIOS entry point and the raw structure
+	// table under /sys/firmware/dmi/tables. Both files are usually readable
+	// by root only.
+	paths := linuxpath.New(i.ctx)
+	epData, err := ioutil.ReadFile(filepath.Join(paths.SysFirmwareDMITables, "smbios_entry_point"))
+	if err != nil {
+		return err
+	}
+	ep, err := ParseEntryPoint(epData)
+	if err != nil {
+		return err
+	}
+	tableData, err := ioutil.ReadFile(filepath.Join(paths.SysFirmwareDMITables, "DMI"))
+	if err != nil {
+		return err
+	}
+	structs, err := ParseStructures(tableData)
+	if err != nil {
+		// we can still make good use of the structures we decoded
+		i.ctx.Warn("error decoding the SMBIOS structure table: %v", err)
+	}
+	i.EntryPoint = ep
+	i.Structures = structs
+	i.decode()
+	return nil
+}
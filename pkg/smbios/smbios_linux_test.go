@@ -0,0 +1,533 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+// +build linux
+
+package smbios_test
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jaypipes/ghw/pkg/context"
+	"github.com/jaypipes/ghw/pkg/linuxdmi"
+	"github.com/jaypipes/ghw/pkg/option"
+	"github.com/jaypipes/ghw/pkg/smbios"
+	"github.com/jaypipes/ghw/pkg/util"
+)
+
+// smbiosStruct builds a raw SMBIOS structure. `formatted` is the formatted
+// area of the structure, *excluding* the header.
+func smbiosStruct(structType uint8, handle uint16, formatted []byte, strs ...string) []byte {
+	data := []byte{structType, uint8(4 + len(formatted)), 0, 0}
+	binary.LittleEndian.PutUint16(data[2:], handle)
+	data = append(data, formatted...)
+	if len(strs) == 0 {
+		return append(data, 0, 0)
+	}
+	for _, s := range strs {
+		data = append(data, []byte(s)...)
+		data = append(data, 0)
+	}
+	return append(data, 0)
+}
+
+// memoryDeviceStruct builds a SMBIOS 2.8 Memory Device (type 17) structure.
+func memoryDeviceStruct(handle, size uint16, extSize uint32, speed uint16, strs ...string) []byte {
+	// offsets are relative to the beginning of the structure, header included
+	formatted := make([]byte, 0x28-4)
+	put16 := func(offset int, val uint16) {
+		binary.LittleEndian.PutUint16(formatted[offset-4:], val)
+	}
+	put16(0x04, 0x1000)
+	put16(0x08, 72)
+	put16(0x0A, 64)
+	put16(0x0C, size)
+	formatted[0x0E-4] = 0x09 // DIMM
+	formatted[0x10-4] = 1    // device locator
+	formatted[0x11-4] = 2    // bank locator
+	formatted[0x12-4] = 0x1A // DDR4
+	put16(0x15, speed)
+	if len(strs) > 2 {
+		formatted[0x17-4] = 3 // manufacturer
+		formatted[0x18-4] = 4 // serial
+		formatted[0x19-4] = 5 // asset tag
+		formatted[0x1A-4] = 6 // part number
+	}
+	formatted[0x1B-4] = 2 // dual rank
+	binary.LittleEndian.PutUint32(formatted[0x1C-4:], extSize)
+	put16(0x20, speed)
+	return smbiosStruct(smbios.TypeMemoryDevice, handle, formatted, strs...)
+}
+
+// formattedArea returns a zeroed formatted area for a structure of the given
+// length, along with setters taking offsets relative to the beginning of the
+// structure, header included.
+func formattedArea(length int) ([]byte, func(int, uint8), func(int, uint16), func(int, uint32)) {
+	formatted := make([]byte, length-4)
+	put8 := func(offset int, val uint8) {
+		formatted[offset-4] = val
+	}
+	put16 := func(offset int, val uint16) {
+		binary.LittleEndian.PutUint16(formatted[offset-4:], val)
+	}
+	put32 := func(offset int, val uint32) {
+		binary.LittleEndian.PutUint32(formatted[offset-4:], val)
+	}
+	return formatted, put8, put16, put32
+}
+
+// systemTable builds a structure table containing one structure of each of
+// the types decoded by the smbios package, besides the memory devices
+func systemTable() []byte {
+	var table []byte
+
+	f, put8, put16, _ := formattedArea(0x1A)
+	put8(0x04, 1)
+	put8(0x05, 2)
+	put8(0x08, 3)
+	put8(0x09, 0xFF)
+	put8(0x14, 1)
+	put8(0x15, 2)
+	put8(0x16, 0xFF)
+	put8(0x17, 0xFF)
+	put16(0x18, 16)
+	table = append(table, smbiosStruct(smbios.TypeBIOS, 0x0000, f, "Acme", "1.2.3", "03/14/2020")...)
+
+	f, put8, _, _ = formattedArea(0x1B)
+	for idx := 0; idx < 16; idx++ {
+		put8(0x08+idx, uint8(idx))
+	}
+	put8(0x04, 1)
+	put8(0x05, 2)
+	put8(0x06, 3)
+	put8(0x07, 4)
+	put8(0x19, 5)
+	put8(0x1A, 6)
+	table = append(table, smbiosStruct(smbios.TypeSystem, 0x0100, f,
+		"Acme", "Server 9000", "v2", "SRV123", "SKU-9000", "Servers")...)
+
+	f, put8, put16, _ = formattedArea(0x0F)
+	for idx := 0; idx < 5; idx++ {
+		put8(0x04+idx, uint8(idx+1))
+	}
+	put8(0x0A, 6)
+	put16(0x0B, 0x0300)
+	put8(0x0D, 0x0A)
+	table = append(table, smbiosStruct(smbios.TypeBaseboard, 0x0200, f,
+		"Acme", "MB-1", "rev A", "MB123", "MB-Tag", "Bottom")...)
+
+	// one contained element, 3 bytes long
+	f, put8, _, _ = formattedArea(0x19)
+	put8(0x04, 1)
+	put8(0x05, 0x80|0x17)
+	put8(0x06, 2)
+	put8(0x07, 3)
+	put8(0x08, 4)
+	put8(0x09, 0x03)
+	put8(0x0A, 0x03)
+	put8(0x0B, 0x04)
+	put8(0x11, 2)
+	put8(0x12, 2)
+	put8(0x13, 1)
+	put8(0x14, 3)
+	put8(0x18, 5)
+	table = append(table, smbiosStruct(smbios.TypeChassis, 0x0300, f,
+		"Acme", "C1", "CH123", "CH-Tag", "CH-SKU")...)
+
+	f, put8, put16, _ = formattedArea(0x30)
+	put8(0x04, 1)
+	put8(0x05, 0x03)
+	put8(0x06, 0xFE)
+	put8(0x07, 2)
+	put8(0x10, 3)
+	put16(0x12, 100)
+	put16(0x14, 4000)
+	put16(0x16, 2600)
+	put8(0x18, 0x41)
+	put16(0x1A, 0x0700)
+	put16(0x1C, 0x0701)
+	put16(0x1E, 0xFFFF)
+	put8(0x23, 0xFF)
+	put8(0x24, 0xFF)
+	put8(0x25, 0xFF)
+	put16(0x28, 0x0101)
+	put16(0x2A, 300)
+	put16(0x2C, 290)
+	put16(0x2E, 600)
+	table = append(table, smbiosStruct(smbios.TypeProcessor, 0x0400, f,
+		"CPU0", "Acme", "Acme Processor 9000")...)
+
+	f, put8, put16, put32 := formattedArea(0x1B)
+	put8(0x04, 1)
+	put16(0x05, 0x0181)
+	put16(0x07, 0x8000|16)
+	put16(0x09, 0xFFFF)
+	put8(0x10, 0x05)
+	put8(0x11, 0x05)
+	put8(0x12, 0x08)
+	put32(0x17, 2048)
+	table = append(table, smbiosStruct(smbios.TypeCache, 0x0701, f, "L2 Cache")...)
+
+	f, put8, _, _ = formattedArea(0x09)
+	put8(0x04, 1)
+	put8(0x06, 2)
+	put8(0x07, 0x0B)
+	put8(0x08, 0x1F)
+	table = append(table, smbiosStruct(smbios.TypePortConnector, 0x0800, f, "J1", "LAN1")...)
+
+	f, put8, put16, _ = formattedArea(0x11)
+	put8(0x04, 1)
+	put8(0x05, 0xB6)
+	put8(0x06, 0x0D)
+	put8(0x07, 0x04)
+	put8(0x08, 0x04)
+	put16(0x09, 1)
+	put8(0x0F, 0x3B)
+	put8(0x10, 2<<3|1)
+	table = append(table, smbiosStruct(smbios.TypeSystemSlot, 0x0900, f, "PCIE1")...)
+
+	f, put8, _, _ = formattedArea(0x05)
+	put8(0x04, 2)
+	table = append(table, smbiosStruct(smbios.TypeOEMStrings, 0x0B00, f, "foo", "bar")...)
+
+	f, put8, put16, put32 = formattedArea(0x17)
+	put8(0x04, 0x03)
+	put8(0x05, 0x03)
+	put8(0x06, 0x06)
+	put32(0x07, 0x80000000)
+	put16(0x0D, 16)
+	binary.LittleEndian.PutUint64(f[0x0F-4:], 2*1024*1024*1024*1024)
+	table = append(table, smbiosStruct(smbios.TypePhysicalMemoryArray, 0x1000, f)...)
+
+	f, put8, put16, _ = formattedArea(0x16)
+	put8(0x04, 1)
+	for idx := 0; idx < 7; idx++ {
+		put8(0x05+idx, uint8(idx+1))
+	}
+	put16(0x0C, 750)
+	put16(0x0E, 0x1183)
+	table = append(table, smbiosStruct(smbios.TypePowerSupply, 0x2700, f,
+		"PSU1", "Power Supply 1", "Acme", "PSU123", "PSU-Tag", "PSU-750", "1.0")...)
+
+	return append(table, smbiosStruct(smbios.TypeEndOfTable, 0xFFFF, nil)...)
+}
+
+func smbiosTestSetup(t *testing.T, table []byte) string {
+	root, err := ioutil.TempDir("", "ghw-smbios-testing-*")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	tablesDir := filepath.Join(root, "sys", "firmware", "dmi", "tables")
+	if err := os.MkdirAll(tablesDir, os.ModePerm); err != nil {
+		t.Fatalf("Unable to create %q: %v", tablesDir, err)
+	}
+
+	// SMBIOS 3.2.0 64-bit entry point
+	ep := make([]byte, 0x18)
+	copy(ep, "_SM3_")
+	ep[0x06] = 0x18
+	ep[0x07] = 3
+	ep[0x08] = 2
+	binary.LittleEndian.PutUint32(ep[0x0C:], uint32(len(table)))
+
+	if err := ioutil.WriteFile(filepath.Join(tablesDir, "smbios_entry_point"), ep, 0644); err != nil {
+		t.Fatalf("Unable to write the entry point: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tablesDir, "DMI"), table, 0644); err != nil {
+		t.Fatalf("Unable to write the structure table: %v", err)
+	}
+	return root
+}
+
+func TestSMBIOSMemoryDevices(t *testing.T) {
+	var table []byte
+	table = append(table, memoryDeviceStruct(0x1100, 16384, 0, 2666,
+		"DIMM_A1", "NODE 1", "Samsung", "0123ABCD", "A1_AssetTag", "M393A2K43BB1-CTD  ")...)
+	// empty slot: no strings besides the locators
+	table = append(table, memoryDeviceStruct(0x1101, 0, 0, 0, "DIMM_A2", "NODE 1")...)
+	// 64GB module: size reported in the extended size field
+	table = append(table, memoryDeviceStruct(0x1102, 0x7FFF, 65536, 0xFFFF,
+		"DIMM_B1", "NODE 2", "Hynix", "4567EF01", "B1_AssetTag", "HMAA8GR7AJR4N-XN")...)
+	table = append(table, smbiosStruct(smbios.TypeEndOfTable, 0xFFFF, nil)...)
+
+	root := smbiosTestSetup(t, table)
+	defer os.RemoveAll(root)
+
+	info, err := smbios.New(option.WithChroot(root))
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if info.EntryPoint.Version() != "3.2.0" {
+		t.Errorf("Expected version 3.2.0, got %q", info.EntryPoint.Version())
+	}
+	if len(info.Structures) != 4 {
+		t.Fatalf("Expected 4 structures, got %d", len(info.Structures))
+	}
+	if len(info.MemoryDevices) != 3 {
+		t.Fatalf("Expected 3 memory devices, got %d", len(info.MemoryDevices))
+	}
+
+	md := info.MemoryDevices[0]
+	if md.SizeBytes != 16*1024*1024*1024 {
+		t.Errorf("Expected 16GB module, got %d bytes", md.SizeBytes)
+	}
+	if md.DeviceLocator != "DIMM_A1" || md.BankLocator != "NODE 1" {
+		t.Errorf("Unexpected locators %q %q", md.DeviceLocator, md.BankLocator)
+	}
+	if md.Type != "DDR4" || md.FormFactor != "DIMM" {
+		t.Errorf("Unexpected type/form factor %q %q", md.Type, md.FormFactor)
+	}
+	if md.SpeedMTs != 2666 || md.ConfiguredSpeedMTs != 2666 {
+		t.Errorf("Unexpected speeds %d %d", md.SpeedMTs, md.ConfiguredSpeedMTs)
+	}
+	if md.Manufacturer != "Samsung" || md.SerialNumber != "0123ABCD" {
+		t.Errorf("Unexpected manufacturer/serial %q %q", md.Manufacturer, md.SerialNumber)
+	}
+	if md.PartNumber != "M393A2K43BB1-CTD" {
+		t.Errorf("Expected trimmed part number, got %q", md.PartNumber)
+	}
+	if md.Rank != 2 {
+		t.Errorf("Expected rank 2, got %d", md.Rank)
+	}
+
+	if info.MemoryDevices[1].SizeBytes != 0 {
+		t.Errorf("Expected empty slot, got %d bytes", info.MemoryDevices[1].SizeBytes)
+	}
+	if info.MemoryDevices[1].Manufacturer != "" {
+		t.Errorf("Expected no manufacturer, got %q", info.MemoryDevices[1].Manufacturer)
+	}
+
+	md = info.MemoryDevices[2]
+	if md.SizeBytes != 64*1024*1024*1024 {
+		t.Errorf("Expected 64GB module, got %d bytes", md.SizeBytes)
+	}
+	// 0xFFFF means "look at the extended speed", which is missing in 2.8
+	if md.SpeedMTs != 0 {
+		t.Errorf("Expected unknown speed, got %d", md.SpeedMTs)
+	}
+}
+
+func TestSMBIOSParseStructuresMalformed(t *testing.T) {
+	table := memoryDeviceStruct(0x1100, 16384, 0, 2666, "DIMM_A1", "NODE 1")
+	// cut the string-set terminator away
+	structs, err := smbios.ParseStructures(table[:len(table)-1])
+	if err == nil {
+		t.Fatalf("Expected error parsing a truncated table")
+	}
+	if len(structs) != 0 {
+		t.Fatalf("Expected no structures, got %d", len(structs))
+	}
+
+	if _, err := smbios.ParseEntryPoint([]byte("_XX_junk")); err == nil {
+		t.Fatalf("Expected error parsing a junk entry point")
+	}
+}
+
+func TestSMBIOSSystemRecords(t *testing.T) {
+	root := smbiosTestSetup(t, systemTable())
+	defer os.RemoveAll(root)
+
+	info, err := smbios.New(option.WithChroot(root))
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+
+	bios := info.BIOS
+	if bios == nil {
+		t.Fatalf("Expected BIOS information")
+	}
+	if bios.Vendor != "Acme" || bios.Version != "1.2.3" || bios.ReleaseDate != "03/14/2020" {
+		t.Errorf("Unexpected BIOS %+v", bios)
+	}
+	if bios.ROMSizeBytes != 16*1024*1024 {
+		t.Errorf("Expected 16MB ROM, got %d bytes", bios.ROMSizeBytes)
+	}
+	if bios.BIOSRelease != "1.2" || bios.FirmwareRelease != "" {
+		t.Errorf("Unexpected releases %q %q", bios.BIOSRelease, bios.FirmwareRelease)
+	}
+
+	sys := info.System
+	if sys == nil {
+		t.Fatalf("Expected system information")
+	}
+	if sys.Manufacturer != "Acme" || sys.ProductName != "Server 9000" || sys.SerialNumber != "SRV123" {
+		t.Errorf("Unexpected system %+v", sys)
+	}
+	if sys.UUID != "03020100-0504-0706-0809-0a0b0c0d0e0f" {
+		t.Errorf("Unexpected UUID %q", sys.UUID)
+	}
+	if sys.SKUNumber != "SKU-9000" || sys.Family != "Servers" {
+		t.Errorf("Unexpected SKU/family %q %q", sys.SKUNumber, sys.Family)
+	}
+
+	if len(info.Baseboards) != 1 {
+		t.Fatalf("Expected 1 baseboard, got %d", len(info.Baseboards))
+	}
+	bb := info.Baseboards[0]
+	if bb.Product != "MB-1" || bb.AssetTag != "MB-Tag" || bb.LocationInChassis != "Bottom" {
+		t.Errorf("Unexpected baseboard %+v", bb)
+	}
+	if bb.Type != "Motherboard" || bb.ChassisHandle != 0x0300 {
+		t.Errorf("Unexpected baseboard type/chassis %q %x", bb.Type, bb.ChassisHandle)
+	}
+
+	if len(info.Chassis) != 1 {
+		t.Fatalf("Expected 1 chassis, got %d", len(info.Chassis))
+	}
+	ch := info.Chassis[0]
+	if ch.Type != 0x17 || ch.SerialNumber != "CH123" {
+		t.Errorf("Unexpected chassis %+v", ch)
+	}
+	if ch.BootUpState != "Safe" || ch.ThermalState != "Warning" || ch.HeightU != 2 {
+		t.Errorf("Unexpected chassis states %+v", ch)
+	}
+	if ch.SKUNumber != "CH-SKU" {
+		t.Errorf("Expected SKU after the contained elements, got %q", ch.SKUNumber)
+	}
+
+	if len(info.Processors) != 1 {
+		t.Fatalf("Expected 1 processor, got %d", len(info.Processors))
+	}
+	proc := info.Processors[0]
+	if proc.Family != 0x0101 || proc.Type != "Central Processor" || !proc.IsPopulated || proc.Status != "Enabled" {
+		t.Errorf("Unexpected processor %+v", proc)
+	}
+	if proc.CoreCount != 300 || proc.CoreEnabled != 290 || proc.ThreadCount != 600 {
+		t.Errorf("Unexpected core/thread counts %+v", proc)
+	}
+	if proc.L2CacheHandle != 0x0701 || proc.L3CacheHandle != 0xFFFF {
+		t.Errorf("Unexpected cache handles %+v", proc)
+	}
+
+	if len(info.Caches) != 1 {
+		t.Fatalf("Expected 1 cache, got %d", len(info.Caches))
+	}
+	cache := info.Caches[0]
+	if cache.Level != 2 || !cache.IsEnabled || cache.OperationalMode != "Write Back" {
+		t.Errorf("Unexpected cache %+v", cache)
+	}
+	if cache.MaxSizeBytes != 1024*1024 || cache.InstalledSizeBytes != 2*1024*1024 {
+		t.Errorf("Unexpected cache sizes %d %d", cache.MaxSizeBytes, cache.InstalledSizeBytes)
+	}
+	if cache.SystemCacheType != "Unified" || cache.Associativity != "16-way Set-Associative" {
+		t.Errorf("Unexpected cache type/associativity %+v", cache)
+	}
+
+	if len(info.PortConnectors) != 1 {
+		t.Fatalf("Expected 1 port connector, got %d", len(info.PortConnectors))
+	}
+	port := info.PortConnectors[0]
+	if port.ExternalReferenceDesignator != "LAN1" || port.ExternalConnectorType != "RJ-45" || port.PortType != "Network Port" {
+		t.Errorf("Unexpected port connector %+v", port)
+	}
+
+	if len(info.SystemSlots) != 1 {
+		t.Fatalf("Expected 1 system slot, got %d", len(info.SystemSlots))
+	}
+	slot := info.SystemSlots[0]
+	if slot.Type != "PCI Express Gen 3 x16" || slot.CurrentUsage != "In use" {
+		t.Errorf("Unexpected system slot %+v", slot)
+	}
+	if slot.PCIAddress != "0000:3b:02.1" {
+		t.Errorf("Unexpected slot PCI address %q", slot.PCIAddress)
+	}
+
+	if len(info.OEMStrings) != 2 || info.OEMStrings[1] != "bar" {
+		t.Errorf("Unexpected OEM strings %v", info.OEMStrings)
+	}
+
+	if len(info.MemoryArrays) != 1 {
+		t.Fatalf("Expected 1 memory array, got %d", len(info.MemoryArrays))
+	}
+	arr := info.MemoryArrays[0]
+	if arr.MaximumCapacityBytes != 2*1024*1024*1024*1024 || arr.NumMemoryDevices != 16 {
+		t.Errorf("Unexpected memory array %+v", arr)
+	}
+	if arr.Use != "System memory" || arr.ErrorCorrectionType != "Multi-bit ECC" {
+		t.Errorf("Unexpected memory array use/ECC %+v", arr)
+	}
+
+	if len(info.PowerSupplies) != 1 {
+		t.Fatalf("Expected 1 power supply, got %d", len(info.PowerSupplies))
+	}
+	psu := info.PowerSupplies[0]
+	if psu.ModelPartNumber != "PSU-750" || psu.MaxPowerCapacityWatts != 750 {
+		t.Errorf("Unexpected power supply %+v", psu)
+	}
+	if !psu.IsHotReplaceable || !psu.IsPresent || psu.IsUnplugged {
+		t.Errorf("Unexpected power supply characteristics %+v", psu)
+	}
+	if psu.Status != "OK" || psu.Type != "Switching" {
+		t.Errorf("Unexpected power supply status/type %q %q", psu.Status, psu.Type)
+	}
+}
+
+func TestSMBIOSDMIItemFallback(t *testing.T) {
+	root := smbiosTestSetup(t, systemTable())
+	defer os.RemoveAll(root)
+
+	// only the world-readable files are found in sysfs
+	idDir := filepath.Join(root, "sys", "class", "dmi", "id")
+	if err := os.MkdirAll(idDir, os.ModePerm); err != nil {
+		t.Fatalf("Unable to create %q: %v", idDir, err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(idDir, "product_name"), []byte("From sysfs\n"), 0644); err != nil {
+		t.Fatalf("Unable to write product_name: %v", err)
+	}
+
+	ctx := context.New(option.WithChroot(root), option.WithNullAlerter())
+	if name := linuxdmi.Item(ctx, "product_name"); name != "From sysfs" {
+		t.Errorf("Expected product name from sysfs, got %q", name)
+	}
+	if serial := linuxdmi.Item(ctx, "product_serial"); serial != "SRV123" {
+		t.Errorf("Expected product serial from SMBIOS, got %q", serial)
+	}
+
+	// the table is decoded once, by the first item missing from sysfs
+	dmi := linuxdmi.NewItems(ctx)
+	if chassisType := dmi.Item("chassis_type"); chassisType != "23" {
+		t.Errorf("Expected chassis type from SMBIOS, got %q", chassisType)
+	}
+	if err := os.RemoveAll(filepath.Join(root, "sys", "firmware")); err != nil {
+		t.Fatalf("Unable to remove the SMBIOS table: %v", err)
+	}
+	if tag := dmi.Item("board_asset_tag"); tag != "MB-Tag" {
+		t.Errorf("Expected board asset tag from SMBIOS, got %q", tag)
+	}
+}
+
+func TestSMBIOSDMIItemEmptyString(t *testing.T) {
+	// a BIOS structure without any string
+	f, _, _, _ := formattedArea(0x18)
+	root := smbiosTestSetup(t, smbiosStruct(smbios.TypeBIOS, 0x0000, f))
+	defer os.RemoveAll(root)
+
+	ctx := context.New(option.WithChroot(root), option.WithNullAlerter())
+	if vendor := linuxdmi.Item(ctx, "bios_vendor"); vendor != util.UNKNOWN {
+		t.Errorf("Expected unknown BIOS vendor, got %q", vendor)
+	}
+}
+
+func TestSMBIOSBIOSROMSize(t *testing.T) {
+	// a SMBIOS 2.4 BIOS structure, without the extended ROM size, reporting
+	// the largest size the ROM size byte can
+	f, put8, _, _ := formattedArea(0x18)
+	put8(0x09, 0xFF)
+	root := smbiosTestSetup(t, smbiosStruct(smbios.TypeBIOS, 0x0000, f))
+	defer os.RemoveAll(root)
+
+	info, err := smbios.New(option.WithChroot(root))
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if info.BIOS == nil || info.BIOS.ROMSizeBytes != 16*1024*1024 {
+		t.Fatalf("Expected 16MB ROM, got %+v", info.BIOS)
+	}
+}
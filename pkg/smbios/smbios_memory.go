@@ -0,0 +1,218 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package smbios
+
+import (
+	"github.com/jaypipes/ghw/pkg/unitutil"
+)
+
+const (
+	// TypePhysicalMemoryArray is the type of the Physical Memory Array
+	// (type 16) structure
+	TypePhysicalMemoryArray = 16
+	// TypeMemoryDevice is the type of the Memory Device (type 17) structure
+	TypeMemoryDevice = 17
+)
+
+var (
+	memoryArrayLocationString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "System board or motherboard",
+		0x04: "ISA add-on card",
+		0x05: "EISA add-on card",
+		0x06: "PCI add-on card",
+		0x07: "MCA add-on card",
+		0x08: "PCMCIA add-on card",
+		0x09: "Proprietary add-on card",
+		0x0A: "NuBus",
+		0xA0: "PC-98/C20 add-on card",
+		0xA1: "PC-98/C24 add-on card",
+		0xA2: "PC-98/E add-on card",
+		0xA3: "PC-98/Local bus add-on card",
+		0xA4: "CXL add-on card",
+	}
+
+	memoryArrayUseString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "System memory",
+		0x04: "Video memory",
+		0x05: "Flash memory",
+		0x06: "Non-volatile RAM",
+		0x07: "Cache memory",
+	}
+
+	memoryDeviceFormFactorString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "SIMM",
+		0x04: "SIP",
+		0x05: "Chip",
+		0x06: "DIP",
+		0x07: "ZIP",
+		0x08: "Proprietary Card",
+		0x09: "DIMM",
+		0x0A: "TSOP",
+		0x0B: "Row of chips",
+		0x0C: "RIMM",
+		0x0D: "SODIMM",
+		0x0E: "SRIMM",
+		0x0F: "FB-DIMM",
+		0x10: "Die",
+	}
+
+	memoryDeviceTypeString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "DRAM",
+		0x04: "EDRAM",
+		0x05: "VRAM",
+		0x06: "SRAM",
+		0x07: "RAM",
+		0x08: "ROM",
+		0x09: "Flash",
+		0x0A: "EEPROM",
+		0x0B: "FEPROM",
+		0x0C: "EPROM",
+		0x0D: "CDRAM",
+		0x0E: "3DRAM",
+		0x0F: "SDRAM",
+		0x10: "SGRAM",
+		0x11: "RDRAM",
+		0x12: "DDR",
+		0x13: "DDR2",
+		0x14: "DDR2 FB-DIMM",
+		0x18: "DDR3",
+		0x19: "FBD2",
+		0x1A: "DDR4",
+		0x1B: "LPDDR",
+		0x1C: "LPDDR2",
+		0x1D: "LPDDR3",
+		0x1E: "LPDDR4",
+		0x1F: "Logical non-volatile device",
+		0x20: "HBM",
+		0x21: "HBM2",
+		0x22: "DDR5",
+		0x23: "LPDDR5",
+	}
+)
+
+// PhysicalMemoryArray describes a Physical Memory Array (type 16) structure,
+// which represents a collection of memory devices operating together
+type PhysicalMemoryArray struct {
+	Handle              uint16 `json:"handle"`
+	Location            string `json:"location"`
+	Use                 string `json:"use"`
+	ErrorCorrectionType string `json:"error_correction_type"`
+	// MaximumCapacityBytes is the maximum memory capacity the array
+	// supports. Zero if unknown.
+	MaximumCapacityBytes int64 `json:"maximum_capacity_bytes"`
+	NumMemoryDevices     int   `json:"num_memory_devices"`
+}
+
+func parsePhysicalMemoryArray(s *Structure) *PhysicalMemoryArray {
+	arr := &PhysicalMemoryArray{
+		Handle:              s.Header.Handle,
+		Location:            lookupString(memoryArrayLocationString, s.Byte(0x04)),
+		Use:                 lookupString(memoryArrayUseString, s.Byte(0x05)),
+		ErrorCorrectionType: lookupString(errorCorrectionTypeString, s.Byte(0x06)),
+		NumMemoryDevices:    int(s.Word(0x0D)),
+	}
+	maxCapacity := s.DWord(0x07)
+	if maxCapacity == 0x80000000 && s.Has(0x0F, 8) {
+		// the actual capacity, in bytes, is found in the Extended Maximum
+		// Capacity field
+		arr.MaximumCapacityBytes = int64(s.QWord(0x0F))
+	} else if maxCapacity != 0x80000000 {
+		arr.MaximumCapacityBytes = int64(maxCapacity) * unitutil.KB
+	}
+	return arr
+}
+
+// MemoryDevice describes a Memory Device (type 17) structure, which
+// represents a single memory slot (e.g. a DIMM socket) and the module
+// installed in it, if any
+type MemoryDevice struct {
+	Handle                    uint16 `json:"handle"`
+	PhysicalMemoryArrayHandle uint16 `json:"physical_memory_array_handle"`
+	// Total and data width, in bits. Zero if unknown.
+	TotalWidth uint16 `json:"total_width"`
+	DataWidth  uint16 `json:"data_width"`
+	// SizeBytes is zero if no module is installed in the slot, and -1 if a
+	// module is installed but its size is unknown
+	SizeBytes     int64  `json:"size_bytes"`
+	FormFactor    string `json:"form_factor"`
+	DeviceLocator string `json:"device_locator"`
+	BankLocator   string `json:"bank_locator"`
+	Type          string `json:"type"`
+	// Speeds are in megatransfers per second (MT/s). Zero if unknown.
+	SpeedMTs           uint32 `json:"speed_mts"`
+	ConfiguredSpeedMTs uint32 `json:"configured_speed_mts"`
+	Manufacturer       string `json:"manufacturer"`
+	SerialNumber       string `json:"serial_number"`
+	AssetTag           string `json:"asset_tag"`
+	PartNumber         string `json:"part_number"`
+	// Rank is zero if unknown
+	Rank int `json:"rank"`
+}
+
+func parseMemoryDevice(s *Structure) *MemoryDevice {
+	md := &MemoryDevice{
+		Handle:                    s.Header.Handle,
+		PhysicalMemoryArrayHandle: s.Word(0x04),
+		TotalWidth:                unknownWidth(s.Word(0x08)),
+		DataWidth:                 unknownWidth(s.Word(0x0A)),
+		SizeBytes:                 memoryDeviceSizeBytes(s),
+		FormFactor:                lookupString(memoryDeviceFormFactorString, s.Byte(0x0E)),
+		DeviceLocator:             s.StringAt(0x10),
+		BankLocator:               s.StringAt(0x11),
+		Type:                      lookupString(memoryDeviceTypeString, s.Byte(0x12)),
+		SpeedMTs:                  memoryDeviceSpeed(s, 0x15, 0x54),
+		Manufacturer:              s.StringAt(0x17),
+		SerialNumber:              s.StringAt(0x18),
+		AssetTag:                  s.StringAt(0x19),
+		PartNumber:                s.StringAt(0x1A),
+		Rank:                      int(s.Byte(0x1B) & 0x0F),
+		ConfiguredSpeedMTs:        memoryDeviceSpeed(s, 0x20, 0x58),
+	}
+	return md
+}
+
+func unknownWidth(width uint16) uint16 {
+	if width == 0xFFFF {
+		return 0
+	}
+	return width
+}
+
+func memoryDeviceSizeBytes(s *Structure) int64 {
+	size := s.Word(0x0C)
+	switch {
+	case size == 0:
+		return 0
+	case size == 0xFFFF:
+		return -1
+	case size == 0x7FFF && s.Has(0x1C, 4):
+		// the actual size, in MB, is found in the Extended Size field
+		return int64(s.DWord(0x1C)&0x7FFFFFFF) * unitutil.MB
+	case size&0x8000 != 0:
+		// granularity is KB instead of MB
+		return int64(size&0x7FFF) * unitutil.KB
+	}
+	return int64(size) * unitutil.MB
+}
+
+// memoryDeviceSpeed returns the speed found at the given offset, looking at
+// the extended speed field (SMBIOS 3.3+) if the value doesn't fit in a WORD
+func memoryDeviceSpeed(s *Structure, offset, extOffset int) uint32 {
+	speed := s.Word(offset)
+	if speed == 0xFFFF {
+		return s.DWord(extOffset) & 0x7FFFFFFF
+	}
+	return uint32(speed)
+}
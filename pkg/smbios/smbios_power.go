@@ -0,0 +1,77 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package smbios
+
+const (
+	// TypePowerSupply is the type of the System Power Supply (type 39)
+	// structure
+	TypePowerSupply = 39
+)
+
+var (
+	powerSupplyTypeString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "Linear",
+		0x04: "Switching",
+		0x05: "Battery",
+		0x06: "UPS",
+		0x07: "Converter",
+		0x08: "Regulator",
+	}
+
+	powerSupplyStatusString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "OK",
+		0x04: "Non-critical",
+		0x05: "Critical",
+	}
+)
+
+// PowerSupply describes the System Power Supply (type 39) structure
+type PowerSupply struct {
+	PowerUnitGroup  int    `json:"power_unit_group"`
+	Location        string `json:"location"`
+	DeviceName      string `json:"device_name"`
+	Manufacturer    string `json:"manufacturer"`
+	SerialNumber    string `json:"serial_number"`
+	AssetTag        string `json:"asset_tag"`
+	ModelPartNumber string `json:"model_part_number"`
+	RevisionLevel   string `json:"revision_level"`
+	// MaxPowerCapacityWatts is -1 if unknown
+	MaxPowerCapacityWatts int    `json:"max_power_capacity_watts"`
+	IsHotReplaceable      bool   `json:"is_hot_replaceable"`
+	IsPresent             bool   `json:"is_present"`
+	IsUnplugged           bool   `json:"is_unplugged"`
+	Status                string `json:"status"`
+	Type                  string `json:"type"`
+}
+
+func parsePowerSupply(s *Structure) *PowerSupply {
+	ps := &PowerSupply{
+		PowerUnitGroup:        int(s.Byte(0x04)),
+		Location:              s.StringAt(0x05),
+		DeviceName:            s.StringAt(0x06),
+		Manufacturer:          s.StringAt(0x07),
+		SerialNumber:          s.StringAt(0x08),
+		AssetTag:              s.StringAt(0x09),
+		ModelPartNumber:       s.StringAt(0x0A),
+		RevisionLevel:         s.StringAt(0x0B),
+		MaxPowerCapacityWatts: int(s.Word(0x0C)),
+	}
+	if !s.Has(0x0C, 2) || ps.MaxPowerCapacityWatts == 0x8000 {
+		ps.MaxPowerCapacityWatts = -1
+	}
+	chars := s.Word(0x0E)
+	ps.IsHotReplaceable = chars&0x0001 != 0
+	ps.IsPresent = chars&0x0002 != 0
+	ps.IsUnplugged = chars&0x0004 != 0
+	ps.Status = lookupString(powerSupplyStatusString, uint8((chars>>7)&0x07))
+	ps.Type = lookupString(powerSupplyTypeString, uint8((chars>>10)&0x0F))
+	return ps
+}
@@ -0,0 +1,212 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package smbios
+
+import (
+	"github.com/jaypipes/ghw/pkg/unitutil"
+)
+
+const (
+	// TypeProcessor is the type of the Processor Information (type 4)
+	// structure
+	TypeProcessor = 4
+	// TypeCache is the type of the Cache Information (type 7) structure
+	TypeCache = 7
+)
+
+var (
+	processorTypeString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "Central Processor",
+		0x04: "Math Processor",
+		0x05: "DSP Processor",
+		0x06: "Video Processor",
+	}
+
+	processorStatusString = map[uint8]string{
+		0x00: "Unknown",
+		0x01: "Enabled",
+		0x02: "Disabled by user",
+		0x03: "Disabled by BIOS",
+		0x04: "Idle",
+		0x07: "Other",
+	}
+
+	cacheLocationString = map[uint8]string{
+		0x00: "Internal",
+		0x01: "External",
+		0x02: "Reserved",
+		0x03: "Unknown",
+	}
+
+	cacheOperationalModeString = map[uint8]string{
+		0x00: "Write Through",
+		0x01: "Write Back",
+		0x02: "Varies with Memory Address",
+		0x03: "Unknown",
+	}
+
+	errorCorrectionTypeString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "None",
+		0x04: "Parity",
+		0x05: "Single-bit ECC",
+		0x06: "Multi-bit ECC",
+		0x07: "CRC",
+	}
+
+	cacheTypeString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "Instruction",
+		0x04: "Data",
+		0x05: "Unified",
+	}
+
+	cacheAssociativityString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "Direct Mapped",
+		0x04: "2-way Set-Associative",
+		0x05: "4-way Set-Associative",
+		0x06: "Fully Associative",
+		0x07: "8-way Set-Associative",
+		0x08: "16-way Set-Associative",
+		0x09: "12-way Set-Associative",
+		0x0A: "24-way Set-Associative",
+		0x0B: "32-way Set-Associative",
+		0x0C: "48-way Set-Associative",
+		0x0D: "64-way Set-Associative",
+		0x0E: "20-way Set-Associative",
+	}
+)
+
+// Processor describes the Processor Information (type 4) structure
+type Processor struct {
+	SocketDesignation string `json:"socket_designation"`
+	Type              string `json:"type"`
+	// Family is the raw processor family, as listed in the specification
+	Family       uint16 `json:"family"`
+	Manufacturer string `json:"manufacturer"`
+	// ID is the raw processor identification data (e.g. CPUID on x86)
+	ID      uint64 `json:"id"`
+	Version string `json:"version"`
+	// Clock speeds in MHz. Zero if unknown.
+	ExternalClockMHz int `json:"external_clock_mhz"`
+	MaxSpeedMHz      int `json:"max_speed_mhz"`
+	CurrentSpeedMHz  int `json:"current_speed_mhz"`
+	// IsPopulated is true if the socket holds a processor
+	IsPopulated bool   `json:"is_populated"`
+	Status      string `json:"status"`
+	// Handles of the Cache Information structures for each cache level.
+	// 0xFFFF if the processor doesn't have a cache of that level.
+	L1CacheHandle uint16 `json:"l1_cache_handle"`
+	L2CacheHandle uint16 `json:"l2_cache_handle"`
+	L3CacheHandle uint16 `json:"l3_cache_handle"`
+	SerialNumber  string `json:"serial_number"`
+	AssetTag      string `json:"asset_tag"`
+	PartNumber    string `json:"part_number"`
+	// Core and thread counts. Zero if unknown.
+	CoreCount   int `json:"core_count"`
+	CoreEnabled int `json:"core_enabled"`
+	ThreadCount int `json:"thread_count"`
+}
+
+func parseProcessor(s *Structure) *Processor {
+	status := s.Byte(0x18)
+	proc := &Processor{
+		SocketDesignation: s.StringAt(0x04),
+		Type:              lookupString(processorTypeString, s.Byte(0x05)),
+		Family:            uint16(s.Byte(0x06)),
+		Manufacturer:      s.StringAt(0x07),
+		ID:                s.QWord(0x08),
+		Version:           s.StringAt(0x10),
+		ExternalClockMHz:  int(s.Word(0x12)),
+		MaxSpeedMHz:       int(s.Word(0x14)),
+		CurrentSpeedMHz:   int(s.Word(0x16)),
+		IsPopulated:       status&0x40 != 0,
+		Status:            lookupString(processorStatusString, status&0x07),
+		L1CacheHandle:     s.Word(0x1A),
+		L2CacheHandle:     s.Word(0x1C),
+		L3CacheHandle:     s.Word(0x1E),
+		SerialNumber:      s.StringAt(0x20),
+		AssetTag:          s.StringAt(0x21),
+		PartNumber:        s.StringAt(0x22),
+		CoreCount:         extendedCount(s, 0x23, 0x2A),
+		CoreEnabled:       extendedCount(s, 0x24, 0x2C),
+		ThreadCount:       extendedCount(s, 0x25, 0x2E),
+	}
+	if proc.Family == 0xFE && s.Has(0x28, 2) {
+		proc.Family = s.Word(0x28)
+	}
+	return proc
+}
+
+// extendedCount returns the BYTE count found at the given offset, looking at
+// the WORD count at extOffset (SMBIOS 3.0+) if the value doesn't fit in a BYTE
+func extendedCount(s *Structure, offset, extOffset int) int {
+	count := s.Byte(offset)
+	if count == 0xFF && s.Has(extOffset, 2) {
+		return int(s.Word(extOffset))
+	}
+	return int(count)
+}
+
+// Cache describes the Cache Information (type 7) structure
+type Cache struct {
+	Handle            uint16 `json:"handle"`
+	SocketDesignation string `json:"socket_designation"`
+	// Level is 1 for L1 caches, 2 for L2 caches and so forth
+	Level               int    `json:"level"`
+	IsEnabled           bool   `json:"is_enabled"`
+	IsSocketed          bool   `json:"is_socketed"`
+	Location            string `json:"location"`
+	OperationalMode     string `json:"operational_mode"`
+	MaxSizeBytes        int64  `json:"max_size_bytes"`
+	InstalledSizeBytes  int64  `json:"installed_size_bytes"`
+	ErrorCorrectionType string `json:"error_correction_type"`
+	SystemCacheType     string `json:"system_cache_type"`
+	Associativity       string `json:"associativity"`
+}
+
+func parseCache(s *Structure) *Cache {
+	config := s.Word(0x05)
+	return &Cache{
+		Handle:              s.Header.Handle,
+		SocketDesignation:   s.StringAt(0x04),
+		Level:               int(config&0x07) + 1,
+		IsSocketed:          config&0x08 != 0,
+		Location:            lookupString(cacheLocationString, uint8((config>>5)&0x03)),
+		IsEnabled:           config&0x80 != 0,
+		OperationalMode:     lookupString(cacheOperationalModeString, uint8((config>>8)&0x03)),
+		MaxSizeBytes:        cacheSizeBytes(s, 0x07, 0x13),
+		InstalledSizeBytes:  cacheSizeBytes(s, 0x09, 0x17),
+		ErrorCorrectionType: lookupString(errorCorrectionTypeString, s.Byte(0x10)),
+		SystemCacheType:     lookupString(cacheTypeString, s.Byte(0x11)),
+		Associativity:       lookupString(cacheAssociativityString, s.Byte(0x12)),
+	}
+}
+
+// cacheSizeBytes decodes the cache size WORD at the given offset, looking at
+// the DWORD at extOffset (SMBIOS 3.1+) if the value doesn't fit in a WORD.
+// The most significant bit selects the granularity: 1K or 64K.
+func cacheSizeBytes(s *Structure, offset, extOffset int) int64 {
+	size := s.Word(offset)
+	if size == 0xFFFF && s.Has(extOffset, 4) {
+		extSize := s.DWord(extOffset)
+		if extSize&0x80000000 != 0 {
+			return int64(extSize&0x7FFFFFFF) * 64 * unitutil.KB
+		}
+		return int64(extSize) * unitutil.KB
+	}
+	if size&0x8000 != 0 {
+		return int64(size&0x7FFF) * 64 * unitutil.KB
+	}
+	return int64(size) * unitutil.KB
+}
@@ -0,0 +1,277 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package smbios
+
+import (
+	"fmt"
+)
+
+const (
+	// TypePortConnector is the type of the Port Connector Information
+	// (type 8) structure
+	TypePortConnector = 8
+	// TypeSystemSlot is the type of the System Slots (type 9) structure
+	TypeSystemSlot = 9
+)
+
+var (
+	connectorTypeString = map[uint8]string{
+		0x00: "None",
+		0x01: "Centronics",
+		0x02: "Mini Centronics",
+		0x03: "Proprietary",
+		0x04: "DB-25 pin male",
+		0x05: "DB-25 pin female",
+		0x06: "DB-15 pin male",
+		0x07: "DB-15 pin female",
+		0x08: "DB-9 pin male",
+		0x09: "DB-9 pin female",
+		0x0A: "RJ-11",
+		0x0B: "RJ-45",
+		0x0C: "50-pin MiniSCSI",
+		0x0D: "Mini-DIN",
+		0x0E: "Micro-DIN",
+		0x0F: "PS/2",
+		0x10: "Infrared",
+		0x11: "HP-HIL",
+		0x12: "Access Bus (USB)",
+		0x13: "SSA SCSI",
+		0x14: "Circular DIN-8 male",
+		0x15: "Circular DIN-8 female",
+		0x16: "On Board IDE",
+		0x17: "On Board Floppy",
+		0x18: "9-pin Dual Inline (pin 10 cut)",
+		0x19: "25-pin Dual Inline (pin 26 cut)",
+		0x1A: "50-pin Dual Inline",
+		0x1B: "68-pin Dual Inline",
+		0x1C: "On Board Sound Input from CD-ROM",
+		0x1D: "Mini-Centronics Type-14",
+		0x1E: "Mini-Centronics Type-26",
+		0x1F: "Mini-jack (headphones)",
+		0x20: "BNC",
+		0x21: "1394",
+		0x22: "SAS/SATA Plug Receptacle",
+		0x23: "USB Type-C Receptacle",
+		0xA0: "PC-98",
+		0xA1: "PC-98Hireso",
+		0xA2: "PC-H98",
+		0xA3: "PC-98Note",
+		0xA4: "PC-98Full",
+		0xFF: "Other",
+	}
+
+	portTypeString = map[uint8]string{
+		0x00: "None",
+		0x01: "Parallel Port XT/AT Compatible",
+		0x02: "Parallel Port PS/2",
+		0x03: "Parallel Port ECP",
+		0x04: "Parallel Port EPP",
+		0x05: "Parallel Port ECP/EPP",
+		0x06: "Serial Port XT/AT Compatible",
+		0x07: "Serial Port 16450 Compatible",
+		0x08: "Serial Port 16550 Compatible",
+		0x09: "Serial Port 16550A Compatible",
+		0x0A: "SCSI Port",
+		0x0B: "MIDI Port",
+		0x0C: "Joy Stick Port",
+		0x0D: "Keyboard Port",
+		0x0E: "Mouse Port",
+		0x0F: "SSA SCSI",
+		0x10: "USB",
+		0x11: "FireWire (IEEE P1394)",
+		0x12: "PCMCIA Type I",
+		0x13: "PCMCIA Type II",
+		0x14: "PCMCIA Type III",
+		0x15: "Cardbus",
+		0x16: "Access Bus Port",
+		0x17: "SCSI II",
+		0x18: "SCSI Wide",
+		0x19: "PC-98",
+		0x1A: "PC-98-Hireso",
+		0x1B: "PC-H98",
+		0x1C: "Video Port",
+		0x1D: "Audio Port",
+		0x1E: "Modem Port",
+		0x1F: "Network Port",
+		0x20: "SATA",
+		0x21: "SAS",
+		0x22: "MFDP",
+		0x23: "Thunderbolt",
+		0xA0: "8251 Compatible",
+		0xA1: "8251 FIFO Compatible",
+		0xFF: "Other",
+	}
+
+	slotTypeString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "ISA",
+		0x04: "MCA",
+		0x05: "EISA",
+		0x06: "PCI",
+		0x07: "PC Card (PCMCIA)",
+		0x08: "VL-VESA",
+		0x09: "Proprietary",
+		0x0A: "Processor Card Slot",
+		0x0B: "Proprietary Memory Card Slot",
+		0x0C: "I/O Riser Card Slot",
+		0x0D: "NuBus",
+		0x0E: "PCI-66MHz",
+		0x0F: "AGP",
+		0x10: "AGP 2X",
+		0x11: "AGP 4X",
+		0x12: "PCI-X",
+		0x13: "AGP 8X",
+		0x14: "M.2 Socket 1-DP",
+		0x15: "M.2 Socket 1-SD",
+		0x16: "M.2 Socket 2",
+		0x17: "M.2 Socket 3",
+		0x18: "MXM Type I",
+		0x19: "MXM Type II",
+		0x1A: "MXM Type III (standard connector)",
+		0x1B: "MXM Type III (HE connector)",
+		0x1C: "MXM Type IV",
+		0x1D: "MXM 3.0 Type A",
+		0x1E: "MXM 3.0 Type B",
+		0x1F: "PCI Express Gen 2 SFF-8639 (U.2)",
+		0x20: "PCI Express Gen 3 SFF-8639 (U.2)",
+		0x21: "PCI Express Mini 52-pin with bottom-side keep-outs",
+		0x22: "PCI Express Mini 52-pin without bottom-side keep-outs",
+		0x23: "PCI Express Mini 76-pin",
+		0x24: "PCI Express Gen 4 SFF-8639 (U.2)",
+		0x25: "PCI Express Gen 5 SFF-8639 (U.2)",
+		0x26: "OCP NIC 3.0 Small Form Factor (SFF)",
+		0x27: "OCP NIC 3.0 Large Form Factor (LFF)",
+		0x28: "OCP NIC Prior to 3.0",
+		0x30: "CXL Flexbus 1.0",
+		0xA0: "PC-98/C20",
+		0xA1: "PC-98/C24",
+		0xA2: "PC-98/E",
+		0xA3: "PC-98/Local Bus",
+		0xA4: "PC-98/Card",
+		0xA5: "PCI Express",
+		0xA6: "PCI Express x1",
+		0xA7: "PCI Express x2",
+		0xA8: "PCI Express x4",
+		0xA9: "PCI Express x8",
+		0xAA: "PCI Express x16",
+		0xAB: "PCI Express Gen 2",
+		0xAC: "PCI Express Gen 2 x1",
+		0xAD: "PCI Express Gen 2 x2",
+		0xAE: "PCI Express Gen 2 x4",
+		0xAF: "PCI Express Gen 2 x8",
+		0xB0: "PCI Express Gen 2 x16",
+		0xB1: "PCI Express Gen 3",
+		0xB2: "PCI Express Gen 3 x1",
+		0xB3: "PCI Express Gen 3 x2",
+		0xB4: "PCI Express Gen 3 x4",
+		0xB5: "PCI Express Gen 3 x8",
+		0xB6: "PCI Express Gen 3 x16",
+		0xB8: "PCI Express Gen 4",
+		0xB9: "PCI Express Gen 4 x1",
+		0xBA: "PCI Express Gen 4 x2",
+		0xBB: "PCI Express Gen 4 x4",
+		0xBC: "PCI Express Gen 4 x8",
+		0xBD: "PCI Express Gen 4 x16",
+		0xBE: "PCI Express Gen 5",
+		0xBF: "PCI Express Gen 5 x1",
+		0xC0: "PCI Express Gen 5 x2",
+		0xC1: "PCI Express Gen 5 x4",
+		0xC2: "PCI Express Gen 5 x8",
+		0xC3: "PCI Express Gen 5 x16",
+	}
+
+	slotDataBusWidthString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "8 bit",
+		0x04: "16 bit",
+		0x05: "32 bit",
+		0x06: "64 bit",
+		0x07: "128 bit",
+		0x08: "x1",
+		0x09: "x2",
+		0x0A: "x4",
+		0x0B: "x8",
+		0x0C: "x12",
+		0x0D: "x16",
+		0x0E: "x32",
+	}
+
+	slotUsageString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "Available",
+		0x04: "In use",
+		0x05: "Unavailable",
+	}
+
+	slotLengthString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "Short Length",
+		0x04: "Long Length",
+		0x05: "2.5\" drive form factor",
+		0x06: "3.5\" drive form factor",
+	}
+)
+
+// PortConnector describes the Port Connector Information (type 8) structure
+type PortConnector struct {
+	InternalReferenceDesignator string `json:"internal_reference_designator"`
+	InternalConnectorType       string `json:"internal_connector_type"`
+	ExternalReferenceDesignator string `json:"external_reference_designator"`
+	ExternalConnectorType       string `json:"external_connector_type"`
+	PortType                    string `json:"port_type"`
+}
+
+func parsePortConnector(s *Structure) *PortConnector {
+	return &PortConnector{
+		InternalReferenceDesignator: s.StringAt(0x04),
+		InternalConnectorType:       lookupString(connectorTypeString, s.Byte(0x05)),
+		ExternalReferenceDesignator: s.StringAt(0x06),
+		ExternalConnectorType:       lookupString(connectorTypeString, s.Byte(0x07)),
+		PortType:                    lookupString(portTypeString, s.Byte(0x08)),
+	}
+}
+
+// SystemSlot describes the System Slots (type 9) structure
+type SystemSlot struct {
+	Designation  string `json:"designation"`
+	Type         string `json:"type"`
+	DataBusWidth string `json:"data_bus_width"`
+	CurrentUsage string `json:"current_usage"`
+	Length       string `json:"length"`
+	ID           uint16 `json:"id"`
+	// PCIAddress is the address of the device in the slot, in the same
+	// $DOMAIN:$BUS:$DEVICE.$FUNCTION format used in the pci package. Empty if
+	// the slot doesn't report it (SMBIOS < 2.6) or if the slot is empty.
+	PCIAddress string `json:"pci_address,omitempty"`
+}
+
+func parseSystemSlot(s *Structure) *SystemSlot {
+	slot := &SystemSlot{
+		Designation:  s.StringAt(0x04),
+		Type:         lookupString(slotTypeString, s.Byte(0x05)),
+		DataBusWidth: lookupString(slotDataBusWidthString, s.Byte(0x06)),
+		CurrentUsage: lookupString(slotUsageString, s.Byte(0x07)),
+		Length:       lookupString(slotLengthString, s.Byte(0x08)),
+		ID:           s.Word(0x09),
+	}
+	if s.Has(0x0D, 4) {
+		segment := s.Word(0x0D)
+		bus := s.Byte(0x0F)
+		devFn := s.Byte(0x10)
+		// 0xFF in both bus and device/function means "not applicable"
+		if segment != 0xFFFF && !(bus == 0xFF && devFn == 0xFF) {
+			slot.PCIAddress = fmt.Sprintf(
+				"%04x:%02x:%02x.%x", segment, bus, devFn>>3, devFn&0x07,
+			)
+		}
+	}
+	return slot
+}
@@ -0,0 +1,17 @@
+// +build !linux
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package smbios
+
+import (
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+func (i *Info) load() error {
+	return errors.New("smbios.Info.load not implemented on " + runtime.GOOS)
+}
@@ -0,0 +1,238 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package smbios
+
+import (
+	"fmt"
+)
+
+const (
+	// TypeBIOS is the type of the BIOS Information (type 0) structure
+	TypeBIOS = 0
+	// TypeSystem is the type of the System Information (type 1) structure
+	TypeSystem = 1
+	// TypeBaseboard is the type of the Baseboard Information (type 2) structure
+	TypeBaseboard = 2
+	// TypeChassis is the type of the System Enclosure or Chassis (type 3)
+	// structure
+	TypeChassis = 3
+	// TypeOEMStrings is the type of the OEM Strings (type 11) structure
+	TypeOEMStrings = 11
+)
+
+var (
+	baseboardTypeString = map[uint8]string{
+		0x01: "Unknown",
+		0x02: "Other",
+		0x03: "Server Blade",
+		0x04: "Connectivity Switch",
+		0x05: "System Management Module",
+		0x06: "Processor Module",
+		0x07: "I/O Module",
+		0x08: "Memory Module",
+		0x09: "Daughter board",
+		0x0A: "Motherboard",
+		0x0B: "Processor/Memory Module",
+		0x0C: "Processor/IO Module",
+		0x0D: "Interconnect board",
+	}
+
+	chassisStateString = map[uint8]string{
+		0x01: "Other",
+		0x02: "Unknown",
+		0x03: "Safe",
+		0x04: "Warning",
+		0x05: "Critical",
+		0x06: "Non-recoverable",
+	}
+)
+
+// BIOS describes the BIOS Information (type 0) structure
+type BIOS struct {
+	Vendor      string `json:"vendor"`
+	Version     string `json:"version"`
+	ReleaseDate string `json:"release_date"`
+	// ROMSizeBytes is the size of the physical device containing the BIOS
+	ROMSizeBytes    int64  `json:"rom_size_bytes"`
+	Characteristics uint64 `json:"characteristics"`
+	// Release of the system BIOS and of the embedded controller firmware,
+	// as "major.minor". Empty if not reported.
+	BIOSRelease     string `json:"bios_release"`
+	FirmwareRelease string `json:"firmware_release"`
+}
+
+func parseBIOS(s *Structure) *BIOS {
+	bios := &BIOS{
+		Vendor:          s.StringAt(0x04),
+		Version:         s.StringAt(0x05),
+		ReleaseDate:     s.StringAt(0x08),
+		Characteristics: s.QWord(0x0A),
+		BIOSRelease:     releaseString(s, 0x14),
+		FirmwareRelease: releaseString(s, 0x16),
+	}
+	if !s.Has(0x09, 1) {
+		return bios
+	}
+	romSize := s.Byte(0x09)
+	if romSize == 0xFF && s.Has(0x18, 2) {
+		// Extended BIOS ROM Size: bits 15:14 are the unit (MB, GB) and
+		// bits 13:0 are the size
+		extSize := s.Word(0x18)
+		unit := int64(1024 * 1024)
+		if extSize>>14 == 1 {
+			unit *= 1024
+		}
+		bios.ROMSizeBytes = int64(extSize&0x3FFF) * unit
+	} else {
+		bios.ROMSizeBytes = (int64(romSize) + 1) * 64 * 1024
+	}
+	return bios
+}
+
+// releaseString decodes the major/minor release BYTE pair at the given offset.
+// 0xFF means the release is not reported.
+func releaseString(s *Structure, offset int) string {
+	if !s.Has(offset, 2) || s.Byte(offset) == 0xFF {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d", s.Byte(offset), s.Byte(offset+1))
+}
+
+// System describes the System Information (type 1) structure
+type System struct {
+	Manufacturer string `json:"manufacturer"`
+	ProductName  string `json:"product_name"`
+	Version      string `json:"version"`
+	SerialNumber string `json:"serial_number"`
+	UUID         string `json:"uuid"`
+	SKUNumber    string `json:"sku_number"`
+	Family       string `json:"family"`
+}
+
+func parseSystem(s *Structure, ep *EntryPoint) *System {
+	return &System{
+		Manufacturer: s.StringAt(0x04),
+		ProductName:  s.StringAt(0x05),
+		Version:      s.StringAt(0x06),
+		SerialNumber: s.StringAt(0x07),
+		UUID:         systemUUID(s, ep),
+		SKUNumber:    s.StringAt(0x19),
+		Family:       s.StringAt(0x1A),
+	}
+}
+
+// systemUUID formats the UUID like the linux kernel does in
+// /sys/class/dmi/id/product_uuid
+func systemUUID(s *Structure, ep *EntryPoint) string {
+	if !s.Has(0x08, 16) {
+		return ""
+	}
+	u := s.Formatted[0x08 : 0x08+16]
+	allSet, allClear := true, true
+	for _, b := range u {
+		allSet = allSet && b == 0xFF
+		allClear = allClear && b == 0x00
+	}
+	if allSet || allClear {
+		// not present or not set
+		return ""
+	}
+	// Since SMBIOS 2.6 the first three fields are encoded little-endian
+	if ep == nil || ep.AtLeast(2, 6) {
+		return fmt.Sprintf(
+			"%02x%02x%02x%02x-%02x%02x-%02x%02x-%02x%02x-%02x%02x%02x%02x%02x%02x",
+			u[3], u[2], u[1], u[0], u[5], u[4], u[7], u[6],
+			u[8], u[9], u[10], u[11], u[12], u[13], u[14], u[15],
+		)
+	}
+	return fmt.Sprintf(
+		"%02x%02x%02x%02x-%02x%02x-%02x%02x-%02x%02x-%02x%02x%02x%02x%02x%02x",
+		u[0], u[1], u[2], u[3], u[4], u[5], u[6], u[7],
+		u[8], u[9], u[10], u[11], u[12], u[13], u[14], u[15],
+	)
+}
+
+// Baseboard describes the Baseboard (or Module) Information (type 2)
+// structure
+type Baseboard struct {
+	Manufacturer      string `json:"manufacturer"`
+	Product           string `json:"product"`
+	Version           string `json:"version"`
+	SerialNumber      string `json:"serial_number"`
+	AssetTag          string `json:"asset_tag"`
+	LocationInChassis string `json:"location_in_chassis"`
+	ChassisHandle     uint16 `json:"chassis_handle"`
+	Type              string `json:"type"`
+}
+
+func parseBaseboard(s *Structure) *Baseboard {
+	bb := &Baseboard{
+		Manufacturer:      s.StringAt(0x04),
+		Product:           s.StringAt(0x05),
+		Version:           s.StringAt(0x06),
+		SerialNumber:      s.StringAt(0x07),
+		AssetTag:          s.StringAt(0x08),
+		LocationInChassis: s.StringAt(0x0A),
+		ChassisHandle:     s.Word(0x0B),
+	}
+	if s.Has(0x0D, 1) {
+		bb.Type = lookupString(baseboardTypeString, s.Byte(0x0D))
+	}
+	return bb
+}
+
+// Chassis describes the System Enclosure or Chassis (type 3) structure
+type Chassis struct {
+	Manufacturer string `json:"manufacturer"`
+	// Type is the raw chassis type, as reported by the linux kernel in
+	// /sys/class/dmi/id/chassis_type
+	Type             uint8  `json:"type"`
+	Version          string `json:"version"`
+	SerialNumber     string `json:"serial_number"`
+	AssetTag         string `json:"asset_tag"`
+	BootUpState      string `json:"boot_up_state"`
+	PowerSupplyState string `json:"power_supply_state"`
+	ThermalState     string `json:"thermal_state"`
+	// HeightU is the height of the enclosure in rack units. Zero if
+	// unspecified.
+	HeightU       int    `json:"height_u"`
+	NumPowerCords int    `json:"num_power_cords"`
+	SKUNumber     string `json:"sku_number"`
+}
+
+func parseChassis(s *Structure) *Chassis {
+	ch := &Chassis{
+		Manufacturer:     s.StringAt(0x04),
+		Type:             s.Byte(0x05) & 0x7F,
+		Version:          s.StringAt(0x06),
+		SerialNumber:     s.StringAt(0x07),
+		AssetTag:         s.StringAt(0x08),
+		BootUpState:      lookupString(chassisStateString, s.Byte(0x09)),
+		PowerSupplyState: lookupString(chassisStateString, s.Byte(0x0A)),
+		ThermalState:     lookupString(chassisStateString, s.Byte(0x0B)),
+		HeightU:          int(s.Byte(0x11)),
+		NumPowerCords:    int(s.Byte(0x12)),
+	}
+	if s.Has(0x14, 1) {
+		// the SKU number follows the contained elements
+		elemCount := int(s.Byte(0x13))
+		elemLength := int(s.Byte(0x14))
+		ch.SKUNumber = s.StringAt(0x15 + elemCount*elemLength)
+	}
+	return ch
+}
+
+// parseOEMStrings returns the free-form strings of an OEM Strings (type 11)
+// structure
+func parseOEMStrings(s *Structure) []string {
+	count := int(s.Byte(0x04))
+	strs := make([]string, 0, count)
+	for idx := 0; idx < count && idx < len(s.Strings); idx++ {
+		strs = append(strs, s.Strings[idx])
+	}
+	return strs
+}
@@ -0,0 +1,223 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package smbios
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// TypeEndOfTable marks the last structure of the table
+	TypeEndOfTable = 127
+
+	headerLength = 4
+
+	entryPoint32Anchor = "_SM_"
+	entryPoint64Anchor = "_SM3_"
+	// minimal length of the 32-bit (SMBIOS 2.1+) entry point structure
+	entryPoint32Length = 0x1F
+	// minimal length of the 64-bit (SMBIOS 3.0+) entry point structure
+	entryPoint64Length = 0x18
+)
+
+// EntryPoint describes the SMBIOS entry point structure, which tells us which
+// version of the specification the structure table conforms to
+type EntryPoint struct {
+	MajorVersion int `json:"major_version"`
+	MinorVersion int `json:"minor_version"`
+	// Revision is only reported by 64-bit (SMBIOS 3.0+) entry points
+	Revision int `json:"revision"`
+	// TableSizeBytes is the size of the structure table. For 64-bit entry
+	// points this is the maximum size the table may have.
+	TableSizeBytes uint32 `json:"table_size_bytes"`
+	// Is64Bit is true if the table was found through a 64-bit (SMBIOS 3.0+)
+	// entry point
+	Is64Bit bool `json:"is_64bit"`
+}
+
+// Version returns the specification version as "major.minor.revision"
+func (ep *EntryPoint) Version() string {
+	return fmt.Sprintf("%d.%d.%d", ep.MajorVersion, ep.MinorVersion, ep.Revision)
+}
+
+// AtLeast returns true if the table conforms to the given version of the
+// specification or to a later one
+func (ep *EntryPoint) AtLeast(major, minor int) bool {
+	if ep.MajorVersion != major {
+		return ep.MajorVersion > major
+	}
+	return ep.MinorVersion >= minor
+}
+
+// ParseEntryPoint decodes the content of the SMBIOS entry point structure,
+// exposed on linux as /sys/firmware/dmi/tables/smbios_entry_point
+func ParseEntryPoint(data []byte) (*EntryPoint, error) {
+	if bytes.HasPrefix(data, []byte(entryPoint64Anchor)) {
+		if len(data) < entryPoint64Length {
+			return nil, fmt.Errorf("truncated 64-bit entry point (%d bytes)", len(data))
+		}
+		return &EntryPoint{
+			MajorVersion:   int(data[0x07]),
+			MinorVersion:   int(data[0x08]),
+			Revision:       int(data[0x09]),
+			TableSizeBytes: binary.LittleEndian.Uint32(data[0x0C:]),
+			Is64Bit:        true,
+		}, nil
+	}
+	if bytes.HasPrefix(data, []byte(entryPoint32Anchor)) {
+		if len(data) < entryPoint32Length {
+			return nil, fmt.Errorf("truncated 32-bit entry point (%d bytes)", len(data))
+		}
+		return &EntryPoint{
+			MajorVersion:   int(data[0x06]),
+			MinorVersion:   int(data[0x07]),
+			TableSizeBytes: uint32(binary.LittleEndian.Uint16(data[0x16:])),
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown SMBIOS entry point anchor")
+}
+
+// Header is the header every SMBIOS structure begins with
+type Header struct {
+	Type   uint8  `json:"type"`
+	Length uint8  `json:"length"`
+	Handle uint16 `json:"handle"`
+}
+
+// Structure is a raw SMBIOS structure. The accessor methods take the offsets
+// as listed in the SMBIOS specification, which are relative to the beginning
+// of the structure (hence including the header). All of them gracefully
+// return zero values if the structure is too short to contain the requested
+// field, which is the case for fields added in later versions of the
+// specification.
+type Structure struct {
+	Header Header `json:"header"`
+	// Formatted holds the formatted area of the structure, header included
+	Formatted []byte `json:"-"`
+	// Strings holds the strings following the formatted area. Structures
+	// refer to them using 1-based indexes.
+	Strings []string `json:"strings"`
+}
+
+// Has returns true if the formatted area of the structure is long enough to
+// contain a field of `size` bytes at the given offset
+func (s *Structure) Has(offset, size int) bool {
+	return offset >= 0 && offset+size <= len(s.Formatted)
+}
+
+// Byte returns the BYTE field at the given offset
+func (s *Structure) Byte(offset int) uint8 {
+	if !s.Has(offset, 1) {
+		return 0
+	}
+	return s.Formatted[offset]
+}
+
+// Word returns the WORD field at the given offset
+func (s *Structure) Word(offset int) uint16 {
+	if !s.Has(offset, 2) {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(s.Formatted[offset:])
+}
+
+// DWord returns the DWORD field at the given offset
+func (s *Structure) DWord(offset int) uint32 {
+	if !s.Has(offset, 4) {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(s.Formatted[offset:])
+}
+
+// QWord returns the QWORD field at the given offset
+func (s *Structure) QWord(offset int) uint64 {
+	if !s.Has(offset, 8) {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(s.Formatted[offset:])
+}
+
+// StringAt returns the string referenced by the STRING field at the given
+// offset, or "" if the field is not set
+func (s *Structure) StringAt(offset int) string {
+	idx := int(s.Byte(offset))
+	if idx == 0 || idx > len(s.Strings) {
+		return ""
+	}
+	return strings.TrimSpace(s.Strings[idx-1])
+}
+
+// ParseStructures decodes the SMBIOS structure table, exposed on linux as
+// /sys/firmware/dmi/tables/DMI, stopping at the end-of-table structure.
+// Returns the structures decoded until the first error, if any.
+func ParseStructures(data []byte) ([]*Structure, error) {
+	structs := make([]*Structure, 0)
+	offset := 0
+	for offset+headerLength <= len(data) {
+		hdr := Header{
+			Type:   data[offset],
+			Length: data[offset+1],
+			Handle: binary.LittleEndian.Uint16(data[offset+2:]),
+		}
+		formattedEnd := offset + int(hdr.Length)
+		if hdr.Length < headerLength || formattedEnd > len(data) {
+			return structs, fmt.Errorf(
+				"malformed structure (type %d) at offset %d: length %d",
+				hdr.Type, offset, hdr.Length,
+			)
+		}
+		// the string-set is terminated by two consecutive NUL bytes, and it
+		// is made by these two bytes only if the structure has no strings.
+		stringsEnd := formattedEnd
+		for {
+			if stringsEnd+1 >= len(data) {
+				return structs, fmt.Errorf(
+					"unterminated strings (type %d) at offset %d",
+					hdr.Type, offset,
+				)
+			}
+			if data[stringsEnd] == 0 && data[stringsEnd+1] == 0 {
+				break
+			}
+			stringsEnd++
+		}
+		structs = append(structs, &Structure{
+			Header:    hdr,
+			Formatted: data[offset:formattedEnd],
+			Strings:   parseStrings(data[formattedEnd:stringsEnd]),
+		})
+		offset = stringsEnd + 2
+		if hdr.Type == TypeEndOfTable {
+			break
+		}
+	}
+	return structs, nil
+}
+
+func parseStrings(data []byte) []string {
+	strs := make([]string, 0)
+	if len(data) == 0 {
+		return strs
+	}
+	for _, s := range bytes.Split(data, []byte{0}) {
+		strs = append(strs, string(s))
+	}
+	return strs
+}
+
+// lookupString returns the description of a enumerated value found in the
+// given table, falling back to the numeric value if the table doesn't know it
+func lookupString(table map[uint8]string, value uint8) string {
+	if desc, ok := table[value]; ok {
+		return desc
+	}
+	return "0x" + strconv.FormatUint(uint64(value), 16)
+}
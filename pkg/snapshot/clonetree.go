@@ -43,6 +43,8 @@ func ExpectedCloneContent() []string {
 	fileSpecs = append(fileSpecs, ExpectedCloneNetContent()...)
 	fileSpecs = append(fileSpecs, ExpectedClonePCIContent()...)
 	fileSpecs = append(fileSpecs, ExpectedCloneGPUContent()...)
+	fileSpecs = append(fileSpecs, ExpectedCloneNVMeContent()...)
+	fileSpecs = append(fileSpecs, ExpectedCloneDMIContent()...)
 	return fileSpecs
 }
 
@@ -16,18 +16,22 @@ import (
 
 func createBlockDevices(buildDir string) error {
 	// Grab all the block device pseudo-directories from /sys/block symlinks
-	// (excluding loopback devices) and inject them into our build filesystem
-	// with all but the circular symlink'd subsystem directories
+	// (excluding detached loopback devices) and inject them into our build
+	// filesystem with all but the circular symlink'd subsystem directories
 	devLinks, err := ioutil.ReadDir("/sys/block")
 	if err != nil {
 		return err
 	}
 	for _, devLink := range devLinks {
 		dname := devLink.Name()
+		devPath := filepath.Join("/sys/block", dname)
 		if strings.HasPrefix(dname, "loop") {
-			continue
+			// the loop/ subdirectory only exists while the loop device is
+			// attached to a backing file
+			if _, err := os.Stat(filepath.Join(devPath, "loop")); err != nil {
+				continue
+			}
 		}
-		devPath := filepath.Join("/sys/block", dname)
 		trace("processing block device %q\n", devPath)
 
 		// from the sysfs layout, we know this is always a symlink
@@ -70,6 +74,32 @@ func createBlockDevices(buildDir string) error {
 		if err = createBlockDeviceDir(linkTargetPath, srcDeviceDir); err != nil {
 			return err
 		}
+		if err = createBlockDeviceDriverLinks(buildDir, srcDeviceDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createBlockDeviceDriverLinks copies the driver symlinks of the devices the
+// block device is attached through, from which the storage controller of
+// the block device is found. The drivers themselves are not copied.
+func createBlockDeviceDriverLinks(buildDir string, srcDeviceDir string) error {
+	for dir := filepath.Dir(srcDeviceDir); strings.HasPrefix(dir, "/sys/devices/"); dir = filepath.Dir(dir) {
+		driverLink := filepath.Join(dir, "driver")
+		dest, err := os.Readlink(driverLink)
+		if err != nil {
+			continue
+		}
+		buildDriverLink := filepath.Join(buildDir, driverLink)
+		if _, err := os.Lstat(buildDriverLink); err == nil {
+			// shared with an already processed block device
+			continue
+		}
+		trace("linking driver %s to %s\n", buildDriverLink, dest)
+		if err = os.Symlink(dest, buildDriverLink); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -140,37 +170,110 @@ func createBlockDeviceDir(buildDeviceDir string, srcDeviceDir string) error {
 			f.Close()
 		}
 	}
-	// There is a special file $DEVICE_DIR/queue/rotational that, for some hard
-	// drives, contains a 1 or 0 indicating whether the device is a spinning
-	// disk or not
-	srcQueueDir := filepath.Join(
-		srcDeviceDir,
-		"queue",
-	)
-	buildQueueDir := filepath.Join(
-		buildDeviceDir,
-		"queue",
-	)
-	err = os.MkdirAll(buildQueueDir, os.ModePerm)
-	if err != nil {
+	if err = createVirtualDeviceDir(buildDeviceDir, srcDeviceDir); err != nil {
 		return err
 	}
-	fp := filepath.Join(srcQueueDir, "rotational")
-	buf, err := ioutil.ReadFile(fp)
-	if err != nil {
+	if err = createStackLinksDirs(buildDeviceDir, srcDeviceDir); err != nil {
 		return err
 	}
-	targetPath := filepath.Join(buildQueueDir, "rotational")
-	trace("creating %s\n", targetPath)
-	f, err := os.Create(targetPath)
-	if err != nil {
-		return err
+	return createQueueDir(buildDeviceDir, srcDeviceDir)
+}
+
+func createQueueDir(buildDeviceDir string, srcDeviceDir string) error {
+	// The $DEVICE_DIR/queue directory describes the request queue of the
+	// device and its I/O characteristics, like whether the device is a
+	// spinning disk (queue/rotational) or its I/O scheduler. The iosched/
+	// subdirectory holds the tunables of the active scheduler.
+	srcQueueDir := filepath.Join(srcDeviceDir, "queue")
+	if _, err := os.Stat(srcQueueDir); err != nil {
+		// not all the block devices have a request queue
+		return nil
 	}
-	if _, err = f.Write(buf); err != nil {
-		return err
+	return filepath.Walk(srcQueueDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		targetPath := filepath.Join(buildDeviceDir, strings.TrimPrefix(path, srcDeviceDir))
+		if fi.IsDir() {
+			trace("creating queue directory %s\n", targetPath)
+			return os.MkdirAll(targetPath, os.ModePerm)
+		}
+		if !fi.Mode().IsRegular() || fi.Mode().Perm()&0444 == 0 {
+			// write-only pseudofiles, like queue/iosched/* of some
+			// schedulers, can't be read back
+			return nil
+		}
+		if err := copyPseudoFile(path, targetPath); err != nil {
+			if errors.Is(err, os.ErrPermission) {
+				trace("permission denied reading %q - skipped\n", path)
+				return nil
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+func createVirtualDeviceDir(buildDeviceDir string, srcDeviceDir string) error {
+	// Device-mapper targets, MD arrays and loop devices describe themselves
+	// in the dm/, md/ and loop/ subdirectories
+	virtualDevEntries := []string{
+		"dm/name",
+		"dm/suspended",
+		"dm/uuid",
+		"md/array_state",
+		"md/degraded",
+		"md/level",
+		"md/raid_disks",
+		"md/dev-*/slot",
+		"md/dev-*/state",
+		"loop/autoclear",
+		"loop/backing_file",
+		"loop/offset",
 	}
-	f.Close()
+	for _, entry := range virtualDevEntries {
+		matches, err := filepath.Glob(filepath.Join(srcDeviceDir, entry))
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			targetPath := filepath.Join(buildDeviceDir, strings.TrimPrefix(match, srcDeviceDir))
+			if err = os.MkdirAll(filepath.Dir(targetPath), os.ModePerm); err != nil {
+				return err
+			}
+			trace("creating %s\n", targetPath)
+			if err = copyPseudoFile(match, targetPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
+func createStackLinksDirs(buildDeviceDir string, srcDeviceDir string) error {
+	// Block devices, partitions included, link the block devices they are
+	// built on in their slaves/ subdirectory, and the block devices built on
+	// top of them in their holders/ subdirectory
+	for _, dname := range []string{"holders", "slaves"} {
+		srcLinksDir := filepath.Join(srcDeviceDir, dname)
+		links, err := ioutil.ReadDir(srcLinksDir)
+		if err != nil || len(links) == 0 {
+			// not part of a storage stack
+			continue
+		}
+		buildLinksDir := filepath.Join(buildDeviceDir, dname)
+		if err = os.MkdirAll(buildLinksDir, os.ModePerm); err != nil {
+			return err
+		}
+		for _, link := range links {
+			if err = copyLink(
+				filepath.Join(srcLinksDir, link.Name()),
+				filepath.Join(buildLinksDir, link.Name()),
+			); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -217,5 +320,5 @@ func createPartitionDir(buildPartitionDir string, srcPartitionDir string) error
 			f.Close()
 		}
 	}
-	return nil
+	return createStackLinksDirs(buildPartitionDir, srcPartitionDir)
 }
@@ -0,0 +1,41 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package snapshot
+
+import (
+	"io/ioutil"
+	"path/filepath"
+)
+
+const (
+	// warning: don't use the context package here, this means not even the linuxpath package.
+	// TODO(fromani) remove the path duplication
+	sysFirmwareDMITables = "/sys/firmware/dmi/tables"
+)
+
+// ExpectedCloneDMIContent returns a slice of glob patterns which represent
+// the pseudofiles ghw cares about, pertaining to the SMBIOS structure table
+// the memory modules and the SMBIOS records are decoded from.
+func ExpectedCloneDMIContent() []string {
+	return dmiTablesSpecs(sysFirmwareDMITables)
+}
+
+// dmiTablesSpecs returns the SMBIOS entry point and structure table found in
+// tablesDir. Both files are usually readable by root only, hence they are
+// skipped when they can't be read, like filterReadable does.
+func dmiTablesSpecs(tablesDir string) []string {
+	fileSpecs := []string{}
+	for _, name := range []string{"smbios_entry_point", "DMI"} {
+		fileSpec := filepath.Join(tablesDir, name)
+		if _, err := ioutil.ReadFile(fileSpec); err != nil {
+			trace("cannot read %q - skipped\n", fileSpec)
+			continue
+		}
+		fileSpecs = append(fileSpecs, fileSpec)
+	}
+	return fileSpecs
+}
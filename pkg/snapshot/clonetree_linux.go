@@ -34,6 +34,7 @@ func ExpectedCloneStaticContent() []string {
 		"/proc/cpuinfo",
 		"/proc/meminfo",
 		"/proc/self/mounts",
+		"/proc/self/mountinfo",
 		"/sys/devices/system/cpu/cpu*/cache/index*/*",
 		"/sys/devices/system/cpu/cpu*/topology/*",
 		"/sys/devices/system/memory/block_size_bytes",
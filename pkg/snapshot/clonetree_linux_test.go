@@ -7,6 +7,7 @@
 package snapshot_test
 
 import (
+	"encoding/binary"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -15,6 +16,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/jaypipes/ghw/pkg/option"
+	"github.com/jaypipes/ghw/pkg/smbios"
 	"github.com/jaypipes/ghw/pkg/snapshot"
 )
 
@@ -95,6 +98,85 @@ func TestCloneSystemTree(t *testing.T) {
 	}
 }
 
+func TestCloneDMITables(t *testing.T) {
+	root, err := ioutil.TempDir("", "ghw-test-clonetree-dmi-*")
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	// the tables are not exposed, e.g. on virtual machines without SMBIOS
+	tablesDir := filepath.Join(root, "sys", "firmware", "dmi", "tables")
+	if fileSpecs := snapshot.DMITablesSpecs(tablesDir); len(fileSpecs) != 0 {
+		t.Fatalf("Expected no content to clone, got %v", fileSpecs)
+	}
+
+	// a SMBIOS 3.2.0 entry point and a table holding a System Information
+	// structure
+	table := []byte{smbios.TypeSystem, 8, 0x00, 0x01, 1, 2, 0, 0}
+	table = append(table, "Acme\x00Server\x00\x00"...)
+	table = append(table, smbios.TypeEndOfTable, 4, 0xFF, 0xFF, 0, 0)
+	ep := make([]byte, 0x18)
+	copy(ep, "_SM3_")
+	ep[0x06] = 0x18
+	ep[0x07] = 3
+	ep[0x08] = 2
+	binary.LittleEndian.PutUint32(ep[0x0C:], uint32(len(table)))
+	if err := os.MkdirAll(tablesDir, os.ModePerm); err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tablesDir, "smbios_entry_point"), ep, 0400); err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tablesDir, "DMI"), table, 0400); err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+
+	cloneRoot, err := ioutil.TempDir("", "ghw-test-clonetree-*")
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	defer os.RemoveAll(cloneRoot)
+
+	fileSpecs := snapshot.DMITablesSpecs(tablesDir)
+	if len(fileSpecs) != 2 {
+		t.Fatalf("Expected the entry point and the structure table, got %v", fileSpecs)
+	}
+	if err := snapshot.CopyFilesInto(fileSpecs, cloneRoot, nil); err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+
+	tmpfile, err := ioutil.TempFile("", "ghw-test-snapshot-*.tgz")
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	defer func() {
+		tmpfile.Close()
+		os.Remove(tmpfile.Name())
+	}()
+	if err := snapshot.PackWithWriter(tmpfile, cloneRoot); err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if err := tmpfile.Close(); err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+
+	unpackedRoot, err := snapshot.Unpack(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	defer os.RemoveAll(unpackedRoot)
+
+	// the fake sysfs tree is cloned preserving its absolute path
+	info, err := smbios.New(option.WithChroot(filepath.Join(unpackedRoot, root)))
+	if err != nil {
+		t.Fatalf("Expected nil err, but got %v", err)
+	}
+	if info.System == nil || info.System.Manufacturer != "Acme" || info.System.ProductName != "Server" {
+		t.Fatalf("Expected the system information decoded from the snapshot, got %+v", info.System)
+	}
+}
+
 func scanTree(root, prefix string, excludeList []string) ([]string, error) {
 	var contents []string
 	return contents, filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
@@ -7,25 +7,144 @@
 package snapshot
 
 import (
-	"strings"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// warning: don't use the context package here, this means not even the linuxpath package.
+	// TODO(fromani) remove the path duplication
+	procNetVLANConfig = "/proc/net/vlan/config"
 )
 
 // ExpectedCloneNetContent returns a slice of strings pertaning to the network interfaces ghw
-// cares about. We cannot use a static list because we want to filter away the virtual devices,
-// which  ghw doesn't concern itself about. So we need to do some runtime discovery.
+// cares about. We cannot use a static list because the attributes of the interfaces depend
+// on their kind, e.g. bonds or bridges, and on their backing device. So we need to do some
+// runtime discovery.
 // Additionally, we want to make sure to clone the backing device data.
 func ExpectedCloneNetContent() []string {
 	ifaceEntries := []string{
 		"addr_assign_type",
 		// intentionally avoid to clone "address" to avoid to leak any host-idenfifiable data.
+		"carrier",
+		"dev_port",
+		"duplex",
+		"iflink",
+		"ifindex",
+		"mtu",
+		"operstate",
+		"phys_port_name",
+		"speed",
+		"tx_queue_len",
+		"type",
+		"uevent",
+	}
+
+	// ghw ignores the loopback interface
+	filterName := func(devName string) bool {
+		return devName != "lo"
 	}
 
-	filterLink := func(linkDest string) bool {
-		if strings.Contains(linkDest, "devices/virtual/net") {
-			return false
+	fileSpecs := cloneContentByClass("net", ifaceEntries, filterName, filterNone)
+	fileSpecs = append(fileSpecs, netDeviceSpecs(filterName)...)
+	// the VLAN interfaces are listed once the 8021q module is loaded
+	if _, err := os.Stat(procNetVLANConfig); err == nil {
+		fileSpecs = append(fileSpecs, procNetVLANConfig)
+	}
+	return filterReadable(fileSpecs)
+}
+
+// netDeviceSpecs returns the pseudofiles found only on some network
+// interfaces, depending on their kind, and the pseudofiles describing the
+// devices backing the network interfaces and their drivers, plus one file per
+// hardware queue of the interfaces, to count them. Like for PCI devices, only
+// the entries which exist on this host are returned.
+func netDeviceSpecs(filterName filterFunc) []string {
+	// the settings of bonds, the ports of bridges and the stacking of
+	// interfaces, given as glob patterns. The queue directories are not
+	// created unless they contain a file.
+	perDevOptionalEntries := []string{
+		"bonding/mode",
+		"bonding/slaves",
+		"bonding/xmit_hash_policy",
+		"bridge/stp_state",
+		"brif/*",
+		"lower_*",
+		"master",
+		"queues/rx-*/rps_flow_cnt",
+		"queues/tx-*/tx_timeout",
+	}
+	var fileSpecs []string
+
+	// warning: don't use the context package here, this means not even the linuxpath package.
+	// TODO(fromani) remove the path duplication
+	sysClassNet := filepath.Join("sys", "class", "net")
+	entries, err := ioutil.ReadDir(sysClassNet)
+	if err != nil {
+		return fileSpecs
+	}
+	for _, entry := range entries {
+		if !filterName(entry.Name()) {
+			continue
+		}
+		dest, err := os.Readlink(filepath.Join(sysClassNet, entry.Name()))
+		if err != nil {
+			continue
+		}
+		netDev := filepath.Clean(filepath.Join(sysClassNet, dest))
+		for _, perDevEntry := range perDevOptionalEntries {
+			matches, err := filepath.Glob(filepath.Join(netDev, perDevEntry))
+			if err == nil && len(matches) > 0 {
+				fileSpecs = append(fileSpecs, filepath.Join(netDev, perDevEntry))
+			}
+		}
+
+		dest, err = os.Readlink(filepath.Join(netDev, "device"))
+		if err != nil {
+			continue
+		}
+		fileSpecs = append(fileSpecs, filepath.Join(netDev, "device"))
+		devPath := filepath.Clean(filepath.Join(netDev, dest))
+		// "subsystem" tells the bus of the device, and so its PCI address
+		fileSpecs = append(fileSpecs, filepath.Join(devPath, "subsystem"))
+
+		dest, err = os.Readlink(filepath.Join(devPath, "driver"))
+		if err != nil {
+			continue
+		}
+		fileSpecs = append(fileSpecs, filepath.Join(devPath, "driver"))
+		drvPath := filepath.Clean(filepath.Join(devPath, dest))
+		// built-in drivers have no module link, and most in-tree modules
+		// have no version
+		dest, err = os.Readlink(filepath.Join(drvPath, "module"))
+		if err != nil {
+			continue
+		}
+		fileSpecs = append(fileSpecs, filepath.Join(drvPath, "module"))
+		modVersion := filepath.Join(filepath.Clean(filepath.Join(drvPath, dest)), "version")
+		if _, err := os.Stat(modVersion); err == nil {
+			fileSpecs = append(fileSpecs, modVersion)
 		}
-		return true
 	}
+	return fileSpecs
+}
 
-	return cloneContentByClass("net", ifaceEntries, filterNone, filterLink)
+// filterReadable filters out the pseudofiles which can't be read. Some
+// attributes of network interfaces can only be read depending on the state
+// of the interface or on its driver, e.g. "carrier" fails while the interface
+// is down and "phys_port_name" fails if the driver doesn't name its ports.
+func filterReadable(fileSpecs []string) []string {
+	readable := make([]string, 0, len(fileSpecs))
+	for _, fileSpec := range fileSpecs {
+		fi, err := os.Stat(fileSpec)
+		if err == nil && fi.Mode().IsRegular() {
+			if _, err = ioutil.ReadFile(fileSpec); err != nil {
+				trace("cannot read %q - skipped\n", fileSpec)
+				continue
+			}
+		}
+		readable = append(readable, fileSpec)
+	}
+	return readable
 }
@@ -0,0 +1,67 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package snapshot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// warning: don't use the context package here, this means not even the linuxpath package.
+	// TODO(fromani) remove the path duplication
+	sysClassNVMe = "/sys/class/nvme"
+)
+
+// ExpectedCloneNVMeContent returns a slice of glob patterns which represent
+// the pseudofiles ghw cares about, pertaining to the NVMe controllers and the
+// namespaces attached to them. The namespaces themselves are cloned as block
+// devices; here we clone the entries linking them to their controllers,
+// which for NVMe multipath are hidden block devices.
+func ExpectedCloneNVMeContent() []string {
+	ctrlEntries := []string{
+		"address",
+		"firmware_rev",
+		"model",
+		"serial",
+		"state",
+		"transport",
+		// the temperature sensor of the controller
+		"hwmon*/temp1_input",
+		// the namespaces attached to the controller
+		"nvme*n*/size",
+	}
+	fileSpecs := []string{}
+	entries, err := ioutil.ReadDir(sysClassNVMe)
+	if err != nil {
+		// no NVMe controllers, or the nvme driver is not loaded
+		return fileSpecs
+	}
+	for _, entry := range entries {
+		ctrlPath := filepath.Join(sysClassNVMe, entry.Name())
+		dest, err := os.Readlink(ctrlPath)
+		if err != nil {
+			trace("error reading link %q: %v", ctrlPath, err)
+			continue
+		}
+		// first copy the symlink itself, then the content of the backing
+		// controller device
+		fileSpecs = append(fileSpecs, ctrlPath)
+		ctrlData := filepath.Clean(filepath.Join(sysClassNVMe, dest))
+		for _, ctrlEntry := range ctrlEntries {
+			// not all the entries are exposed by all the kernel versions and
+			// transports
+			spec := filepath.Join(ctrlData, ctrlEntry)
+			if matches, err := filepath.Glob(spec); err != nil || len(matches) == 0 {
+				continue
+			}
+			fileSpecs = append(fileSpecs, spec)
+		}
+	}
+	return fileSpecs
+}
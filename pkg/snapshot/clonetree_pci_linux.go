@@ -20,6 +20,10 @@ const (
 	// warning: don't use the context package here, this means not even the linuxpath package.
 	// TODO(fromani) remove the path duplication
 	sysBusPCIDir = "/sys/bus/pci/devices"
+	// IOMMU groups are populated only if the IOMMU is enabled
+	sysKernelIOMMUGroupsDevices = "/sys/kernel/iommu_groups/*/devices/*"
+	// built-in drivers have no module link
+	sysBusPCIDriversModule = "/sys/bus/pci/drivers/*/module"
 )
 
 // ExpectedClonePCIContent return a slice of glob patterns which represent the pseudofiles
@@ -29,6 +33,11 @@ func ExpectedClonePCIContent() []string {
 	fileSpecs := []string{
 		"/sys/bus/pci/drivers/*",
 	}
+	for _, optionalSpec := range []string{sysKernelIOMMUGroupsDevices, sysBusPCIDriversModule} {
+		if matches, err := filepath.Glob(optionalSpec); err == nil && len(matches) > 0 {
+			fileSpecs = append(fileSpecs, optionalSpec)
+		}
+	}
 	pciRoots := []string{
 		sysBusPCIDir,
 	}
@@ -61,14 +70,32 @@ func scanPCIDeviceRoot(root string) (fileSpecs []string, pciRoots []string) {
 
 	perDevEntries := []string{
 		"class",
+		"config",
 		"device",
+		"enable",
 		"irq",
 		"local_cpulist",
 		"modalias",
 		"numa_node",
+		"resource",
 		"revision",
 		"vendor",
 	}
+	// entries found only on some devices (e.g. PCI Express devices only,
+	// SR-IOV physical or virtual functions only), given as glob patterns
+	perDevOptionalEntries := []string{
+		"current_link_speed",
+		"current_link_width",
+		"driver_override",
+		"iommu_group",
+		"max_link_speed",
+		"max_link_width",
+		"msi_irqs/*",
+		"physfn",
+		"sriov_numvfs",
+		"sriov_totalvfs",
+		"virtfn*",
+	}
 	entries, err := ioutil.ReadDir(root)
 	if err != nil {
 		return []string{}, []string{}
@@ -94,6 +121,13 @@ func scanPCIDeviceRoot(root string) (fileSpecs []string, pciRoots []string) {
 		for _, perNetEntry := range perDevEntries {
 			fileSpecs = append(fileSpecs, filepath.Join(pciEntry, perNetEntry))
 		}
+		for _, perNetEntry := range perDevOptionalEntries {
+			matches, err := filepath.Glob(filepath.Join(pciEntry, perNetEntry))
+			if err != nil || len(matches) == 0 {
+				continue
+			}
+			fileSpecs = append(fileSpecs, filepath.Join(pciEntry, perNetEntry))
+		}
 
 		if isPCIBridge(entryPath) {
 			trace("adding new PCI root %q\n", entryName)
@@ -26,3 +26,11 @@ func ExpectedCloneNetContent() []string {
 func ExpectedClonePCIContent() []string {
 	return []string{}
 }
+
+func ExpectedCloneNVMeContent() []string {
+	return []string{}
+}
+
+func ExpectedCloneDMIContent() []string {
+	return []string{}
+}
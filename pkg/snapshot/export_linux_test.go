@@ -0,0 +1,10 @@
+//
+// Use and distribution licensed under the Apache license version 2.
+//
+// See the COPYING file in the root project directory for full text.
+//
+
+package snapshot
+
+// DMITablesSpecs lets the tests clone the SMBIOS tables of a fake sysfs tree
+var DMITablesSpecs = dmiTablesSpecs
@@ -13,10 +13,26 @@ import (
 )
 
 func SnapshotsDirectory() (string, error) {
+	return directory("snapshots")
+}
+
+// SmartctlDirectory returns the directory of the recorded `smartctl --json`
+// outputs
+func SmartctlDirectory() (string, error) {
+	return directory("smartctl")
+}
+
+// EthtoolDirectory returns the directory of the recorded ethtool outputs, with
+// a subdirectory per driver
+func EthtoolDirectory() (string, error) {
+	return directory("ethtool")
+}
+
+func directory(name string) (string, error) {
 	_, file, _, ok := runtime.Caller(0)
 	if !ok {
 		return "", fmt.Errorf("Cannot retrieve testdata directory")
 	}
 	basedir := filepath.Dir(file)
-	return filepath.Join(basedir, "snapshots"), nil
+	return filepath.Join(basedir, name), nil
 }